@@ -12,6 +12,8 @@ const (
 
 const (
 	// Message, common, consumer - producer.
+	// Content type / encoding are mapped also from / to [broker.PropMsgContentType] / [broker.PropMsgContentEncoding],
+	// set / read by [broker.MessageCodec].
 	PropMsgContentType     = "amqp.property.msg.contentType"
 	PropMsgContentEncoding = "amqp.property.msg.contentEncoding"
 	PropMsgDeliveryMode    = "amqp.property.msg.deliveryMode"
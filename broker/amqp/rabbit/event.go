@@ -0,0 +1,96 @@
+package rabbit
+
+import (
+	"maps"
+	"mime"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/actforgood/xtransport/broker"
+)
+
+// EventHeaderPrefix is the prefix of the AMQP headers holding binary mode CloudEvents attributes,
+// like "cloudEvents:id", as defined by the CloudEvents AMQP protocol binding.
+// The "datacontenttype" attribute is mapped onto the AMQP content type property.
+const EventHeaderPrefix = "cloudEvents:"
+
+// setEventHeaders sets the binary mode CloudEvents headers upon given headers, from given props.
+func setEventHeaders(headers amqp.Table, props broker.Props) {
+	for extName, extValue := range props.EventExtensions() {
+		headers[EventHeaderPrefix+extName] = extValue
+	}
+	headers[EventHeaderPrefix+"specversion"] = props.EventSpecVersion()
+	headers[EventHeaderPrefix+"id"] = props.EventID()
+	headers[EventHeaderPrefix+"source"] = props.EventSource()
+	headers[EventHeaderPrefix+"type"] = props.EventType()
+	if subject := props.EventSubject(); subject != "" {
+		headers[EventHeaderPrefix+"subject"] = subject
+	}
+	if eventTime := props.EventTime(); !eventTime.IsZero() {
+		headers[EventHeaderPrefix+"time"] = eventTime.Format(time.RFC3339Nano)
+	}
+	if dataSchema := props.EventDataSchema(); dataSchema != "" {
+		headers[EventHeaderPrefix+"dataschema"] = dataSchema
+	}
+}
+
+// setEventProps sets, upon given message, the CloudEvents props of an event received in binary mode
+// (through "cloudEvents:" prefixed headers), or in structured mode (case in which message's body
+// becomes event's data, and its content type event's "datacontenttype").
+// Messages which do not hold an event, or hold a malformed structured mode event, are left untouched.
+func setEventProps(msg *broker.Message, amqpMsg amqp.Delivery) {
+	if mediaType, _, _ := mime.ParseMediaType(amqpMsg.ContentType); mediaType == broker.MediaTypeCloudEventsJSON {
+		eventMsg, err := broker.UnmarshalStructuredEvent(amqpMsg.Body)
+		if err != nil {
+			return
+		}
+		msg.Body = eventMsg.Body
+		maps.Copy(msg.Props, eventMsg.Props)
+		msg.Props[PropMsgContentType] = eventMsg.Props.GetString(broker.PropMsgContentType)
+		msg.Props[broker.PropMsgContentType] = msg.Props[PropMsgContentType]
+
+		return
+	}
+
+	if _, found := amqpMsg.Headers[EventHeaderPrefix+"specversion"]; !found {
+		return
+	}
+	extensions := make(map[string]any)
+	for headerName, headerValue := range amqpMsg.Headers {
+		attrName, isEventHeader := strings.CutPrefix(headerName, EventHeaderPrefix)
+		if !isEventHeader {
+			continue
+		}
+		switch attrName {
+		case "specversion":
+			msg.Props[broker.PropEventSpecVersion] = headerValue
+		case "id":
+			msg.Props[broker.PropEventID] = headerValue
+		case "source":
+			msg.Props[broker.PropEventSource] = headerValue
+		case "type":
+			msg.Props[broker.PropEventType] = headerValue
+		case "subject":
+			msg.Props[broker.PropEventSubject] = headerValue
+		case "dataschema":
+			msg.Props[broker.PropEventDataSchema] = headerValue
+		case "time":
+			switch eventTime := headerValue.(type) {
+			case time.Time:
+				msg.Props[broker.PropEventTime] = eventTime
+			case string:
+				if parsedTime, err := time.Parse(time.RFC3339Nano, eventTime); err == nil {
+					msg.Props[broker.PropEventTime] = parsedTime
+				}
+			}
+		default:
+			extensions[attrName] = headerValue
+		}
+	}
+	if len(extensions) > 0 {
+		msg.Props[broker.PropEventExtensions] = extensions
+	}
+	msg.Props[broker.PropEventMode] = broker.EventModeBinary
+}
@@ -0,0 +1,158 @@
+package rabbit
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+// deliveryFromPublishing simulates the delivery of an AMQP publishing.
+func deliveryFromPublishing(pubMsg amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{
+		Headers:         pubMsg.Headers,
+		ContentType:     pubMsg.ContentType,
+		ContentEncoding: pubMsg.ContentEncoding,
+		MessageId:       pubMsg.MessageId,
+		Body:            pubMsg.Body,
+	}
+}
+
+func newTestEventMessage(mode string) broker.Message {
+	props := broker.Event{
+		ID:         "evt-1",
+		Source:     "/shop/orders",
+		Type:       "com.shop.order.placed.v2",
+		Subject:    "ord-123",
+		Time:       time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC),
+		Extensions: map[string]any{"tenant": "acme"},
+		Mode:       mode,
+	}.Props()
+	props[PropMsgContentType] = "application/json"
+	props[PropMsgMessageID] = "msg-1"
+	props[PropMsgHeaders] = amqp.Table{"x-app": "shop"}
+
+	return broker.Message{Body: []byte(`{"id":"ord-123"}`), Props: props}
+}
+
+func TestEventMapping(t *testing.T) {
+	t.Parallel()
+
+	t.Run("binary mode", testEventMappingBinary)
+	t.Run("structured mode", testEventMappingStructured)
+	t.Run("not an event", testEventMappingNotAnEvent)
+	t.Run("returns error for invalid event", testEventMappingErr)
+}
+
+func assertEventMessage(t *testing.T, expectedMode string, msg broker.Message) {
+	t.Helper()
+
+	assert.Equal(t, `{"id":"ord-123"}`, string(msg.Body))
+	assert.Equal(t, "application/json", msg.Props.GetString(PropMsgContentType))
+	assert.Equal(t, "msg-1", msg.Props.GetString(PropMsgMessageID))
+	assert.Equal(t, expectedMode, msg.Props.EventMode())
+	assert.Equal(t, broker.EventSpecVersion, msg.Props.EventSpecVersion())
+	assert.Equal(t, "evt-1", msg.Props.EventID())
+	assert.Equal(t, "/shop/orders", msg.Props.EventSource())
+	assert.Equal(t, "com.shop.order.placed.v2", msg.Props.EventType())
+	assert.Equal(t, "ord-123", msg.Props.EventSubject())
+	assert.Equal(t, time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC), msg.Props.EventTime())
+	assert.Equal(t, map[string]any{"tenant": "acme"}, msg.Props.EventExtensions())
+	assert.Nil(t, broker.ValidateEvent(msg.Props))
+}
+
+func testEventMappingBinary(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	msg := newTestEventMessage(broker.EventModeBinary)
+
+	// act
+	pubMsg, err := newPublishing(msg)
+	resultMsg := ConvertToMessage(deliveryFromPublishing(pubMsg))
+
+	// assert
+	assert.RequireNil(t, err)
+	assert.Equal(t, amqp.Table{
+		"cloudEvents:specversion": "1.0",
+		"cloudEvents:id":          "evt-1",
+		"cloudEvents:source":      "/shop/orders",
+		"cloudEvents:type":        "com.shop.order.placed.v2",
+		"cloudEvents:subject":     "ord-123",
+		"cloudEvents:time":        "2024-05-17T10:30:00Z",
+		"cloudEvents:tenant":      "acme",
+	}, pubMsg.Headers)
+	assert.Equal(t, "application/json", pubMsg.ContentType)
+	assertEventMessage(t, broker.EventModeBinary, resultMsg)
+}
+
+func testEventMappingStructured(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	msg := newTestEventMessage(broker.EventModeStructured)
+
+	// act
+	pubMsg, err := newPublishing(msg)
+	resultMsg := ConvertToMessage(deliveryFromPublishing(pubMsg))
+
+	// assert
+	assert.RequireNil(t, err)
+	assert.Equal(t, amqp.Table(nil), pubMsg.Headers)
+	assert.Equal(t, broker.MediaTypeCloudEventsJSON, pubMsg.ContentType)
+	assert.Equal(t,
+		`{"data":{"id":"ord-123"},"datacontenttype":"application/json","id":"evt-1","source":"/shop/orders",`+
+			`"specversion":"1.0","subject":"ord-123","tenant":"acme","time":"2024-05-17T10:30:00Z",`+
+			`"type":"com.shop.order.placed.v2"}`,
+		string(pubMsg.Body),
+	)
+	assertEventMessage(t, broker.EventModeStructured, resultMsg)
+}
+
+func testEventMappingNotAnEvent(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	msg := broker.Message{
+		Body:  []byte(`{"id":"ord-123"}`),
+		Props: broker.Props{PropMsgContentType: "application/json"},
+	}
+
+	// act
+	pubMsg, err := newPublishing(msg)
+	resultMsg := ConvertToMessage(deliveryFromPublishing(pubMsg))
+	malformedMsg := ConvertToMessage(amqp.Delivery{Body: []byte(`{`), ContentType: broker.MediaTypeCloudEventsJSON})
+
+	// assert
+	assert.RequireNil(t, err)
+	assert.Equal(t, amqp.Table(nil), pubMsg.Headers)
+	assert.Equal(t, msg.Body, resultMsg.Body)
+	assert.True(t, !resultMsg.Props.IsEvent())
+	assert.Equal(t, `{`, string(malformedMsg.Body))
+	assert.True(t, !malformedMsg.Props.IsEvent())
+}
+
+func testEventMappingErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	invalidMsg := broker.Message{Props: broker.Event{ID: "evt-1"}.Props()}
+	encodedMsg := newTestEventMessage(broker.EventModeStructured)
+	encodedMsg.Props[PropMsgContentEncoding] = broker.ContentEncodingGzip
+
+	// act
+	_, errInvalidBinary := newPublishing(invalidMsg)
+	invalidMsg.Props[broker.PropEventMode] = broker.EventModeStructured
+	_, errInvalidStructured := newPublishing(invalidMsg)
+	_, errEncoded := newPublishing(encodedMsg)
+
+	// assert
+	assert.NotNil(t, errInvalidBinary)
+	assert.NotNil(t, errInvalidStructured)
+	if assert.NotNil(t, errEncoded) {
+		assert.Equal(t, "content encoding is not supported for structured mode events", errEncoded.Error())
+	}
+}
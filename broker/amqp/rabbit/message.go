@@ -1,14 +1,19 @@
 package rabbit
 
 import (
+	"bytes"
+
 	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/decoder"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // ConvertToMessage converts an AMQP message to internal Message format.
+// CloudEvents events, received in binary or structured mode, have their attributes set as event props
+// (see [broker.Props.EventID] and the other accessors), and their data as body.
 func ConvertToMessage(amqpMsg amqp.Delivery) broker.Message {
-	return broker.Message{
+	msg := broker.Message{
 		Body: amqpMsg.Body,
 		Props: broker.Props{
 			PropMsgHeaders:         amqpMsg.Headers,
@@ -30,8 +35,35 @@ func ConvertToMessage(amqpMsg amqp.Delivery) broker.Message {
 			PropMsgRedelivered:     amqpMsg.Redelivered,
 			PropMsgExchange:        amqpMsg.Exchange,
 			PropMsgRoutingKey:      amqpMsg.RoutingKey,
+			// broker props, for messages to be decoded with a [broker.MessageCodec].
+			broker.PropMsgContentType:     amqpMsg.ContentType,
+			broker.PropMsgContentEncoding: amqpMsg.ContentEncoding,
 		},
 	}
+	setEventProps(&msg, amqpMsg)
+
+	return msg
+}
+
+// getPropString returns the string prop stored under given AMQP key, or, if not set,
+// under given equivalent broker key.
+func getPropString(props broker.Props, key, brokerKey string) string {
+	if value := props.GetString(key); value != "" {
+		return value
+	}
+
+	return props.GetString(brokerKey)
+}
+
+// DecodeMessage decodes the body of given message into dest, with the decoder
+// registered in [decoder.DefaultRegistry] for message's content type.
+// A [*decoder.UnsupportedMediaTypeError] is returned if content type is not supported.
+func DecodeMessage(msg broker.Message, dest any) error {
+	return decoder.DefaultRegistry.Decode(
+		getPropString(msg.Props, PropMsgContentType, broker.PropMsgContentType),
+		bytes.NewReader(msg.Body),
+		dest,
+	)
 }
 
 // IsRetried checks if the given AMQP message has been retried
@@ -0,0 +1,60 @@
+package rabbit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/broker/amqp/rabbit"
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decodes with message's content type decoder", testDecodeMessageSuccess)
+	t.Run("returns unsupported media type error", testDecodeMessageUnsupportedMediaType)
+}
+
+func testDecodeMessageSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		msg = broker.Message{
+			Body:  []byte(`<user><name>John Doe</name></user>`),
+			Props: broker.Props{rabbit.PropMsgContentType: "application/xml"},
+		}
+		dest struct {
+			Name string `xml:"name"`
+		}
+	)
+
+	// act
+	err := rabbit.DecodeMessage(msg, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
+
+func testDecodeMessageUnsupportedMediaType(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		msg = broker.Message{
+			Body:  []byte("John Doe"),
+			Props: broker.Props{rabbit.PropMsgContentType: "text/plain"},
+		}
+		dest string
+	)
+
+	// act
+	err := rabbit.DecodeMessage(msg, &dest)
+
+	// assert
+	assert.True(t, errors.Is(err, decoder.ErrUnsupportedMediaType))
+}
@@ -3,6 +3,8 @@ package rabbit
 import (
 	"context"
 	"errors"
+	"maps"
+	"time"
 
 	"github.com/actforgood/xerr"
 	"github.com/actforgood/xrand"
@@ -13,12 +15,32 @@ import (
 
 type publisher struct {
 	config    Config
+	pubConfig PublisherConfig
 	connFac   ConnectionFactory
 	channelID string
 }
 
+// PublisherConfig holds the configuration for [NewPublisherWithConfig].
+type PublisherConfig struct {
+	// MetricsRecorder records publishing metrics. Defaults to [broker.NopMetricsRecorder].
+	MetricsRecorder broker.MetricsRecorder
+}
+
 func NewPublisher(connFac ConnectionFactory, config Config) (broker.Publisher, error) {
-	pub := &publisher{config: config, connFac: connFac, channelID: xrand.String(6)}
+	return NewPublisherWithConfig(connFac, config, PublisherConfig{})
+}
+
+// NewPublisherWithConfig instantiates a new publisher upon given Exchange-Queue-Bind setup,
+// configured through given publisher config.
+func NewPublisherWithConfig(
+	connFac ConnectionFactory,
+	config Config,
+	pubConfig PublisherConfig,
+) (broker.Publisher, error) {
+	if pubConfig.MetricsRecorder == nil {
+		pubConfig.MetricsRecorder = broker.NopMetricsRecorder{}
+	}
+	pub := &publisher{config: config, pubConfig: pubConfig, connFac: connFac, channelID: xrand.String(6)}
 	if err := pub.initialize(); err != nil {
 		return nil, err
 	}
@@ -74,20 +96,17 @@ func (p *publisher) initialize() error {
 }
 
 func (p *publisher) Publish(ctx context.Context, msg broker.Message) error {
-	pubMsg := amqp.Publishing{
-		ContentType:     msg.Props.GetString(PropMsgContentType),
-		ContentEncoding: msg.Props.GetString(PropMsgContentEncoding),
-		DeliveryMode:    uint8(msg.Props.GetInt(PropMsgDeliveryMode)),
-		Priority:        uint8(msg.Props.GetInt(PropMsgPriority)),
-		CorrelationId:   msg.Props.GetString(PropMsgCorrelationID),
-		ReplyTo:         msg.Props.GetString(PropMsgReplyTo),
-		Expiration:      msg.Props.GetString(PropMsgExpiration),
-		MessageId:       msg.Props.GetString(PropMsgMessageID),
-		Timestamp:       msg.Props.GetTime(PropMsgTimestamp),
-		Type:            msg.Props.GetString(PropMsgType),
-		UserId:          msg.Props.GetString(PropMsgUserID),
-		AppId:           msg.Props.GetString(PropMsgAppID),
-		Body:            msg.Body,
+	start := time.Now()
+	err := p.publish(ctx, msg)
+	p.pubConfig.MetricsRecorder.MessagePublished(p.config.Exchange.Name, time.Since(start), err)
+
+	return err
+}
+
+func (p *publisher) publish(ctx context.Context, msg broker.Message) error {
+	pubMsg, err := newPublishing(msg)
+	if err != nil {
+		return xerr.Wrap(err, "could not publish message")
 	}
 
 	ch, err := p.connFac.Channel(p.channelID)
@@ -118,3 +137,53 @@ func (p *publisher) Close() error {
 
 	return nil
 }
+
+// newPublishing converts a message into an AMQP publishing.
+// Event messages (see [broker.Event]) are mapped according to their content mode:
+// in binary mode, event's attributes are set as "cloudEvents:" prefixed headers (see [EventHeaderPrefix]);
+// in structured mode, the whole event is JSON encoded as body.
+// Note: [PropMsgHeaders] are not published.
+func newPublishing(msg broker.Message) (amqp.Publishing, error) {
+	pubMsg := amqp.Publishing{
+		ContentType:     getPropString(msg.Props, PropMsgContentType, broker.PropMsgContentType),
+		ContentEncoding: getPropString(msg.Props, PropMsgContentEncoding, broker.PropMsgContentEncoding),
+		DeliveryMode:    uint8(msg.Props.GetInt(PropMsgDeliveryMode)),
+		Priority:        uint8(msg.Props.GetInt(PropMsgPriority)),
+		CorrelationId:   msg.Props.GetString(PropMsgCorrelationID),
+		ReplyTo:         msg.Props.GetString(PropMsgReplyTo),
+		Expiration:      msg.Props.GetString(PropMsgExpiration),
+		MessageId:       msg.Props.GetString(PropMsgMessageID),
+		Timestamp:       msg.Props.GetTime(PropMsgTimestamp),
+		Type:            msg.Props.GetString(PropMsgType),
+		UserId:          msg.Props.GetString(PropMsgUserID),
+		AppId:           msg.Props.GetString(PropMsgAppID),
+		Body:            msg.Body,
+	}
+	if !msg.Props.IsEvent() {
+		return pubMsg, nil
+	}
+
+	if msg.Props.EventMode() == broker.EventModeStructured {
+		if pubMsg.ContentEncoding != "" && pubMsg.ContentEncoding != broker.ContentEncodingIdentity {
+			return pubMsg, xerr.New("content encoding is not supported for structured mode events")
+		}
+		eventMsg := broker.Message{Body: msg.Body, Props: maps.Clone(msg.Props)}
+		eventMsg.Props[broker.PropMsgContentType] = pubMsg.ContentType
+		body, err := broker.MarshalStructuredEvent(eventMsg)
+		if err != nil {
+			return pubMsg, err
+		}
+		pubMsg.Body = body
+		pubMsg.ContentType = broker.MediaTypeCloudEventsJSON
+
+		return pubMsg, nil
+	}
+
+	if err := broker.ValidateEvent(msg.Props); err != nil {
+		return pubMsg, err
+	}
+	pubMsg.Headers = make(amqp.Table)
+	setEventHeaders(pubMsg.Headers, msg.Props)
+
+	return pubMsg, nil
+}
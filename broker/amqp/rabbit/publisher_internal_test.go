@@ -0,0 +1,104 @@
+package rabbit
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestNewPublishing(t *testing.T) {
+	t.Parallel()
+
+	t.Run("plain message", testNewPublishingPlainMessage)
+	t.Run("re-published event message", testNewPublishingRepublishedEvent)
+	t.Run("message codec props are mapped", testNewPublishingMessageCodecProps)
+}
+
+func testNewPublishingPlainMessage(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	consumedMsg := ConvertToMessage(amqp.Delivery{
+		Headers: amqp.Table{
+			"x-death":             []any{amqp.Table{"count": int64(1)}},
+			"x-first-death-queue": "orders",
+		},
+		ContentType:   "application/json",
+		CorrelationId: "corr-1",
+		MessageId:     "msg-1",
+		Body:          []byte(`{"id":"ord-123"}`),
+	})
+
+	// act
+	pubMsg, err := newPublishing(consumedMsg)
+
+	// assert
+	assert.RequireNil(t, err)
+	assert.Equal(t, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: "corr-1",
+		MessageId:     "msg-1",
+		Body:          []byte(`{"id":"ord-123"}`),
+	}, pubMsg)
+}
+
+func testNewPublishingRepublishedEvent(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	consumedMsg := ConvertToMessage(amqp.Delivery{
+		Headers: amqp.Table{
+			"x-death":                 []any{amqp.Table{"count": int64(1)}},
+			"cloudEvents:specversion": "1.0",
+			"cloudEvents:id":          "evt-1",
+			"cloudEvents:source":      "/shop/orders",
+			"cloudEvents:type":        "com.shop.order.placed.v1",
+		},
+		Body: []byte(`{"id":"ord-123"}`),
+	})
+	consumedMsg.Props[broker.PropEventType] = "com.shop.order.placed.v2" // upcasted
+
+	// act
+	pubMsg, err := newPublishing(consumedMsg)
+
+	// assert
+	assert.RequireNil(t, err)
+	assert.Equal(t, amqp.Table{
+		"cloudEvents:specversion": "1.0",
+		"cloudEvents:id":          "evt-1",
+		"cloudEvents:source":      "/shop/orders",
+		"cloudEvents:type":        "com.shop.order.placed.v2",
+	}, pubMsg.Headers)
+}
+
+func testNewPublishingMessageCodecProps(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	type order struct {
+		ID string `json:"id"`
+	}
+	codec := broker.NewMessageCodec[order](
+		broker.JSONCodec{},
+		broker.MessageCodecConfig{ContentEncoding: broker.ContentEncodingGzip},
+	)
+	msg, err := codec.Encode(order{ID: "ord-123"}, broker.Props{PropMsgMessageID: "msg-1"})
+	assert.RequireNil(t, err)
+
+	// act
+	pubMsg, err := newPublishing(msg)
+	resultMsg := ConvertToMessage(deliveryFromPublishing(pubMsg))
+	resultOrder, errDecode := codec.Decode(resultMsg)
+
+	// assert
+	assert.RequireNil(t, err)
+	assert.Equal(t, "application/json", pubMsg.ContentType)
+	assert.Equal(t, broker.ContentEncodingGzip, pubMsg.ContentEncoding)
+	assert.Equal(t, "application/json", resultMsg.Props.GetString(PropMsgContentType))
+	assert.Equal(t, broker.ContentEncodingGzip, resultMsg.Props.GetString(PropMsgContentEncoding))
+	assert.Nil(t, errDecode)
+	assert.Equal(t, order{ID: "ord-123"}, resultOrder)
+}
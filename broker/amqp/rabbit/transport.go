@@ -23,6 +23,16 @@ type rabbitmqTransport struct {
 	consummersStopped chan struct{}
 	logger            *slog.Logger
 	mu                *sync.RWMutex
+	config            TransportConfig
+}
+
+// TransportConfig holds the configuration for [NewRabbitMQTransportWithConfig].
+type TransportConfig struct {
+	// MetricsRecorder records consumers metrics. Defaults to [broker.NopMetricsRecorder].
+	MetricsRecorder broker.MetricsRecorder
+	// PanicGuard configures the recovery of panics occurred while consuming messages.
+	// Defaults to a config with a de-duplication window of 1 minute.
+	PanicGuard *broker.PanicGuardConfig
 }
 
 // NewRabbitMQTransport instantiates a new RabbitMQ transport.
@@ -31,12 +41,30 @@ func NewRabbitMQTransport(
 	logger *slog.Logger,
 	consumers ...broker.Consumer,
 ) xtransport.Transport {
+	return NewRabbitMQTransportWithConfig(connFac, logger, TransportConfig{}, consumers...)
+}
+
+// NewRabbitMQTransportWithConfig instantiates a new RabbitMQ transport, configured through given config.
+func NewRabbitMQTransportWithConfig(
+	connFac ConnectionFactory,
+	logger *slog.Logger,
+	config TransportConfig,
+	consumers ...broker.Consumer,
+) xtransport.Transport {
+	if config.MetricsRecorder == nil {
+		config.MetricsRecorder = broker.NopMetricsRecorder{}
+	}
+	if config.PanicGuard == nil {
+		config.PanicGuard = &broker.PanicGuardConfig{DedupWindow: time.Minute}
+	}
+
 	return &rabbitmqTransport{
 		connFac:           connFac,
 		consumers:         consumers,
 		consummersStopped: make(chan struct{}),
 		logger:            logger,
 		mu:                new(sync.RWMutex),
+		config:            config,
 	}
 }
 
@@ -313,10 +341,16 @@ func (rt *rabbitmqTransport) consumeMessages(
 	skippedCount := 0
 	var ackResult byte
 
+	var (
+		consumerName = consumer.Props().GetString(PropConsumerConsumeName)
+		queueName    = consumer.Props().GetString(PropConsumerQueueName)
+		recorder     = rt.config.MetricsRecorder
+	)
 	logger := rt.logger.With(
-		"consumer", consumer.Props().GetString(PropConsumerConsumeName),
-		"queue", consumer.Props().GetString(PropConsumerQueueName),
+		"consumer", consumerName,
+		"queue", queueName,
 	)
+	panicGuard := broker.NewPanicGuard(*rt.config.PanicGuard, logger)
 
 	for msg := range msgChan {
 		var newCtx context.Context
@@ -335,17 +369,26 @@ func (rt *rabbitmqTransport) consumeMessages(
 				lgr.Error("could not NACK-Requeue message at shutdown", "err", xerr.Wrap(err, ""))
 			} else {
 				skippedCount++
+				recorder.MessageSkipped(consumerName, queueName)
 			}
 
 			continue
 		}
 
-		if IsRetried(msg) && GetOriginQueue(msg) != consumer.Props().GetString(PropConsumerQueueName) {
+		if IsRetried(msg) && GetOriginQueue(msg) != queueName {
 			// skip retried messages that did not originate from this consumer's queue
 			// multiple consumers might share the same DLX and routing key.
 			ackResult = broker.ConsumeResultAck
 		} else {
-			ackResult = consumer.Consume(newCtx, ConvertToMessage(msg))
+			if msg.Redelivered {
+				recorder.MessageRedelivered(consumerName, queueName)
+			}
+			if retryCount := RetryCount(msg); retryCount > 0 {
+				recorder.MessageRetried(consumerName, queueName, retryCount)
+			}
+			start := time.Now()
+			ackResult = panicGuard.Consume(newCtx, consumer, ConvertToMessage(msg))
+			recorder.MessageConsumed(consumerName, queueName, ackResult, time.Since(start))
 			if IsRetried(msg) &&
 				consumer.Props().GetInt(PropConsumerConsumeInternalRetryMax) > 0 &&
 				RetryCount(msg) >= consumer.Props().GetInt(PropConsumerConsumeInternalRetryMax) &&
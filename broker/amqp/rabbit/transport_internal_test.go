@@ -0,0 +1,163 @@
+package rabbit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/actforgood/xtransport"
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+// acknowledgerMock is an [amqp.Acknowledger] which records the acknowledgements.
+type acknowledgerMock struct {
+	acks     int
+	nacks    int
+	requeues int
+	mu       sync.Mutex
+}
+
+func (ack *acknowledgerMock) Ack(uint64, bool) error {
+	ack.mu.Lock()
+	defer ack.mu.Unlock()
+	ack.acks++
+
+	return nil
+}
+
+func (ack *acknowledgerMock) Nack(_ uint64, _ bool, requeue bool) error {
+	ack.mu.Lock()
+	defer ack.mu.Unlock()
+	if requeue {
+		ack.requeues++
+	} else {
+		ack.nacks++
+	}
+
+	return nil
+}
+
+func (ack *acknowledgerMock) Reject(uint64, bool) error {
+	return nil
+}
+
+// consumerStub is a [broker.Consumer] returning, for each message, the result found in its body.
+type consumerStub struct{}
+
+func (consumerStub) Props() broker.Props {
+	return broker.Props{
+		PropConsumerConsumeName:             "emails",
+		PropConsumerQueueName:               "q_emails",
+		PropConsumerConsumeInternalRetryMax: 3,
+	}
+}
+
+func (consumerStub) Consume(_ context.Context, msg broker.Message) byte {
+	if len(msg.Body) == 0 {
+		panic("intentionally triggered panic")
+	}
+
+	return msg.Body[0]
+}
+
+func TestRabbitMQTransport_consumeMessages(t *testing.T) {
+	t.Parallel()
+
+	t.Run("consume metrics are recorded", testConsumeMessagesMetrics)
+	t.Run("messages are skipped at shutdown", testConsumeMessagesSkippedAtShutdown)
+}
+
+func retryHeaders(count int64) amqp.Table {
+	return amqp.Table{
+		"x-death":             []any{amqp.Table{"count": count}},
+		"x-first-death-queue": "q_emails",
+	}
+}
+
+func testConsumeMessagesMetrics(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		recorder     = new(broker.MetricsRecorderMock)
+		acknowledger = new(acknowledgerMock)
+		subject      = NewRabbitMQTransportWithConfig(
+			nil,
+			slog.New(mock.NewSlogHandler()),
+			TransportConfig{MetricsRecorder: recorder},
+		).(*rabbitmqTransport)
+		msgChan  = make(chan amqp.Delivery, 5)
+		wg       sync.WaitGroup
+		results  = make(map[string]int)
+		retries  []int
+		mu       sync.Mutex
+		consumer = consumerStub{}
+	)
+	recorder.SetMessageConsumedCallback(func(consumer, queue string, result byte, duration time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "emails", consumer)
+		assert.Equal(t, "q_emails", queue)
+		assert.True(t, duration > 0)
+		results[broker.ConsumeResultString(result)]++
+	})
+	recorder.SetMessageRetriedCallback(func(_, _ string, retryCount int) {
+		retries = append(retries, retryCount)
+	})
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger, Body: []byte{broker.ConsumeResultAck}}
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger, Body: []byte{broker.ConsumeResultNackRequeue}, Redelivered: true}
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger, Body: []byte{broker.ConsumeResultNack}, Headers: retryHeaders(1)}
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger, Body: []byte{broker.ConsumeResultNack}, Headers: retryHeaders(3)}
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger} // panics
+	close(msgChan)
+	wg.Add(1)
+
+	// act
+	subject.consumeMessages(xtransport.ContextWithCorrelationID(context.Background(), "abc"), msgChan, &wg, consumer)
+
+	// assert
+	assert.Equal(t, 5, recorder.MessageConsumedCallsCount())
+	assert.Equal(t, map[string]int{"ack": 1, "nack_requeue": 1, "nack": 3}, results)
+	assert.Equal(t, 1, recorder.MessageRedeliveredCallsCount())
+	assert.Equal(t, []int{1, 3}, retries)
+	assert.Equal(t, 0, recorder.MessageSkippedCallsCount())
+	assert.Equal(t, 2, acknowledger.acks) // last retry exceeded max retry count, it got acknowledged.
+	assert.Equal(t, 2, acknowledger.nacks)
+	assert.Equal(t, 1, acknowledger.requeues)
+}
+
+func testConsumeMessagesSkippedAtShutdown(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		recorder     = new(broker.MetricsRecorderMock)
+		acknowledger = new(acknowledgerMock)
+		subject      = NewRabbitMQTransportWithConfig(
+			nil,
+			slog.New(mock.NewSlogHandler()),
+			TransportConfig{MetricsRecorder: recorder},
+		).(*rabbitmqTransport)
+		msgChan = make(chan amqp.Delivery, 2)
+		wg      sync.WaitGroup
+	)
+	subject.shutDown = true
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger, Body: []byte{broker.ConsumeResultAck}}
+	msgChan <- amqp.Delivery{Acknowledger: acknowledger, Body: []byte{broker.ConsumeResultAck}}
+	close(msgChan)
+	wg.Add(1)
+
+	// act
+	subject.consumeMessages(context.Background(), msgChan, &wg, consumerStub{})
+
+	// assert
+	assert.Equal(t, 2, recorder.MessageSkippedCallsCount())
+	assert.Equal(t, 0, recorder.MessageConsumedCallsCount())
+	assert.Equal(t, 2, acknowledger.requeues)
+}
@@ -0,0 +1,302 @@
+package broker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/actforgood/xerr"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/actforgood/xtransport/decoder"
+)
+
+// Message properties common to all brokers, set / read by [MessageCodec].
+// Transports map them onto their own properties, like the AMQP content type / encoding.
+const (
+	PropMsgContentType     = "broker.property.msg.contentType"
+	PropMsgContentEncoding = "broker.property.msg.contentEncoding"
+)
+
+// Content encodings supported by [MessageCodec].
+const (
+	ContentEncodingIdentity = "identity"
+	ContentEncodingGzip     = "gzip"
+)
+
+// Codec serialises Go values into messages' bodies, and decodes them back.
+type Codec interface {
+	// ContentType returns the media type of the serialised values, like "application/json".
+	ContentType() string
+	// Marshal serialises given value.
+	Marshal(value any) ([]byte, error)
+	// Decode decodes contents of given reader into dest, see [decoder.Decoder].
+	Decode(r io.Reader, dest any) error
+}
+
+// JSONCodec is the JSON [Codec].
+type JSONCodec struct{}
+
+// ContentType returns "application/json".
+func (JSONCodec) ContentType() string {
+	return decoder.MediaTypeJSON
+}
+
+// Marshal serialises given value with [json.Marshal].
+func (JSONCodec) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Decode decodes contents with [decoder.DecodeJSON].
+func (JSONCodec) Decode(r io.Reader, dest any) error {
+	return decoder.DecodeJSON(r, dest)
+}
+
+// ProtobufCodec is the protobuf (binary wire format) [Codec]. Values must be [proto.Message]s.
+type ProtobufCodec struct{}
+
+// ContentType returns "application/protobuf".
+func (ProtobufCodec) ContentType() string {
+	return decoder.MediaTypeProtobuf
+}
+
+// Marshal serialises given value with [proto.Marshal].
+func (ProtobufCodec) Marshal(value any) ([]byte, error) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return nil, xerr.New(fmt.Sprintf("protobuf: cannot marshal %T, a proto.Message is expected", value))
+	}
+
+	return proto.Marshal(msg)
+}
+
+// Decode decodes contents with [decoder.DecodeProtobuf].
+func (ProtobufCodec) Decode(r io.Reader, dest any) error {
+	return decoder.DecodeProtobuf(r, dest)
+}
+
+// MessagePackCodec is the MessagePack [Codec].
+// Values are serialised like [json.Marshal] does (so "json" struct tags are honoured),
+// see also [decoder.DecodeMessagePack].
+type MessagePackCodec struct{}
+
+// ContentType returns "application/msgpack".
+func (MessagePackCodec) ContentType() string {
+	return decoder.MediaTypeMessagePack
+}
+
+// Marshal serialises given value as MessagePack.
+func (MessagePackCodec) Marshal(value any) ([]byte, error) {
+	return marshalMessagePack(value)
+}
+
+// Decode decodes contents with [decoder.DecodeMessagePack].
+func (MessagePackCodec) Decode(r io.Reader, dest any) error {
+	return decoder.DecodeMessagePack(r, dest)
+}
+
+// MessageCodecConfig holds the configuration for a [MessageCodec].
+type MessageCodecConfig struct {
+	// ContentEncoding, optional, is the encoding applied upon encoded messages' bodies,
+	// see ContentEncoding* constants. By default, bodies are not compressed.
+	ContentEncoding string
+	// Registry, optional, is used to decode messages having a different content type than codec's one.
+	// Defaults to [decoder.DefaultRegistry].
+	Registry *decoder.Registry
+	// MaxDecodedSize is the max size, in bytes, of a decompressed message body.
+	// Larger bodies are rejected with a [*decoder.TooLargeError]. Defaults to 16Mb.
+	MaxDecodedSize int64
+}
+
+const defaultMaxDecodedSize int64 = 16 << 20
+
+// MessageCodec encodes values of type T into messages, and decodes them back.
+// It is concurrent safe to use.
+//
+// Example:
+//
+//	codec := broker.NewMessageCodec[OrderPlaced](broker.JSONCodec{}, broker.MessageCodecConfig{})
+//	msg, err := codec.Encode(OrderPlaced{ID: "123"}, broker.Props{rabbit.PropPublishRoutingKey: "orders"})
+//	// ...
+//	err = publisher.Publish(ctx, msg)
+type MessageCodec[T any] struct {
+	codec  Codec
+	config MessageCodecConfig
+}
+
+// NewMessageCodec instantiates a new [MessageCodec] upon given codec.
+func NewMessageCodec[T any](codec Codec, config MessageCodecConfig) *MessageCodec[T] {
+	if config.Registry == nil {
+		config.Registry = decoder.DefaultRegistry
+	}
+	if config.MaxDecodedSize <= 0 {
+		config.MaxDecodedSize = defaultMaxDecodedSize
+	}
+
+	return &MessageCodec[T]{
+		codec:  codec,
+		config: config,
+	}
+}
+
+// Encode serialises given value into a message having given props (which are not modified, and can be nil),
+// and [PropMsgContentType], [PropMsgContentEncoding] set accordingly.
+func (c *MessageCodec[T]) Encode(value T, props Props) (Message, error) {
+	body, err := c.codec.Marshal(value)
+	if err != nil {
+		return Message{}, xerr.Wrap(err, "could not marshal message")
+	}
+	msgProps := make(Props, len(props)+2)
+	maps.Copy(msgProps, props)
+	msgProps[PropMsgContentType] = c.codec.ContentType()
+
+	switch c.config.ContentEncoding {
+	case "":
+	case ContentEncodingIdentity:
+		msgProps[PropMsgContentEncoding] = ContentEncodingIdentity
+	case ContentEncodingGzip:
+		var buf bytes.Buffer
+		gzipWriter := gzip.NewWriter(&buf)
+		if _, err := gzipWriter.Write(body); err != nil {
+			return Message{}, xerr.Wrap(err, "could not compress message")
+		}
+		if err := gzipWriter.Close(); err != nil {
+			return Message{}, xerr.Wrap(err, "could not compress message")
+		}
+		body = buf.Bytes()
+		msgProps[PropMsgContentEncoding] = ContentEncodingGzip
+	default:
+		return Message{}, xerr.New("unsupported content encoding " + strconv.Quote(c.config.ContentEncoding))
+	}
+
+	return Message{Body: body, Props: msgProps}, nil
+}
+
+// Decode decodes given message's body into a value of type T.
+// Body is decompressed according to [PropMsgContentEncoding], and decoded with codec,
+// or, if [PropMsgContentType] is set to another media type, with the decoder registered for it.
+// Decoding errors are [decoder] typed errors, like [*decoder.SyntaxError], [*decoder.UnsupportedMediaTypeError],
+// or [*decoder.TooLargeError] if decompressed body exceeds [MessageCodecConfig].MaxDecodedSize.
+func (c *MessageCodec[T]) Decode(msg Message) (T, error) {
+	var (
+		value T
+		r     io.Reader = bytes.NewReader(msg.Body)
+	)
+
+	switch contentEncoding := msg.Props.GetString(PropMsgContentEncoding); contentEncoding {
+	case "", ContentEncodingIdentity:
+	case ContentEncodingGzip:
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			return value, xerr.Wrap(err, "could not decompress message")
+		}
+		defer gzipReader.Close()
+		r = http.MaxBytesReader(nil, gzipReader, c.config.MaxDecodedSize)
+	default:
+		return value, xerr.New("unsupported content encoding " + strconv.Quote(contentEncoding))
+	}
+
+	dest := any(&value)
+	if typ := reflect.TypeFor[T](); typ.Kind() == reflect.Pointer { // like *pb.Order, decode into a new pointee
+		value = reflect.New(typ.Elem()).Interface().(T)
+		dest = value
+	}
+
+	var err error
+	if contentType := msg.Props.GetString(PropMsgContentType); contentType == "" || contentType == c.codec.ContentType() {
+		err = c.codec.Decode(r, dest)
+	} else {
+		err = c.config.Registry.Decode(contentType, r, dest)
+	}
+	if err != nil {
+		var (
+			zero        T
+			tooLargeErr *decoder.TooLargeError
+			maxBytesErr *http.MaxBytesError
+		)
+		if !errors.As(err, &tooLargeErr) && errors.As(err, &maxBytesErr) {
+			err = &decoder.TooLargeError{Limit: maxBytesErr.Limit, Err: err}
+		}
+
+		return zero, err
+	}
+
+	return value, nil
+}
+
+// TypedConsumeFunc consumes a decoded message value, and returns the consume result,
+// see ConsumeResult* constants. Props are the message's props.
+type TypedConsumeFunc[T any] func(ctx context.Context, value T, props Props) byte
+
+// TypedConsumerConfig holds the configuration for a typed consumer, see [NewTypedConsumer].
+type TypedConsumerConfig struct {
+	// Props are consumer's props, see [Consumer.Props].
+	Props Props
+	// DecodeErrorResult is the consume result returned for a message which cannot be decoded.
+	// Defaults to [ConsumeResultNack], as requeueing such a message would most likely result in an endless loop.
+	DecodeErrorResult byte
+	// OnDecodeError, optional, is called with each message which cannot be decoded, and the error.
+	OnDecodeError func(ctx context.Context, msg Message, err error)
+}
+
+// NewTypedConsumer returns a [Consumer] which decodes messages into values of type T with given codec,
+// and passes them to given function.
+//
+// Example:
+//
+//	consumer := broker.NewTypedConsumer(
+//		broker.NewMessageCodec[OrderPlaced](broker.JSONCodec{}, broker.MessageCodecConfig{}),
+//		func(ctx context.Context, order OrderPlaced, props broker.Props) byte {
+//			// process order
+//			return broker.ConsumeResultAck
+//		},
+//		broker.TypedConsumerConfig{Props: consumerProps},
+//	)
+func NewTypedConsumer[T any](
+	codec *MessageCodec[T],
+	consumeFn TypedConsumeFunc[T],
+	config TypedConsumerConfig,
+) Consumer {
+	if config.DecodeErrorResult == 0 {
+		config.DecodeErrorResult = ConsumeResultNack
+	}
+
+	return typedConsumer[T]{
+		codec:     codec,
+		consumeFn: consumeFn,
+		config:    config,
+	}
+}
+
+type typedConsumer[T any] struct {
+	codec     *MessageCodec[T]
+	consumeFn TypedConsumeFunc[T]
+	config    TypedConsumerConfig
+}
+
+// Props ...see [Consumer.Props].
+func (c typedConsumer[T]) Props() Props {
+	return c.config.Props
+}
+
+// Consume ...see [Consumer.Consume].
+func (c typedConsumer[T]) Consume(ctx context.Context, msg Message) byte {
+	value, err := c.codec.Decode(msg)
+	if err != nil {
+		if c.config.OnDecodeError != nil {
+			c.config.OnDecodeError(ctx, msg, err)
+		}
+
+		return c.config.DecodeErrorResult
+	}
+
+	return c.consumeFn(ctx, value, msg.Props)
+}
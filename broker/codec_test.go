@@ -0,0 +1,326 @@
+package broker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+type testOrder struct {
+	ID        string            `json:"id"`
+	Quantity  int               `json:"quantity"`
+	Delta     int64             `json:"delta"`
+	Big       uint64            `json:"big"`
+	Price     float64           `json:"price"`
+	Paid      bool              `json:"paid"`
+	Notes     *string           `json:"notes"`
+	Tags      []string          `json:"tags"`
+	Attrs     map[string]string `json:"attrs"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Payload   []byte            `json:"payload"`
+}
+
+func newTestOrder() testOrder {
+	return testOrder{
+		ID:        "ord-123",
+		Quantity:  70000,
+		Delta:     -40000,
+		Big:       1 << 63,
+		Price:     12.34,
+		Paid:      true,
+		Tags:      []string{"a", strings.Repeat("b", 40), strings.Repeat("c", 300)},
+		Attrs:     map[string]string{"color": "black", "size": "M"},
+		CreatedAt: time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC),
+		Payload:   []byte{0, 1, 2},
+	}
+}
+
+func TestMessageCodec(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json - encodes and decodes back", testMessageCodecRoundTrip(broker.JSONCodec{}, ""))
+	t.Run("msgpack - encodes and decodes back", testMessageCodecRoundTrip(broker.MessagePackCodec{}, ""))
+	t.Run("gzip json - encodes and decodes back",
+		testMessageCodecRoundTrip(broker.JSONCodec{}, broker.ContentEncodingGzip))
+	t.Run("identity msgpack - encodes and decodes back",
+		testMessageCodecRoundTrip(broker.MessagePackCodec{}, broker.ContentEncodingIdentity))
+	t.Run("protobuf - encodes and decodes back", testMessageCodecProtobuf)
+	t.Run("decodes other content type with registry", testMessageCodecOtherContentType)
+	t.Run("returns error", testMessageCodecErr)
+}
+
+func testMessageCodecRoundTrip(codec broker.Codec, contentEncoding string) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		var (
+			subject = broker.NewMessageCodec[testOrder](codec, broker.MessageCodecConfig{ContentEncoding: contentEncoding})
+			order   = newTestOrder()
+			props   = broker.Props{"app": "shop"}
+		)
+
+		// act
+		msg, errEncode := subject.Encode(order, props)
+		decodedOrder, errDecode := subject.Decode(msg)
+
+		// assert
+		assert.Nil(t, errEncode)
+		assert.Nil(t, errDecode)
+		assert.Equal(t, order, decodedOrder)
+		assert.Equal(t, codec.ContentType(), msg.Props.GetString(broker.PropMsgContentType))
+		assert.Equal(t, contentEncoding, msg.Props.GetString(broker.PropMsgContentEncoding))
+		assert.Equal(t, "shop", msg.Props.GetString("app"))
+		assert.Equal(t, 1, len(props)) // given props are not modified
+	}
+}
+
+func testMessageCodecProtobuf(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := broker.NewMessageCodec[*wrapperspb.StringValue](broker.ProtobufCodec{}, broker.MessageCodecConfig{})
+
+	// act
+	msg, errEncode := subject.Encode(wrapperspb.String("John Doe"), nil)
+	value, errDecode := subject.Decode(msg)
+
+	// assert
+	assert.Nil(t, errEncode)
+	if assert.Nil(t, errDecode) {
+		assert.Equal(t, "John Doe", value.GetValue())
+	}
+	assert.Equal(t, decoder.MediaTypeProtobuf, msg.Props.GetString(broker.PropMsgContentType))
+}
+
+func testMessageCodecOtherContentType(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = broker.NewMessageCodec[testOrder](broker.JSONCodec{}, broker.MessageCodecConfig{})
+		order   = newTestOrder()
+	)
+	body, err := broker.MessagePackCodec{}.Marshal(order)
+	assert.RequireNil(t, err)
+	msg := broker.Message{
+		Body:  body,
+		Props: broker.Props{broker.PropMsgContentType: decoder.MediaTypeMessagePackX},
+	}
+
+	// act
+	decodedOrder, err := subject.Decode(msg)
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, order, decodedOrder)
+}
+
+func testMessageCodecErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := broker.NewMessageCodec[testOrder](broker.JSONCodec{}, broker.MessageCodecConfig{})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, err := subject.Decode(broker.Message{
+			Body:  []byte(`{}`),
+			Props: broker.Props{broker.PropMsgContentType: "text/plain"},
+		})
+
+		// assert
+		assert.True(t, errors.Is(err, decoder.ErrUnsupportedMediaType))
+	})
+
+	t.Run("unsupported content encoding", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, errDecode := subject.Decode(broker.Message{
+			Body:  []byte(`{}`),
+			Props: broker.Props{broker.PropMsgContentEncoding: "br"},
+		})
+		_, errEncode := broker.NewMessageCodec[testOrder](
+			broker.JSONCodec{},
+			broker.MessageCodecConfig{ContentEncoding: "br"},
+		).Encode(testOrder{}, nil)
+
+		// assert
+		if assert.NotNil(t, errDecode) {
+			assert.Equal(t, `unsupported content encoding "br"`, errDecode.Error())
+		}
+		if assert.NotNil(t, errEncode) {
+			assert.Equal(t, `unsupported content encoding "br"`, errEncode.Error())
+		}
+	})
+
+	t.Run("malformed gzip body", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, err := subject.Decode(broker.Message{
+			Body:  []byte(`{}`),
+			Props: broker.Props{broker.PropMsgContentEncoding: broker.ContentEncodingGzip},
+		})
+
+		// assert
+		assert.NotNil(t, err)
+	})
+
+	t.Run("decompressed body too large", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		codec := broker.NewMessageCodec[string](
+			broker.JSONCodec{},
+			broker.MessageCodecConfig{ContentEncoding: broker.ContentEncodingGzip, MaxDecodedSize: 1024},
+		)
+		msg, errEncode := codec.Encode(strings.Repeat("a", 4096), nil)
+		assert.RequireNil(t, errEncode)
+
+		// act
+		value, err := codec.Decode(msg)
+
+		// assert
+		var tooLargeErr *decoder.TooLargeError
+		if assert.True(t, errors.As(err, &tooLargeErr)) {
+			assert.Equal(t, int64(1024), tooLargeErr.Limit)
+		}
+		assert.Equal(t, "", value)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		order, err := subject.Decode(broker.Message{Body: []byte(`{"id":"ord-123","quantity":"x"}`)})
+
+		// assert
+		var mismatchErr *decoder.TypeMismatchError
+		if assert.True(t, errors.As(err, &mismatchErr)) {
+			assert.Equal(t, "quantity", mismatchErr.Field)
+		}
+		assert.Equal(t, testOrder{}, order)
+	})
+
+	t.Run("protobuf codec with non proto message", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, err := broker.NewMessageCodec[testOrder](broker.ProtobufCodec{}, broker.MessageCodecConfig{}).
+			Encode(testOrder{}, nil)
+
+		// assert
+		if assert.NotNil(t, err) {
+			assert.True(t, strings.Contains(err.Error(), "a proto.Message is expected"))
+		}
+	})
+}
+
+func TestMessagePackCodec_Marshal(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	value := map[string]any{
+		"b": []any{nil, true, false, 1, -1, 200, -200, 1.5},
+		"a": "x",
+	}
+	expected := []byte{
+		0x82,
+		0xa1, 'a', 0xa1, 'x',
+		0xa1, 'b', 0x98, 0xc0, 0xc3, 0xc2, 0x01, 0xff, 0xcc, 0xc8, 0xd1, 0xff, 0x38,
+		0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+	}
+
+	// act
+	result, err := broker.MessagePackCodec{}.Marshal(value)
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestNewTypedConsumer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("consumes decoded value", testNewTypedConsumerSuccess)
+	t.Run("returns configured result for undecodable message", testNewTypedConsumerDecodeErr)
+}
+
+func testNewTypedConsumerSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		codec         = broker.NewMessageCodec[testOrder](broker.JSONCodec{}, broker.MessageCodecConfig{})
+		consumerProps = broker.Props{"name": "orders"}
+		consumedOrder testOrder
+		consumedProps broker.Props
+		subject       = broker.NewTypedConsumer(
+			codec,
+			func(_ context.Context, order testOrder, props broker.Props) byte {
+				consumedOrder = order
+				consumedProps = props
+
+				return broker.ConsumeResultAck
+			},
+			broker.TypedConsumerConfig{Props: consumerProps},
+		)
+		order = newTestOrder()
+	)
+	msg, err := codec.Encode(order, broker.Props{"app": "shop"})
+	assert.RequireNil(t, err)
+
+	// act
+	result := subject.Consume(context.Background(), msg)
+
+	// assert
+	assert.Equal(t, broker.ConsumeResultAck, result)
+	assert.Equal(t, order, consumedOrder)
+	assert.Equal(t, "shop", consumedProps.GetString("app"))
+	assert.Equal(t, consumerProps, subject.Props())
+}
+
+func testNewTypedConsumerDecodeErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		codec       = broker.NewMessageCodec[testOrder](broker.JSONCodec{}, broker.MessageCodecConfig{})
+		consumeFn   = func(context.Context, testOrder, broker.Props) byte { return broker.ConsumeResultAck }
+		msg         = broker.Message{Body: []byte(`{"id":`)}
+		reportedErr error
+		reportedMsg broker.Message
+	)
+	defaultSubject := broker.NewTypedConsumer(codec, consumeFn, broker.TypedConsumerConfig{})
+	configuredSubject := broker.NewTypedConsumer(codec, consumeFn, broker.TypedConsumerConfig{
+		DecodeErrorResult: broker.ConsumeResultNackRequeue,
+		OnDecodeError: func(_ context.Context, msg broker.Message, err error) {
+			reportedMsg = msg
+			reportedErr = err
+		},
+	})
+
+	// act
+	defaultResult := defaultSubject.Consume(context.Background(), msg)
+	configuredResult := configuredSubject.Consume(context.Background(), msg)
+
+	// assert
+	assert.Equal(t, broker.ConsumeResultNack, defaultResult)
+	assert.Equal(t, broker.ConsumeResultNackRequeue, configuredResult)
+	var syntaxErr *decoder.SyntaxError
+	assert.True(t, errors.As(reportedErr, &syntaxErr))
+	assert.True(t, bytes.Equal(msg.Body, reportedMsg.Body))
+}
@@ -0,0 +1,359 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actforgood/xerr"
+)
+
+// CloudEvents (https://cloudevents.io) 1.0 attributes' props.
+// Event data is the message's body, and its content type (the "datacontenttype" attribute), the [PropMsgContentType].
+const (
+	PropEventSpecVersion = "broker.property.event.specVersion"
+	PropEventID          = "broker.property.event.id"
+	PropEventSource      = "broker.property.event.source"
+	PropEventType        = "broker.property.event.type"
+	PropEventSubject     = "broker.property.event.subject"
+	PropEventTime        = "broker.property.event.time"
+	PropEventDataSchema  = "broker.property.event.dataSchema"
+	// PropEventExtensions holds the extension attributes, as a map[string]any.
+	PropEventExtensions = "broker.property.event.extensions"
+	// PropEventMode is the content mode in which the event is (to be) transferred, see EventMode* constants.
+	PropEventMode = "broker.property.event.mode"
+)
+
+// CloudEvents content modes.
+const (
+	// EventModeBinary transfers event's attributes as message's properties / headers,
+	// and event's data as message's body. It is the default mode.
+	EventModeBinary = "binary"
+	// EventModeStructured transfers the whole event, encoded as JSON, as message's body.
+	EventModeStructured = "structured"
+)
+
+const (
+	// EventSpecVersion is the supported CloudEvents specification version.
+	EventSpecVersion = "1.0"
+	// MediaTypeCloudEventsJSON is the content type of structured mode JSON encoded events.
+	MediaTypeCloudEventsJSON = "application/cloudevents+json"
+)
+
+// Event holds a CloudEvents 1.0 event's attributes, see also [Props.EventID] and the other accessors.
+//
+// Example of publishing an event:
+//
+//	event := broker.Event{ID: uuid.NewString(), Source: "/shop/orders", Type: "com.shop.order.placed.v2"}
+//	msg, err := orderCodec.Encode(order, event.Props())
+//	// ...
+//	err = publisher.Publish(ctx, msg)
+type Event struct {
+	// ID identifies the event. Required.
+	ID string
+	// Source identifies the context in which the event happened, like "/shop/orders". Required.
+	Source string
+	// Type is the type of the event, usually including a version, like "com.shop.order.placed.v2". Required.
+	Type string
+	// Subject, optional, is the subject of the event in the context of the source, like the order ID.
+	Subject string
+	// Time, optional, is the time when the event happened.
+	Time time.Time
+	// DataSchema, optional, is the URI of the schema event's data adheres to.
+	DataSchema string
+	// Extensions, optional, are the extension attributes.
+	// Names must consist of lower-case letters and digits, see [ValidateEvent].
+	Extensions map[string]any
+	// Mode is the content mode in which the event is to be transferred, see EventMode* constants.
+	// Defaults to [EventModeBinary].
+	Mode string
+}
+
+// Props returns the props describing the event, to be set upon a message.
+func (event Event) Props() Props {
+	props := Props{
+		PropEventSpecVersion: EventSpecVersion,
+		PropEventID:          event.ID,
+		PropEventSource:      event.Source,
+		PropEventType:        event.Type,
+	}
+	if event.Subject != "" {
+		props[PropEventSubject] = event.Subject
+	}
+	if !event.Time.IsZero() {
+		props[PropEventTime] = event.Time
+	}
+	if event.DataSchema != "" {
+		props[PropEventDataSchema] = event.DataSchema
+	}
+	if len(event.Extensions) > 0 {
+		props[PropEventExtensions] = maps.Clone(event.Extensions)
+	}
+	if event.Mode != "" {
+		props[PropEventMode] = event.Mode
+	}
+
+	return props
+}
+
+// IsEvent returns true if props describe a CloudEvents event.
+func (p Props) IsEvent() bool {
+	return p.GetString(PropEventSpecVersion) != ""
+}
+
+// EventSpecVersion returns the "specversion" event attribute.
+func (p Props) EventSpecVersion() string {
+	return p.GetString(PropEventSpecVersion)
+}
+
+// EventID returns the "id" event attribute.
+func (p Props) EventID() string {
+	return p.GetString(PropEventID)
+}
+
+// EventSource returns the "source" event attribute.
+func (p Props) EventSource() string {
+	return p.GetString(PropEventSource)
+}
+
+// EventType returns the "type" event attribute.
+func (p Props) EventType() string {
+	return p.GetString(PropEventType)
+}
+
+// EventSubject returns the "subject" event attribute.
+func (p Props) EventSubject() string {
+	return p.GetString(PropEventSubject)
+}
+
+// EventTime returns the "time" event attribute.
+func (p Props) EventTime() time.Time {
+	return p.GetTime(PropEventTime)
+}
+
+// EventDataSchema returns the "dataschema" event attribute.
+func (p Props) EventDataSchema() string {
+	return p.GetString(PropEventDataSchema)
+}
+
+// EventExtensions returns the extension event attributes.
+func (p Props) EventExtensions() map[string]any {
+	if extensions, ok := p.Get(PropEventExtensions).(map[string]any); ok {
+		return extensions
+	}
+
+	return nil
+}
+
+// EventMode returns the content mode of the event, see EventMode* constants.
+func (p Props) EventMode() string {
+	if mode := p.GetString(PropEventMode); mode != "" {
+		return mode
+	}
+
+	return EventModeBinary
+}
+
+// reservedEventAttributes are the context attributes defined by the specification,
+// which cannot be used as extensions' names.
+var reservedEventAttributes = map[string]struct{}{
+	"specversion":     {},
+	"id":              {},
+	"source":          {},
+	"type":            {},
+	"subject":         {},
+	"time":            {},
+	"dataschema":      {},
+	"datacontenttype": {},
+	"data":            {},
+	"data_base64":     {},
+}
+
+// ValidateEvent checks that props describe a valid CloudEvents 1.0 event:
+// supported spec version, required attributes present, valid extensions' names.
+func ValidateEvent(props Props) error {
+	if specVersion := props.EventSpecVersion(); specVersion != EventSpecVersion {
+		return xerr.New("unsupported cloud event spec version " + strconv.Quote(specVersion))
+	}
+	for _, attr := range [...]struct{ name, value string }{
+		{name: "id", value: props.EventID()},
+		{name: "source", value: props.EventSource()},
+		{name: "type", value: props.EventType()},
+	} {
+		if attr.value == "" {
+			return xerr.New("cloud event " + attr.name + " attribute is required")
+		}
+	}
+	for extName := range props.EventExtensions() {
+		if !isValidEventExtensionName(extName) {
+			return xerr.New("invalid cloud event extension name " + strconv.Quote(extName))
+		}
+	}
+	if mode := props.EventMode(); mode != EventModeBinary && mode != EventModeStructured {
+		return xerr.New("unsupported cloud event mode " + strconv.Quote(mode))
+	}
+
+	return nil
+}
+
+// isValidEventExtensionName checks an extension name consists of lower-case letters and digits,
+// and is not a reserved attribute name.
+func isValidEventExtensionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	if _, reserved := reservedEventAttributes[name]; reserved {
+		return false
+	}
+	for _, char := range name {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarshalStructuredEvent encodes given event message (its event props, content type and body)
+// as a structured mode JSON event, see [MediaTypeCloudEventsJSON].
+// JSON data is embedded as it is ("data"), other data is base64 encoded ("data_base64").
+func MarshalStructuredEvent(msg Message) ([]byte, error) {
+	if err := ValidateEvent(msg.Props); err != nil {
+		return nil, err
+	}
+	envelope := make(map[string]any, 8+len(msg.Props.EventExtensions()))
+	maps.Copy(envelope, msg.Props.EventExtensions())
+	envelope["specversion"] = msg.Props.EventSpecVersion()
+	envelope["id"] = msg.Props.EventID()
+	envelope["source"] = msg.Props.EventSource()
+	envelope["type"] = msg.Props.EventType()
+	if subject := msg.Props.EventSubject(); subject != "" {
+		envelope["subject"] = subject
+	}
+	if eventTime := msg.Props.EventTime(); !eventTime.IsZero() {
+		envelope["time"] = eventTime.Format(time.RFC3339Nano)
+	}
+	if dataSchema := msg.Props.EventDataSchema(); dataSchema != "" {
+		envelope["dataschema"] = dataSchema
+	}
+	contentType := msg.Props.GetString(PropMsgContentType)
+	if contentType != "" {
+		envelope["datacontenttype"] = contentType
+	}
+	if len(msg.Body) > 0 {
+		if isJSONMediaType(contentType) && json.Valid(msg.Body) {
+			envelope["data"] = json.RawMessage(msg.Body)
+		} else {
+			envelope["data_base64"] = base64.StdEncoding.EncodeToString(msg.Body)
+		}
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, xerr.Wrap(err, "could not marshal cloud event")
+	}
+
+	return data, nil
+}
+
+// UnmarshalStructuredEvent decodes a structured mode JSON event (see [MediaTypeCloudEventsJSON])
+// into a message, having event's data as body, and event's attributes as props,
+// with [PropEventMode] set to [EventModeStructured].
+func UnmarshalStructuredEvent(data []byte) (Message, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return Message{}, xerr.Wrap(err, "could not unmarshal cloud event")
+	}
+
+	props := Props{PropEventMode: EventModeStructured}
+	extensions := make(map[string]any)
+	var body []byte
+	for attrName, rawValue := range envelope {
+		var err error
+		switch attrName {
+		case "specversion":
+			err = unmarshalEventStringAttr(rawValue, props, PropEventSpecVersion)
+		case "id":
+			err = unmarshalEventStringAttr(rawValue, props, PropEventID)
+		case "source":
+			err = unmarshalEventStringAttr(rawValue, props, PropEventSource)
+		case "type":
+			err = unmarshalEventStringAttr(rawValue, props, PropEventType)
+		case "subject":
+			err = unmarshalEventStringAttr(rawValue, props, PropEventSubject)
+		case "dataschema":
+			err = unmarshalEventStringAttr(rawValue, props, PropEventDataSchema)
+		case "datacontenttype":
+			err = unmarshalEventStringAttr(rawValue, props, PropMsgContentType)
+		case "time":
+			var timeStr string
+			if err = json.Unmarshal(rawValue, &timeStr); err == nil {
+				props[PropEventTime], err = time.Parse(time.RFC3339Nano, timeStr)
+			}
+		case "data", "data_base64":
+			// processed after datacontenttype is known
+		default:
+			var extValue any
+			err = json.Unmarshal(rawValue, &extValue)
+			extensions[attrName] = extValue
+		}
+		if err != nil {
+			return Message{}, xerr.Wrap(err, "invalid cloud event "+attrName+" attribute")
+		}
+	}
+
+	if rawData, found := envelope["data_base64"]; found {
+		var dataStr string
+		if err := json.Unmarshal(rawData, &dataStr); err != nil {
+			return Message{}, xerr.Wrap(err, "invalid cloud event data_base64")
+		}
+		decodedData, err := base64.StdEncoding.DecodeString(dataStr)
+		if err != nil {
+			return Message{}, xerr.Wrap(err, "invalid cloud event data_base64")
+		}
+		body = decodedData
+	} else if rawData, found := envelope["data"]; found {
+		body = rawData
+		var dataStr string
+		if !isJSONMediaType(props.GetString(PropMsgContentType)) && json.Unmarshal(rawData, &dataStr) == nil {
+			body = []byte(dataStr) // like a "text/plain" data
+		}
+	}
+	if len(extensions) > 0 {
+		props[PropEventExtensions] = extensions
+	}
+	if err := ValidateEvent(props); err != nil {
+		return Message{}, err
+	}
+
+	return Message{Body: bytes.Clone(body), Props: props}, nil
+}
+
+// unmarshalEventStringAttr decodes a string attribute into props.
+func unmarshalEventStringAttr(rawValue json.RawMessage, props Props, propName string) error {
+	var value string
+	if err := json.Unmarshal(rawValue, &value); err != nil {
+		return err
+	}
+	props[propName] = value
+
+	return nil
+}
+
+// isJSONMediaType returns true for an empty (defaults to JSON in structured mode), JSON, or "+json" content type.
+func isJSONMediaType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
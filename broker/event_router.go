@@ -0,0 +1,129 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/actforgood/xerr"
+)
+
+// ErrNoEventHandler is the error reported by an [EventRouter] for a message having no registered handler.
+var ErrNoEventHandler = errors.New("no event handler")
+
+// maxUpcasts is the maximum no. of upcasts applied upon a message, guarding against upcasters cycles.
+const maxUpcasts = 32
+
+// Upcaster migrates a message holding an old version of an event into a newer version,
+// usually by changing its [PropEventType] (like "com.shop.order.placed.v1" => "com.shop.order.placed.v2")
+// and its body. It should not modify given message's props, but a copy of them, see [maps.Clone].
+type Upcaster func(ctx context.Context, msg Message) (Message, error)
+
+// EventRouterConfig holds the configuration for an [EventRouter].
+type EventRouterConfig struct {
+	// Props are consumer's props, see [Consumer.Props].
+	Props Props
+	// Fallback, optional, consumes messages having no registered handler for their event type
+	// (including messages which are not events).
+	Fallback Consumer
+	// ErrorResult is the consume result returned for a message having no handler, or which cannot be upcasted.
+	// Defaults to [ConsumeResultNack].
+	ErrorResult byte
+	// OnError, optional, is called with each message having no handler ([ErrNoEventHandler] is reported),
+	// or which cannot be upcasted, and the error.
+	OnError func(ctx context.Context, msg Message, err error)
+}
+
+// EventRouter is a [Consumer] which routes messages to different handlers within the same consumer,
+// based on their CloudEvents type (see [Props.EventType]). Before routing, messages holding old
+// versions of events are migrated with registered upcasters.
+// Handlers and upcasters should be registered before starting consuming.
+//
+// Example:
+//
+//	router := broker.NewEventRouter(broker.EventRouterConfig{Props: consumerProps}).
+//		Handle("com.shop.order.placed.v2", broker.NewTypedConsumer(orderPlacedCodec, onOrderPlaced, typedConfig)).
+//		Handle("com.shop.order.cancelled.v1", broker.NewTypedConsumer(orderCancelledCodec, onOrderCancelled, typedConfig)).
+//		Upcast("com.shop.order.placed.v1", upcastOrderPlacedV1)
+type EventRouter struct {
+	config    EventRouterConfig
+	handlers  map[string]Consumer
+	upcasters map[string]Upcaster
+}
+
+// NewEventRouter instantiates a new [EventRouter].
+func NewEventRouter(config EventRouterConfig) *EventRouter {
+	if config.ErrorResult == 0 {
+		config.ErrorResult = ConsumeResultNack
+	}
+
+	return &EventRouter{
+		config:    config,
+		handlers:  make(map[string]Consumer),
+		upcasters: make(map[string]Upcaster),
+	}
+}
+
+// Handle registers the handler of given event type.
+func (router *EventRouter) Handle(eventType string, handler Consumer) *EventRouter {
+	router.handlers[eventType] = handler
+
+	return router
+}
+
+// Upcast registers the upcaster of given (old) event type.
+// Upcasters are chained: if the upcasted message's type has an upcaster too, it is applied, and so on.
+func (router *EventRouter) Upcast(eventType string, upcaster Upcaster) *EventRouter {
+	router.upcasters[eventType] = upcaster
+
+	return router
+}
+
+// Props ...see [Consumer.Props].
+func (router *EventRouter) Props() Props {
+	return router.config.Props
+}
+
+// Consume upcasts the message, if needed, and passes it to the handler registered for its event type.
+func (router *EventRouter) Consume(ctx context.Context, msg Message) byte {
+	msg, err := router.upcast(ctx, msg)
+	if err != nil {
+		return router.fail(ctx, msg, err)
+	}
+
+	if handler, found := router.handlers[msg.Props.EventType()]; found {
+		return handler.Consume(ctx, msg)
+	}
+	if router.config.Fallback != nil {
+		return router.config.Fallback.Consume(ctx, msg)
+	}
+
+	return router.fail(ctx, msg, ErrNoEventHandler)
+}
+
+// upcast applies the upcasters chain upon given message.
+func (router *EventRouter) upcast(ctx context.Context, msg Message) (Message, error) {
+	for range maxUpcasts {
+		eventType := msg.Props.EventType()
+		upcaster, found := router.upcasters[eventType]
+		if !found {
+			return msg, nil
+		}
+		upcastedMsg, err := upcaster(ctx, msg)
+		if err != nil {
+			return msg, xerr.Wrap(err, "could not upcast event "+strconv.Quote(eventType))
+		}
+		msg = upcastedMsg
+	}
+
+	return msg, xerr.New("too many upcasts, last event type " + strconv.Quote(msg.Props.EventType()))
+}
+
+// fail reports given error, and returns the configured error result.
+func (router *EventRouter) fail(ctx context.Context, msg Message, err error) byte {
+	if router.config.OnError != nil {
+		router.config.OnError(ctx, msg, err)
+	}
+
+	return router.config.ErrorResult
+}
@@ -0,0 +1,177 @@
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+// newTestEventMessage returns a message holding an event of given type.
+func newTestEventMessage(eventType, body string) broker.Message {
+	return broker.Message{
+		Body:  []byte(body),
+		Props: broker.Event{ID: "evt-1", Source: "/shop/orders", Type: eventType}.Props(),
+	}
+}
+
+// recordingConsumer returns a consumer recording consumed messages' bodies, and returning given result.
+func recordingConsumer(result byte, bodies *[]string) broker.Consumer {
+	return consumerFunc(func(_ context.Context, msg broker.Message) byte {
+		*bodies = append(*bodies, string(msg.Body))
+
+		return result
+	})
+}
+
+func TestEventRouter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("routes by event type", testEventRouterRoutes)
+	t.Run("upcasts old events", testEventRouterUpcasts)
+	t.Run("returns error result", testEventRouterErr)
+}
+
+func testEventRouterRoutes(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		placed, cancelled, fallback []string
+		consumerProps               = broker.Props{"name": "orders"}
+		subject                     = broker.NewEventRouter(broker.EventRouterConfig{
+			Props:    consumerProps,
+			Fallback: recordingConsumer(broker.ConsumeResultNackRequeue, &fallback),
+		}).
+			Handle("order.placed.v2", recordingConsumer(broker.ConsumeResultAck, &placed)).
+			Handle("order.cancelled.v1", recordingConsumer(broker.ConsumeResultNack, &cancelled))
+	)
+
+	// act
+	results := []byte{
+		subject.Consume(context.Background(), newTestEventMessage("order.placed.v2", "p1")),
+		subject.Consume(context.Background(), newTestEventMessage("order.cancelled.v1", "c1")),
+		subject.Consume(context.Background(), newTestEventMessage("order.shipped.v1", "s1")),
+		subject.Consume(context.Background(), broker.Message{Body: []byte("not an event")}),
+	}
+
+	// assert
+	assert.Equal(t, []byte{
+		broker.ConsumeResultAck,
+		broker.ConsumeResultNack,
+		broker.ConsumeResultNackRequeue,
+		broker.ConsumeResultNackRequeue,
+	}, results)
+	assert.Equal(t, []string{"p1"}, placed)
+	assert.Equal(t, []string{"c1"}, cancelled)
+	assert.Equal(t, []string{"s1", "not an event"}, fallback)
+	assert.Equal(t, consumerProps, subject.Props())
+}
+
+func testEventRouterUpcasts(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		placed   []string
+		upcaster = func(fromVersion, toVersion string) broker.Upcaster {
+			return func(_ context.Context, msg broker.Message) (broker.Message, error) {
+				props := maps.Clone(msg.Props)
+				props[broker.PropEventType] = strings.Replace(msg.Props.EventType(), fromVersion, toVersion, 1)
+
+				return broker.Message{Body: append(msg.Body, toVersion...), Props: props}, nil
+			}
+		}
+		subject = broker.NewEventRouter(broker.EventRouterConfig{}).
+			Handle("order.placed.v3", recordingConsumer(broker.ConsumeResultAck, &placed)).
+			Upcast("order.placed.v1", upcaster("v1", "v2")).
+			Upcast("order.placed.v2", upcaster("v2", "v3"))
+		msg = newTestEventMessage("order.placed.v1", "p1-")
+	)
+
+	// act
+	result := subject.Consume(context.Background(), msg)
+
+	// assert
+	assert.Equal(t, broker.ConsumeResultAck, result)
+	assert.Equal(t, []string{"p1-v2v3"}, placed)
+	assert.Equal(t, "order.placed.v1", msg.Props.EventType()) // original message is not modified
+}
+
+func testEventRouterErr(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name             string
+		router           func(config broker.EventRouterConfig) *broker.EventRouter
+		errorResult      byte
+		expectedResult   byte
+		expectedErrCheck func(err error) bool
+	}{
+		{
+			name: "no handler",
+			router: func(config broker.EventRouterConfig) *broker.EventRouter {
+				return broker.NewEventRouter(config)
+			},
+			expectedResult: broker.ConsumeResultNack,
+			expectedErrCheck: func(err error) bool {
+				return errors.Is(err, broker.ErrNoEventHandler)
+			},
+		},
+		{
+			name: "upcaster error",
+			router: func(config broker.EventRouterConfig) *broker.EventRouter {
+				return broker.NewEventRouter(config).
+					Upcast("order.placed.v1", func(context.Context, broker.Message) (broker.Message, error) {
+						return broker.Message{}, errors.New("intentionally triggered upcast error")
+					})
+			},
+			errorResult:    broker.ConsumeResultNackRequeue,
+			expectedResult: broker.ConsumeResultNackRequeue,
+			expectedErrCheck: func(err error) bool {
+				return strings.HasPrefix(err.Error(), `could not upcast event "order.placed.v1"`)
+			},
+		},
+		{
+			name: "upcasters cycle",
+			router: func(config broker.EventRouterConfig) *broker.EventRouter {
+				return broker.NewEventRouter(config).
+					Upcast("order.placed.v1", func(_ context.Context, msg broker.Message) (broker.Message, error) {
+						return msg, nil
+					})
+			},
+			expectedResult: broker.ConsumeResultNack,
+			expectedErrCheck: func(err error) bool {
+				return err.Error() == `too many upcasts, last event type "order.placed.v1"`
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var reportedErr error
+			subject := test.router(broker.EventRouterConfig{
+				ErrorResult: test.errorResult,
+				OnError: func(_ context.Context, _ broker.Message, err error) {
+					reportedErr = err
+				},
+			})
+
+			// act
+			result := subject.Consume(context.Background(), newTestEventMessage("order.placed.v1", "p1"))
+
+			// assert
+			assert.Equal(t, test.expectedResult, result)
+			if assert.NotNil(t, reportedErr) {
+				assert.True(t, test.expectedErrCheck(reportedErr))
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package broker_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func newTestEvent() broker.Event {
+	return broker.Event{
+		ID:         "evt-1",
+		Source:     "/shop/orders",
+		Type:       "com.shop.order.placed.v2",
+		Subject:    "ord-123",
+		Time:       time.Date(2024, 5, 17, 10, 30, 0, 123, time.UTC),
+		DataSchema: "https://example.com/schemas/order-placed-v2.json",
+		Extensions: map[string]any{"tenant": "acme"},
+	}
+}
+
+func TestEvent_Props(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := newTestEvent()
+
+	// act
+	props := subject.Props()
+
+	// assert
+	assert.True(t, props.IsEvent())
+	assert.Equal(t, broker.EventSpecVersion, props.EventSpecVersion())
+	assert.Equal(t, "evt-1", props.EventID())
+	assert.Equal(t, "/shop/orders", props.EventSource())
+	assert.Equal(t, "com.shop.order.placed.v2", props.EventType())
+	assert.Equal(t, "ord-123", props.EventSubject())
+	assert.Equal(t, subject.Time, props.EventTime())
+	assert.Equal(t, subject.DataSchema, props.EventDataSchema())
+	assert.Equal(t, map[string]any{"tenant": "acme"}, props.EventExtensions())
+	assert.Equal(t, broker.EventModeBinary, props.EventMode())
+	assert.Nil(t, broker.ValidateEvent(props))
+	assert.True(t, !broker.Props{}.IsEvent())
+}
+
+func TestValidateEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name        string
+		event       broker.Event
+		props       broker.Props
+		expectedErr string
+	}{
+		{
+			name:        "missing id",
+			event:       broker.Event{Source: "/shop", Type: "order.placed"},
+			expectedErr: "cloud event id attribute is required",
+		},
+		{
+			name:        "missing source",
+			event:       broker.Event{ID: "1", Type: "order.placed"},
+			expectedErr: "cloud event source attribute is required",
+		},
+		{
+			name:        "missing type",
+			event:       broker.Event{ID: "1", Source: "/shop"},
+			expectedErr: "cloud event type attribute is required",
+		},
+		{
+			name:        "invalid extension name",
+			event:       broker.Event{ID: "1", Source: "/shop", Type: "t", Extensions: map[string]any{"Tenant": 1}},
+			expectedErr: `invalid cloud event extension name "Tenant"`,
+		},
+		{
+			name:        "reserved extension name",
+			event:       broker.Event{ID: "1", Source: "/shop", Type: "t", Extensions: map[string]any{"data": 1}},
+			expectedErr: `invalid cloud event extension name "data"`,
+		},
+		{
+			name:        "unsupported mode",
+			event:       broker.Event{ID: "1", Source: "/shop", Type: "t", Mode: "batch"},
+			expectedErr: `unsupported cloud event mode "batch"`,
+		},
+		{
+			name:        "unsupported spec version",
+			props:       broker.Props{broker.PropEventSpecVersion: "0.3"},
+			expectedErr: `unsupported cloud event spec version "0.3"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			props := test.props
+			if props == nil {
+				props = test.event.Props()
+			}
+
+			// act
+			err := broker.ValidateEvent(props)
+
+			// assert
+			if assert.NotNil(t, err) {
+				assert.Equal(t, test.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestStructuredEvent(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json data is embedded", testStructuredEventJSONData)
+	t.Run("binary data is base64 encoded", testStructuredEventBinaryData)
+	t.Run("text data is decoded", testStructuredEventTextData)
+	t.Run("returns error for invalid event", testStructuredEventErr)
+}
+
+func testStructuredEventJSONData(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	event := newTestEvent()
+	event.Mode = broker.EventModeStructured
+	msg := broker.Message{Body: []byte(`{"id":"ord-123"}`), Props: event.Props()}
+	msg.Props[broker.PropMsgContentType] = "application/json"
+
+	// act
+	data, errMarshal := broker.MarshalStructuredEvent(msg)
+	resultMsg, errUnmarshal := broker.UnmarshalStructuredEvent(data)
+
+	// assert
+	assert.Nil(t, errMarshal)
+	assert.Nil(t, errUnmarshal)
+	var envelope map[string]any
+	assert.RequireNil(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, map[string]any{
+		"specversion":     "1.0",
+		"id":              "evt-1",
+		"source":          "/shop/orders",
+		"type":            "com.shop.order.placed.v2",
+		"subject":         "ord-123",
+		"time":            "2024-05-17T10:30:00.000000123Z",
+		"dataschema":      "https://example.com/schemas/order-placed-v2.json",
+		"datacontenttype": "application/json",
+		"tenant":          "acme",
+		"data":            map[string]any{"id": "ord-123"},
+	}, envelope)
+	assert.Equal(t, msg, resultMsg)
+}
+
+func testStructuredEventBinaryData(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	msg := broker.Message{
+		Body:  []byte{0x81, 0xa2, 'i', 'd', 0x01},
+		Props: broker.Event{ID: "evt-1", Source: "/shop", Type: "t", Mode: broker.EventModeStructured}.Props(),
+	}
+	msg.Props[broker.PropMsgContentType] = "application/msgpack"
+
+	// act
+	data, errMarshal := broker.MarshalStructuredEvent(msg)
+	resultMsg, errUnmarshal := broker.UnmarshalStructuredEvent(data)
+
+	// assert
+	assert.Nil(t, errMarshal)
+	assert.Nil(t, errUnmarshal)
+	assert.Equal(t,
+		`{"data_base64":"gaJpZAE=","datacontenttype":"application/msgpack","id":"evt-1",`+
+			`"source":"/shop","specversion":"1.0","type":"t"}`,
+		string(data),
+	)
+	assert.Equal(t, msg, resultMsg)
+}
+
+func testStructuredEventTextData(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	data := []byte(`{"specversion":"1.0","id":"1","source":"/shop","type":"t",` +
+		`"datacontenttype":"text/plain","data":"hello"}`)
+
+	// act
+	msg, err := broker.UnmarshalStructuredEvent(data)
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(msg.Body))
+	assert.Equal(t, "text/plain", msg.Props.GetString(broker.PropMsgContentType))
+	assert.Equal(t, broker.EventModeStructured, msg.Props.EventMode())
+}
+
+func testStructuredEventErr(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name        string
+		data        string
+		expectedErr string
+	}{
+		{
+			name:        "malformed json",
+			data:        `{"id":`,
+			expectedErr: "could not unmarshal cloud event",
+		},
+		{
+			name:        "invalid attribute",
+			data:        `{"specversion":"1.0","id":1,"source":"/shop","type":"t"}`,
+			expectedErr: "invalid cloud event id attribute",
+		},
+		{
+			name:        "invalid time",
+			data:        `{"specversion":"1.0","id":"1","source":"/shop","type":"t","time":"yesterday"}`,
+			expectedErr: "invalid cloud event time attribute",
+		},
+		{
+			name:        "invalid base64 data",
+			data:        `{"specversion":"1.0","id":"1","source":"/shop","type":"t","data_base64":"!"}`,
+			expectedErr: "invalid cloud event data_base64",
+		},
+		{
+			name:        "missing required attribute",
+			data:        `{"specversion":"1.0","id":"1","source":"/shop"}`,
+			expectedErr: "cloud event type attribute is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			_, err := broker.UnmarshalStructuredEvent([]byte(test.data))
+
+			// assert
+			if assert.NotNil(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), test.expectedErr))
+			}
+		})
+	}
+
+	t.Run("marshal invalid event", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, err := broker.MarshalStructuredEvent(broker.Message{Props: broker.Event{ID: "1"}.Props()})
+
+		// assert
+		assert.NotNil(t, err)
+	})
+}
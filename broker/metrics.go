@@ -0,0 +1,156 @@
+package broker
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/actforgood/xtransport/metrics"
+)
+
+// MetricsRecorder records consumers and publishers metrics.
+type MetricsRecorder interface {
+	// MessageConsumed is called after a message was consumed, with the consume result and duration.
+	MessageConsumed(consumer, queue string, result byte, duration time.Duration)
+	// MessageRedelivered is called when a redelivered message is received.
+	MessageRedelivered(consumer, queue string)
+	// MessageRetried is called when a retried message is received, with its retry count.
+	MessageRetried(consumer, queue string, retryCount int)
+	// MessageSkipped is called when a message is not consumed (requeued) due to shutdown.
+	MessageSkipped(consumer, queue string)
+	// MessagePublished is called after a message was published, with the publish duration and error, if any.
+	// Messages are identified by exchange only, as routing keys may have unbounded cardinality.
+	MessagePublished(exchange string, duration time.Duration, err error)
+}
+
+// NopMetricsRecorder is a [MetricsRecorder] which does nothing.
+type NopMetricsRecorder struct{}
+
+// MessageConsumed does nothing.
+func (NopMetricsRecorder) MessageConsumed(string, string, byte, time.Duration) {}
+
+// MessageRedelivered does nothing.
+func (NopMetricsRecorder) MessageRedelivered(string, string) {}
+
+// MessageRetried does nothing.
+func (NopMetricsRecorder) MessageRetried(string, string, int) {}
+
+// MessageSkipped does nothing.
+func (NopMetricsRecorder) MessageSkipped(string, string) {}
+
+// MessagePublished does nothing.
+func (NopMetricsRecorder) MessagePublished(string, time.Duration, error) {}
+
+// ConsumeResultString returns a textual representation of given consume result,
+// like "ack", "nack", "nack_requeue".
+func ConsumeResultString(result byte) string {
+	switch result {
+	case ConsumeResultAck:
+		return "ack"
+	case ConsumeResultNack:
+		return "nack"
+	case ConsumeResultNackRequeue:
+		return "nack_requeue"
+	default:
+		return "unknown_" + strconv.Itoa(int(result))
+	}
+}
+
+// registryMetricsRecorder is a [MetricsRecorder] which records metrics in a [metrics.Registry].
+type registryMetricsRecorder struct {
+	consumed        *metrics.CounterVec
+	consumeDuration *metrics.HistogramVec
+	redelivered     *metrics.CounterVec
+	retried         *metrics.CounterVec
+	retryCount      *metrics.HistogramVec
+	skipped         *metrics.CounterVec
+	publishDuration *metrics.HistogramVec
+	publishErrors   *metrics.CounterVec
+}
+
+// NewRegistryMetricsRecorder instantiates a new [MetricsRecorder] which records, into given registry:
+//   - broker_messages_consumed_total counter and broker_consume_duration_seconds histogram,
+//     labelled by consumer, queue and result,
+//   - broker_messages_redelivered_total, broker_messages_retried_total, broker_messages_skipped_total counters
+//     and broker_message_retry_count histogram, labelled by consumer and queue,
+//   - broker_publish_duration_seconds histogram and broker_publish_errors_total counter,
+//     labelled by exchange.
+func NewRegistryMetricsRecorder(registry *metrics.Registry) MetricsRecorder {
+	consumerLabels := []string{"consumer", "queue"}
+
+	return registryMetricsRecorder{
+		consumed: registry.NewCounterVec(
+			"broker_messages_consumed_total",
+			"Total number of consumed messages.",
+			"consumer", "queue", "result",
+		),
+		consumeDuration: registry.NewHistogramVec(
+			"broker_consume_duration_seconds",
+			"Messages consume duration in seconds.",
+			metrics.DefaultDurationBuckets,
+			"consumer", "queue", "result",
+		),
+		redelivered: registry.NewCounterVec(
+			"broker_messages_redelivered_total",
+			"Total number of redelivered messages.",
+			consumerLabels...,
+		),
+		retried: registry.NewCounterVec(
+			"broker_messages_retried_total",
+			"Total number of retried messages.",
+			consumerLabels...,
+		),
+		retryCount: registry.NewHistogramVec(
+			"broker_message_retry_count",
+			"Retry count of retried messages.",
+			[]float64{1, 2, 3, 5, 10, 25},
+			consumerLabels...,
+		),
+		skipped: registry.NewCounterVec(
+			"broker_messages_skipped_total",
+			"Total number of messages requeued without being consumed, due to shutdown.",
+			consumerLabels...,
+		),
+		publishDuration: registry.NewHistogramVec(
+			"broker_publish_duration_seconds",
+			"Messages publish duration in seconds.",
+			metrics.DefaultDurationBuckets,
+			"exchange",
+		),
+		publishErrors: registry.NewCounterVec(
+			"broker_publish_errors_total",
+			"Total number of messages which could not be published.",
+			"exchange",
+		),
+	}
+}
+
+// MessageConsumed ...see [MetricsRecorder.MessageConsumed].
+func (rec registryMetricsRecorder) MessageConsumed(consumer, queue string, result byte, duration time.Duration) {
+	resultLabel := ConsumeResultString(result)
+	rec.consumed.Inc(consumer, queue, resultLabel)
+	rec.consumeDuration.Observe(duration.Seconds(), consumer, queue, resultLabel)
+}
+
+// MessageRedelivered ...see [MetricsRecorder.MessageRedelivered].
+func (rec registryMetricsRecorder) MessageRedelivered(consumer, queue string) {
+	rec.redelivered.Inc(consumer, queue)
+}
+
+// MessageRetried ...see [MetricsRecorder.MessageRetried].
+func (rec registryMetricsRecorder) MessageRetried(consumer, queue string, retryCount int) {
+	rec.retried.Inc(consumer, queue)
+	rec.retryCount.Observe(float64(retryCount), consumer, queue)
+}
+
+// MessageSkipped ...see [MetricsRecorder.MessageSkipped].
+func (rec registryMetricsRecorder) MessageSkipped(consumer, queue string) {
+	rec.skipped.Inc(consumer, queue)
+}
+
+// MessagePublished ...see [MetricsRecorder.MessagePublished].
+func (rec registryMetricsRecorder) MessagePublished(exchange string, duration time.Duration, err error) {
+	rec.publishDuration.Observe(duration.Seconds(), exchange)
+	if err != nil {
+		rec.publishErrors.Inc(exchange)
+	}
+}
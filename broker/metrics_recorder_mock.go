@@ -0,0 +1,114 @@
+package broker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MetricsRecorderMock is a mock for [MetricsRecorder]. It can be used in UT.
+type MetricsRecorderMock struct {
+	messageConsumedCallsCnt    uint32
+	messageConsumedCallback    func(consumer, queue string, result byte, duration time.Duration)
+	messageRedeliveredCallsCnt uint32
+	messageRedeliveredCallback func(consumer, queue string)
+	messageRetriedCallsCnt     uint32
+	messageRetriedCallback     func(consumer, queue string, retryCount int)
+	messageSkippedCallsCnt     uint32
+	messageSkippedCallback     func(consumer, queue string)
+	messagePublishedCallsCnt   uint32
+	messagePublishedCallback   func(exchange string, duration time.Duration, err error)
+}
+
+// MessageConsumed mock logic...
+func (mock *MetricsRecorderMock) MessageConsumed(consumer, queue string, result byte, duration time.Duration) {
+	atomic.AddUint32(&mock.messageConsumedCallsCnt, 1)
+	if mock.messageConsumedCallback != nil {
+		mock.messageConsumedCallback(consumer, queue, result, duration)
+	}
+}
+
+// MessageRedelivered mock logic...
+func (mock *MetricsRecorderMock) MessageRedelivered(consumer, queue string) {
+	atomic.AddUint32(&mock.messageRedeliveredCallsCnt, 1)
+	if mock.messageRedeliveredCallback != nil {
+		mock.messageRedeliveredCallback(consumer, queue)
+	}
+}
+
+// MessageRetried mock logic...
+func (mock *MetricsRecorderMock) MessageRetried(consumer, queue string, retryCount int) {
+	atomic.AddUint32(&mock.messageRetriedCallsCnt, 1)
+	if mock.messageRetriedCallback != nil {
+		mock.messageRetriedCallback(consumer, queue, retryCount)
+	}
+}
+
+// MessageSkipped mock logic...
+func (mock *MetricsRecorderMock) MessageSkipped(consumer, queue string) {
+	atomic.AddUint32(&mock.messageSkippedCallsCnt, 1)
+	if mock.messageSkippedCallback != nil {
+		mock.messageSkippedCallback(consumer, queue)
+	}
+}
+
+// MessagePublished mock logic...
+func (mock *MetricsRecorderMock) MessagePublished(exchange string, duration time.Duration, err error) {
+	atomic.AddUint32(&mock.messagePublishedCallsCnt, 1)
+	if mock.messagePublishedCallback != nil {
+		mock.messagePublishedCallback(exchange, duration, err)
+	}
+}
+
+// SetMessageConsumedCallback sets the callback to be executed on MessageConsumed call.
+func (mock *MetricsRecorderMock) SetMessageConsumedCallback(
+	cb func(consumer, queue string, result byte, duration time.Duration),
+) {
+	mock.messageConsumedCallback = cb
+}
+
+// MessageConsumedCallsCount returns the no. of times MessageConsumed was called.
+func (mock *MetricsRecorderMock) MessageConsumedCallsCount() int {
+	return int(atomic.LoadUint32(&mock.messageConsumedCallsCnt))
+}
+
+// SetMessageRedeliveredCallback sets the callback to be executed on MessageRedelivered call.
+func (mock *MetricsRecorderMock) SetMessageRedeliveredCallback(cb func(consumer, queue string)) {
+	mock.messageRedeliveredCallback = cb
+}
+
+// MessageRedeliveredCallsCount returns the no. of times MessageRedelivered was called.
+func (mock *MetricsRecorderMock) MessageRedeliveredCallsCount() int {
+	return int(atomic.LoadUint32(&mock.messageRedeliveredCallsCnt))
+}
+
+// SetMessageRetriedCallback sets the callback to be executed on MessageRetried call.
+func (mock *MetricsRecorderMock) SetMessageRetriedCallback(cb func(consumer, queue string, retryCount int)) {
+	mock.messageRetriedCallback = cb
+}
+
+// MessageRetriedCallsCount returns the no. of times MessageRetried was called.
+func (mock *MetricsRecorderMock) MessageRetriedCallsCount() int {
+	return int(atomic.LoadUint32(&mock.messageRetriedCallsCnt))
+}
+
+// SetMessageSkippedCallback sets the callback to be executed on MessageSkipped call.
+func (mock *MetricsRecorderMock) SetMessageSkippedCallback(cb func(consumer, queue string)) {
+	mock.messageSkippedCallback = cb
+}
+
+// MessageSkippedCallsCount returns the no. of times MessageSkipped was called.
+func (mock *MetricsRecorderMock) MessageSkippedCallsCount() int {
+	return int(atomic.LoadUint32(&mock.messageSkippedCallsCnt))
+}
+
+// SetMessagePublishedCallback sets the callback to be executed on MessagePublished call.
+func (mock *MetricsRecorderMock) SetMessagePublishedCallback(
+	cb func(exchange string, duration time.Duration, err error),
+) {
+	mock.messagePublishedCallback = cb
+}
+
+// MessagePublishedCallsCount returns the no. of times MessagePublished was called.
+func (mock *MetricsRecorderMock) MessagePublishedCallsCount() int {
+	return int(atomic.LoadUint32(&mock.messagePublishedCallsCnt))
+}
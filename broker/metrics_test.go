@@ -0,0 +1,74 @@
+package broker_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/metrics"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestConsumeResultString(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		result   byte
+		expected string
+	}{
+		{result: broker.ConsumeResultAck, expected: "ack"},
+		{result: broker.ConsumeResultNack, expected: "nack"},
+		{result: broker.ConsumeResultNackRequeue, expected: "nack_requeue"},
+		{result: 0, expected: "unknown_0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			result := broker.ConsumeResultString(test.result)
+
+			// assert
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestRegistryMetricsRecorder(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		registry = metrics.NewRegistry()
+		subject  = broker.NewRegistryMetricsRecorder(registry)
+		output   strings.Builder
+	)
+
+	// act
+	subject.MessageConsumed("emails", "q_emails", broker.ConsumeResultAck, 20*time.Millisecond)
+	subject.MessageConsumed("emails", "q_emails", broker.ConsumeResultNack, 20*time.Millisecond)
+	subject.MessageRedelivered("emails", "q_emails")
+	subject.MessageRetried("emails", "q_emails", 2)
+	subject.MessageSkipped("emails", "q_emails")
+	subject.MessagePublished("notifications", time.Millisecond, nil)
+	subject.MessagePublished("notifications", time.Millisecond, errors.New("intentionally triggered error"))
+
+	// assert
+	_ = registry.WriteText(&output)
+	for _, expected := range [...]string{
+		`broker_messages_consumed_total{consumer="emails",queue="q_emails",result="ack"} 1`,
+		`broker_messages_consumed_total{consumer="emails",queue="q_emails",result="nack"} 1`,
+		`broker_consume_duration_seconds_bucket{consumer="emails",queue="q_emails",result="ack",le="0.025"} 1`,
+		`broker_messages_redelivered_total{consumer="emails",queue="q_emails"} 1`,
+		`broker_messages_retried_total{consumer="emails",queue="q_emails"} 1`,
+		`broker_message_retry_count_bucket{consumer="emails",queue="q_emails",le="2"} 1`,
+		`broker_messages_skipped_total{consumer="emails",queue="q_emails"} 1`,
+		`broker_publish_duration_seconds_count{exchange="notifications"} 2`,
+		`broker_publish_errors_total{exchange="notifications"} 1`,
+	} {
+		assert.True(t, strings.Contains(output.String(), expected))
+	}
+}
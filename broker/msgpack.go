@@ -0,0 +1,169 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// marshalMessagePack serialises given value as MessagePack.
+// The value is first converted, through JSON, into a generic value, so that the same rules
+// as for [json.Marshal] apply (like "json" struct tags, [json.Marshaler] implementations).
+func marshalMessagePack(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	enc := msgpackEncoder{buf: make([]byte, 0, len(data))}
+	if err := enc.encode(generic); err != nil {
+		return nil, err
+	}
+
+	return enc.buf, nil
+}
+
+// msgpackEncoder encodes generic values (as produced by [json.Decoder.UseNumber]) as MessagePack.
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (enc *msgpackEncoder) encode(value any) error {
+	switch val := value.(type) {
+	case nil:
+		enc.buf = append(enc.buf, 0xc0)
+	case bool:
+		if val {
+			enc.buf = append(enc.buf, 0xc3)
+		} else {
+			enc.buf = append(enc.buf, 0xc2)
+		}
+	case json.Number:
+		return enc.encodeNumber(val)
+	case string:
+		enc.encodeString(val)
+	case []any:
+		enc.encodeLength(len(val), 0x90, 15, 0xdc, 0xdd)
+		for _, elem := range val {
+			if err := enc.encode(elem); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		enc.encodeLength(len(val), 0x80, 15, 0xde, 0xdf)
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys) // deterministic output
+		for _, key := range keys {
+			enc.encodeString(key)
+			if err := enc.encode(val[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: cannot encode %T", value)
+	}
+
+	return nil
+}
+
+func (enc *msgpackEncoder) encodeNumber(num json.Number) error {
+	str := num.String()
+	if !strings.ContainsAny(str, ".eE") {
+		if intVal, err := strconv.ParseInt(str, 10, 64); err == nil {
+			enc.encodeInt(intVal)
+
+			return nil
+		}
+		if uintVal, err := strconv.ParseUint(str, 10, 64); err == nil {
+			enc.encodeUint(uintVal)
+
+			return nil
+		}
+	}
+	floatVal, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return fmt.Errorf("msgpack: cannot encode number %s: %w", str, err)
+	}
+	enc.buf = append(enc.buf, 0xcb)
+	enc.buf = binary.BigEndian.AppendUint64(enc.buf, math.Float64bits(floatVal))
+
+	return nil
+}
+
+func (enc *msgpackEncoder) encodeInt(val int64) {
+	switch {
+	case val >= 0:
+		enc.encodeUint(uint64(val))
+	case val >= -32:
+		enc.buf = append(enc.buf, byte(val))
+	case val >= math.MinInt8:
+		enc.buf = append(enc.buf, 0xd0, byte(val))
+	case val >= math.MinInt16:
+		enc.buf = append(enc.buf, 0xd1)
+		enc.buf = binary.BigEndian.AppendUint16(enc.buf, uint16(val))
+	case val >= math.MinInt32:
+		enc.buf = append(enc.buf, 0xd2)
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, uint32(val))
+	default:
+		enc.buf = append(enc.buf, 0xd3)
+		enc.buf = binary.BigEndian.AppendUint64(enc.buf, uint64(val))
+	}
+}
+
+func (enc *msgpackEncoder) encodeUint(val uint64) {
+	switch {
+	case val <= 0x7f:
+		enc.buf = append(enc.buf, byte(val))
+	case val <= math.MaxUint8:
+		enc.buf = append(enc.buf, 0xcc, byte(val))
+	case val <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xcd)
+		enc.buf = binary.BigEndian.AppendUint16(enc.buf, uint16(val))
+	case val <= math.MaxUint32:
+		enc.buf = append(enc.buf, 0xce)
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, uint32(val))
+	default:
+		enc.buf = append(enc.buf, 0xcf)
+		enc.buf = binary.BigEndian.AppendUint64(enc.buf, val)
+	}
+}
+
+func (enc *msgpackEncoder) encodeString(str string) {
+	switch length := len(str); {
+	case length <= 31:
+		enc.buf = append(enc.buf, 0xa0|byte(length))
+	case length <= math.MaxUint8:
+		enc.buf = append(enc.buf, 0xd9, byte(length))
+	default:
+		enc.encodeLength(length, 0, -1, 0xda, 0xdb)
+	}
+	enc.buf = append(enc.buf, str...)
+}
+
+// encodeLength encodes the header of a container (or string) of given length:
+// the fix format (fixPrefix|length) if length <= fixMax, or the 16 / 32 bits format otherwise.
+func (enc *msgpackEncoder) encodeLength(length int, fixPrefix byte, fixMax int, prefix16, prefix32 byte) {
+	switch {
+	case length <= fixMax:
+		enc.buf = append(enc.buf, fixPrefix|byte(length))
+	case length <= math.MaxUint16:
+		enc.buf = append(enc.buf, prefix16)
+		enc.buf = binary.BigEndian.AppendUint16(enc.buf, uint16(length))
+	default:
+		enc.buf = append(enc.buf, prefix32)
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, uint32(length))
+	}
+}
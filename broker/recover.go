@@ -0,0 +1,96 @@
+package broker
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/actforgood/xtransport"
+)
+
+// PanicGuardConfig holds the configuration for a [PanicGuard].
+type PanicGuardConfig struct {
+	// Result is the consume result returned when a panic occurred.
+	// Defaults to [ConsumeResultNack], as requeueing a message which causes a panic
+	// would most likely result in an endless loop.
+	Result byte
+	// Reporters are called with each reported panic, see [xtransport.PanicReporter].
+	Reporters []xtransport.PanicReporter
+	// StackMaxFrames is the max no. of stack frames logged / reported.
+	// Zero value means all the frames are kept.
+	StackMaxFrames int
+	// DedupWindow, if set, suppresses the logging / reporting of identical panics
+	// (same value and location) occurred within this window after one was reported.
+	DedupWindow time.Duration
+}
+
+// PanicGuard recovers panics occurred while consuming messages.
+// It is concurrent safe to use.
+type PanicGuard struct {
+	config PanicGuardConfig
+	dedup  *xtransport.PanicDeduplicator
+	logger *slog.Logger
+}
+
+// NewPanicGuard instantiates a new [PanicGuard].
+func NewPanicGuard(config PanicGuardConfig, logger *slog.Logger) *PanicGuard {
+	if config.Result == 0 {
+		config.Result = ConsumeResultNack
+	}
+	guard := &PanicGuard{
+		config: config,
+		logger: logger,
+	}
+	if config.DedupWindow > 0 {
+		guard.dedup = xtransport.NewPanicDeduplicator(config.DedupWindow)
+	}
+
+	return guard
+}
+
+// Consume calls consumer's Consume, recovering any panic.
+// Upon panic, the panic is logged / reported, and the configured result is returned.
+func (guard *PanicGuard) Consume(ctx context.Context, consumer Consumer, msg Message) (result byte) {
+	defer func() {
+		err := recover()
+		if err == nil {
+			return
+		}
+		result = guard.config.Result
+		report, shouldLog := xtransport.HandlePanic(
+			ctx, err, debug.Stack(), guard.config.StackMaxFrames, guard.dedup, guard.config.Reporters...,
+		)
+		if !shouldLog {
+			return
+		}
+		logParams := []any{
+			"err", err,
+			"stack", report.Stack,
+		}
+		if correlationID := xtransport.CorrelationIDFromContext(ctx); correlationID != "" {
+			logParams = append(logParams, "correlationId", correlationID)
+		}
+		if report.Suppressed > 0 {
+			logParams = append(logParams, "suppressedCount", report.Suppressed)
+		}
+		guard.logger.Error("consumer panic catched", logParams...)
+	}()
+
+	return consumer.Consume(ctx, msg)
+}
+
+// Wrap returns a [Consumer] decorator which recovers panics occurred inside given consumer's Consume.
+func (guard *PanicGuard) Wrap(consumer Consumer) Consumer {
+	return guardedConsumer{Consumer: consumer, guard: guard}
+}
+
+type guardedConsumer struct {
+	Consumer
+	guard *PanicGuard
+}
+
+// Consume ...see [Consumer.Consume].
+func (c guardedConsumer) Consume(ctx context.Context, msg Message) byte {
+	return c.guard.Consume(ctx, c.Consumer, msg)
+}
@@ -0,0 +1,106 @@
+package broker_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/actforgood/xtransport"
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+// consumerFunc is a [broker.Consumer] which consumes messages through a function.
+type consumerFunc func(context.Context, broker.Message) byte
+
+func (f consumerFunc) Props() broker.Props {
+	return broker.Props{}
+}
+
+func (f consumerFunc) Consume(ctx context.Context, msg broker.Message) byte {
+	return f(ctx, msg)
+}
+
+func TestPanicGuard(t *testing.T) {
+	t.Parallel()
+
+	t.Run("consume result is returned if no panic occurred", testPanicGuardNoPanic)
+	t.Run("panic is catched and configured result returned", testPanicGuardWithPanic)
+}
+
+func testPanicGuardNoPanic(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		loggerMock = mock.NewSlogHandler()
+		subject    = broker.NewPanicGuard(broker.PanicGuardConfig{}, slog.New(loggerMock))
+		consumer   = consumerFunc(func(context.Context, broker.Message) byte {
+			return broker.ConsumeResultNackRequeue
+		})
+	)
+
+	// act
+	result := subject.Wrap(consumer).Consume(context.Background(), broker.Message{})
+
+	// assert
+	assert.Equal(t, broker.ConsumeResultNackRequeue, result)
+	assert.Equal(t, 0, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func testPanicGuardWithPanic(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name           string
+		config         broker.PanicGuardConfig
+		expectedResult byte
+	}{
+		{
+			name:           "default result",
+			config:         broker.PanicGuardConfig{},
+			expectedResult: broker.ConsumeResultNack,
+		},
+		{
+			name:           "custom result",
+			config:         broker.PanicGuardConfig{Result: broker.ConsumeResultAck},
+			expectedResult: broker.ConsumeResultAck,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var reports []xtransport.PanicReport
+			test.config.Reporters = []xtransport.PanicReporter{
+				xtransport.PanicReporterFunc(func(_ context.Context, report xtransport.PanicReport) {
+					reports = append(reports, report)
+				}),
+			}
+			var (
+				loggerMock = mock.NewSlogHandler()
+				subject    = broker.NewPanicGuard(test.config, slog.New(loggerMock))
+				consumer   = consumerFunc(func(context.Context, broker.Message) byte {
+					panic("intentionally triggered panic")
+				})
+				ctx = xtransport.ContextWithCorrelationID(context.Background(), "abcd-1234")
+			)
+
+			// act
+			result := subject.Consume(ctx, consumer, broker.Message{Body: []byte("payload")})
+
+			// assert
+			assert.Equal(t, test.expectedResult, result)
+			if assert.Equal(t, 1, len(reports)) {
+				assert.Equal(t, "intentionally triggered panic", reports[0].Value)
+			}
+			if assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError)) {
+				assert.Equal(t, "abcd-1234", loggerMock.ValueAt(1, "correlationId"))
+				assert.Equal(t, reports[0].Stack, loggerMock.ValueAt(1, "stack"))
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package decoder
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"time"
+)
+
+// newCBORSyntaxError returns a new [*SyntaxError] for CBOR format.
+func newCBORSyntaxError() error {
+	return &SyntaxError{Format: FormatCBOR}
+}
+
+// CBOR major types.
+const (
+	cborUint byte = iota
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+)
+
+// cborIndefinite is the additional information denoting an indefinite length.
+const cborIndefinite = 31
+
+// DecodeCBOR decodes CBOR contents of a [io.Reader] into a variable.
+// Destination is populated like [DecodeJSON] does (so "json" struct tags are honoured),
+// with byte strings mapped to []byte, date/time tags (0, 1) to [time.Time],
+// and bignum tags (2, 3) to [big.Int]. Other tags are ignored, the tagged item is used.
+func DecodeCBOR(r io.Reader, dest any) error {
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	parser := cborParser{data: data}
+	value, err := parser.parse(0)
+	if err != nil {
+		return err
+	}
+	if parser.pos != len(data) {
+		return &TrailingDataError{Format: FormatCBOR}
+	}
+
+	return assignGeneric(value, dest)
+}
+
+// cborParser parses CBOR encoded data into generic values.
+type cborParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *cborParser) next(n uint64) ([]byte, error) {
+	if n > uint64(len(p.data)-p.pos) {
+		return nil, newCBORSyntaxError()
+	}
+	b := p.data[p.pos : p.pos+int(n)]
+	p.pos += int(n)
+
+	return b, nil
+}
+
+// head parses an item's head, returning its major type, additional information and argument.
+func (p *cborParser) head() (byte, byte, uint64, error) {
+	b, err := p.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		arg, err := p.next(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var n uint64
+		for _, c := range arg {
+			n = n<<8 | uint64(c)
+		}
+
+		return major, info, n, nil
+	case info == cborIndefinite && major != cborUint && major != cborNegInt && major != cborTag:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, newCBORSyntaxError()
+	}
+}
+
+// isBreak checks if next byte is the "break" stop code, consuming it if so.
+func (p *cborParser) isBreak() (bool, error) {
+	if p.pos >= len(p.data) {
+		return false, newCBORSyntaxError()
+	}
+	if p.data[p.pos] == 0xff {
+		p.pos++
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (p *cborParser) parse(depth int) (any, error) {
+	if depth > maxBinaryDepth {
+		return nil, newCBORSyntaxError()
+	}
+	major, info, arg, err := p.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return arg, nil
+	case cborNegInt:
+		if arg > math.MaxInt64 {
+			return new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(arg)), nil
+		}
+
+		return -1 - int64(arg), nil
+	case cborBytes, cborText:
+		b, err := p.parseString(major, info, arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(b), nil
+		}
+
+		return b, nil
+	case cborArray:
+		return p.parseArray(info, arg, depth)
+	case cborMap:
+		return p.parseMap(info, arg, depth)
+	case cborTag:
+		value, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return cborTagged(arg, value)
+	default: // simple values and floats
+		return p.parseSimple(info, arg)
+	}
+}
+
+// parseString parses a byte/text string, concatenating chunks of indefinite length strings.
+func (p *cborParser) parseString(major, info byte, arg uint64) ([]byte, error) {
+	if info != cborIndefinite {
+		b, err := p.next(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte(nil), b...), nil
+	}
+
+	var b []byte
+	for {
+		brk, err := p.isBreak()
+		if err != nil {
+			return nil, err
+		}
+		if brk {
+			return b, nil
+		}
+		chunkMajor, chunkInfo, chunkLen, err := p.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, newCBORSyntaxError()
+		}
+		chunk, err := p.next(chunkLen)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+func (p *cborParser) parseArray(info byte, n uint64, depth int) (any, error) {
+	if info == cborIndefinite {
+		arr := make([]any, 0)
+		for {
+			brk, err := p.isBreak()
+			if err != nil {
+				return nil, err
+			}
+			if brk {
+				return arr, nil
+			}
+			value, err := p.parse(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+	}
+
+	if n > uint64(len(p.data)-p.pos) { // each element takes at least 1 byte
+		return nil, newCBORSyntaxError()
+	}
+	arr := make([]any, 0, n)
+	for range n {
+		value, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+	}
+
+	return arr, nil
+}
+
+func (p *cborParser) parseMap(info byte, n uint64, depth int) (any, error) {
+	m := make(map[string]any)
+	if info != cborIndefinite && n > uint64(len(p.data)-p.pos)/2 { // each pair takes at least 2 bytes
+		return nil, newCBORSyntaxError()
+	}
+	for idx := uint64(0); info == cborIndefinite || idx < n; idx++ {
+		if info == cborIndefinite {
+			brk, err := p.isBreak()
+			if err != nil {
+				return nil, err
+			}
+			if brk {
+				break
+			}
+		}
+		key, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[genericMapKey(key)] = value
+	}
+
+	return m, nil
+}
+
+// parseSimple parses simple values and floats.
+func (p *cborParser) parseSimple(info byte, arg uint64) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		return halfToFloat64(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	default:
+		return nil, newCBORSyntaxError()
+	}
+}
+
+// cborTagged interprets a tagged value.
+func cborTagged(tag uint64, value any) (any, error) {
+	switch tag {
+	case 0: // standard date/time string
+		s, ok := value.(string)
+		if !ok {
+			return nil, newCBORSyntaxError()
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, newCBORSyntaxError()
+		}
+
+		return t, nil
+	case 1: // epoch-based date/time
+		switch v := value.(type) {
+		case uint64:
+			return time.Unix(int64(v), 0).UTC(), nil
+		case int64:
+			return time.Unix(v, 0).UTC(), nil
+		case float64:
+			sec, frac := math.Modf(v)
+
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		default:
+			return nil, newCBORSyntaxError()
+		}
+	case 2, 3: // positive / negative bignum
+		b, ok := value.([]byte)
+		if !ok {
+			return nil, newCBORSyntaxError()
+		}
+		n := new(big.Int).SetBytes(b)
+		if tag == 3 {
+			n.Sub(big.NewInt(-1), n)
+		}
+
+		return n, nil
+	default:
+		return value, nil
+	}
+}
+
+// halfToFloat64 converts an IEEE 754 half-precision float to float64.
+func halfToFloat64(half uint16) float64 {
+	exp := int(half>>10) & 0x1f
+	mant := float64(half & 0x3ff)
+	var val float64
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			val = math.Inf(1)
+		} else {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+1024, exp-25)
+	}
+	if half&0x8000 != 0 {
+		return -val
+	}
+
+	return val
+}
@@ -0,0 +1,138 @@
+package decoder_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully decodes cbor", testDecodeCBORSuccess)
+	t.Run("successfully decodes bignum", testDecodeCBORBignum)
+	t.Run("returns error", testDecodeCBORErr)
+}
+
+func testDecodeCBORSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		r = bytes.NewReader(concatBytes(
+			[]byte{0xaa}, // map with 10 entries
+			[]byte{0x64}, []byte("name"), []byte{0x7f, 0x64}, []byte("John"), []byte{0x64}, []byte(" Doe"), []byte{0xff},
+			[]byte{0x63}, []byte("age"), []byte{0x18, 0x2a},
+			[]byte{0x64}, []byte("tags"), []byte{0x9f, 0x61, 'a', 0x61, 'b', 0xff},
+			[]byte{0x63}, []byte("bin"), []byte{0x42, 0x01, 0x02},
+			[]byte{0x63}, []byte("neg"), []byte{0x38, 0xc7},
+			[]byte{0x63}, []byte("big"), []byte{0x19, 0x01, 0x2c},
+			[]byte{0x61}, []byte("f"), []byte{0xf9, 0x3e, 0x00},
+			[]byte{0x62}, []byte("ok"), []byte{0xf5},
+			[]byte{0x63}, []byte("nil"), []byte{0xf6},
+			[]byte{0x62}, []byte("ts"), []byte{0xc1, 0x18, 0x64},
+		))
+		dest testBinaryStruct
+	)
+
+	// act
+	err := decoder.DecodeCBOR(r, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, expectedTestBinaryStruct, dest)
+	}
+}
+
+func testDecodeCBORBignum(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		r        = bytes.NewReader([]byte{0xc3, 0x49, 0x01, 0, 0, 0, 0, 0, 0, 0, 0}) // -1 - 2^64
+		dest     big.Int
+		expected = new(big.Int).Sub(big.NewInt(-1), new(big.Int).Lsh(big.NewInt(1), 64))
+	)
+
+	// act
+	err := decoder.DecodeCBOR(r, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, expected.String(), dest.String())
+	}
+}
+
+func testDecodeCBORErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name          string
+		input         []byte
+		expectedError string
+	}{
+		{
+			name:          "returns empty body error",
+			input:         []byte{},
+			expectedError: "body must not be empty",
+		},
+		{
+			name:          "returns multi objects error",
+			input:         []byte{0xa0, 0xf6},
+			expectedError: "CBOR does not contain a single object",
+		},
+		{
+			name:          "returns malformed error for truncated data",
+			input:         []byte{0x64, 'n'},
+			expectedError: "badly-formed CBOR",
+		},
+		{
+			name:          "returns malformed error for missing break",
+			input:         []byte{0x9f, 0x01},
+			expectedError: "badly-formed CBOR",
+		},
+		{
+			name:          "returns malformed error for invalid additional information",
+			input:         []byte{0x1c},
+			expectedError: "badly-formed CBOR",
+		},
+		{
+			name:          "returns malformed error for too large declared length",
+			input:         []byte{0x9a, 0xff, 0xff, 0xff, 0xff, 0xf6},
+			expectedError: "badly-formed CBOR",
+		},
+		{
+			name:          "returns malformed error for too deep nesting",
+			input:         append(bytes.Repeat([]byte{0x81}, 1001), 0xf6),
+			expectedError: "badly-formed CBOR",
+		},
+		{
+			name:          "returns malformed error for invalid date/time",
+			input:         []byte{0xc0, 0x61, 'x'},
+			expectedError: "badly-formed CBOR",
+		},
+		{
+			name:          "returns invalid field type error",
+			input:         concatBytes([]byte{0xa1, 0x64}, []byte("name"), []byte{0x01}),
+			expectedError: `invalid value for the "name" field`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dest testBinaryStruct
+
+			// act
+			actualError := decoder.DecodeCBOR(bytes.NewReader(test.input), &dest)
+
+			// assert
+			if assert.NotNil(t, actualError) {
+				assert.Equal(t, test.expectedError, actualError.Error())
+			}
+		})
+	}
+}
@@ -1,7 +1,36 @@
 // Package decoder provides decoding utilities.
 package decoder
 
-import "io"
+import (
+	"errors"
+	"io"
+	"net/http"
+)
 
 // Decoder decodes contents of a [io.Reader] into a variable.
 type Decoder func(io.Reader, any) error
+
+// readBody reads all the contents of given reader, returning an [*EmptyBodyError]
+// if it is empty, or a [*TooLargeError] if a [http.MaxBytesReader] limit was exceeded.
+func readBody(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, translateReadError(err)
+	}
+	if len(data) == 0 {
+		return nil, &EmptyBodyError{}
+	}
+
+	return data, nil
+}
+
+// translateReadError returns a [*TooLargeError] if a [http.MaxBytesReader] limit was exceeded,
+// given error otherwise.
+func translateReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &TooLargeError{Limit: maxBytesErr.Limit, Err: err}
+	}
+
+	return err
+}
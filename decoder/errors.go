@@ -0,0 +1,232 @@
+package decoder
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Formats reported by [SyntaxError] and [TrailingDataError].
+const (
+	FormatJSON          = "JSON"
+	FormatXML           = "XML"
+	FormatForm          = "form"
+	FormatMultipartForm = "multipart form"
+	FormatMessagePack   = "MessagePack"
+	FormatCBOR          = "CBOR"
+	FormatProtobuf      = "protobuf"
+)
+
+// SyntaxError is returned when contents are not well-formed.
+type SyntaxError struct {
+	// Format is the decoded format, like "JSON", see Format* constants.
+	Format string
+	// Offset is the byte offset after which the error occurred, if known (0 otherwise).
+	Offset int64
+	// Line is the line on which the error occurred, if known (0 otherwise).
+	Line int
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the error message, like "badly-formed JSON (at position 2)".
+func (err *SyntaxError) Error() string {
+	switch {
+	case err.Offset > 0:
+		return fmt.Sprintf("badly-formed %s (at position %d)", err.Format, err.Offset)
+	case err.Line > 0:
+		return fmt.Sprintf("badly-formed %s (at line %d)", err.Format, err.Line)
+	default:
+		return "badly-formed " + err.Format
+	}
+}
+
+// Unwrap returns the underlying error.
+func (err *SyntaxError) Unwrap() error {
+	return err.Err
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (err *SyntaxError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// TypeMismatchError is returned when a value cannot be assigned to its destination.
+type TypeMismatchError struct {
+	// Field is the dotted path of the field, like "user.name", if known.
+	Field string
+	// Pointer is the JSON pointer (RFC 6901) of the field, like "/user/name", if known.
+	Pointer string
+	// Expected is the Go type of the destination, if known.
+	Expected string
+	// Actual is the kind of the provided value, like "number", "string", if known.
+	Actual string
+	// Value is the provided value, if known.
+	Value string
+	// Offset is the byte offset after which the error occurred, if known (0 otherwise).
+	Offset int64
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the error message, like `invalid value for the "name" field (at position 11)`.
+func (err *TypeMismatchError) Error() string {
+	switch {
+	case err.Field == "":
+		return fmt.Sprintf("invalid value %q", err.Value)
+	case err.Offset > 0:
+		return fmt.Sprintf("invalid value for the %q field (at position %d)", err.Field, err.Offset)
+	default:
+		return fmt.Sprintf("invalid value for the %q field", err.Field)
+	}
+}
+
+// Unwrap returns the underlying error.
+func (err *TypeMismatchError) Unwrap() error {
+	return err.Err
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (err *TypeMismatchError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// UnknownFieldError is returned when unknown fields are disallowed, and such a field is provided.
+type UnknownFieldError struct {
+	// Field is the unknown field's name.
+	Field string
+}
+
+// Error returns the error message, like `unknown field "extra"`.
+func (err *UnknownFieldError) Error() string {
+	return "unknown field " + strconv.Quote(err.Field)
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (err *UnknownFieldError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// EmptyBodyError is returned when there are no contents to decode.
+type EmptyBodyError struct{}
+
+// Error returns the error message.
+func (*EmptyBodyError) Error() string {
+	return "body must not be empty"
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (*EmptyBodyError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// TooLargeError is returned when contents exceed the [http.MaxBytesReader] limit.
+type TooLargeError struct {
+	// Limit is the maximum size, in bytes.
+	Limit int64
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (*TooLargeError) Error() string {
+	return "body too large"
+}
+
+// Unwrap returns the underlying error.
+func (err *TooLargeError) Unwrap() error {
+	return err.Err
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 413.
+func (*TooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// TrailingDataError is returned when there is more data after the decoded value.
+type TrailingDataError struct {
+	// Format is the decoded format, like "JSON", see Format* constants.
+	Format string
+}
+
+// Error returns the error message, like "json does not contain a single object",
+// or "MessagePack does not contain a single object".
+func (err *TrailingDataError) Error() string {
+	switch err.Format {
+	case FormatXML:
+		return "xml does not contain a single root element"
+	case FormatJSON: // message kept as it was before typed errors were introduced.
+		return "json does not contain a single object"
+	default:
+		return err.Format + " does not contain a single object"
+	}
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (*TrailingDataError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Limits reported by [LimitError].
+const (
+	LimitNestingDepth = "nesting depth"
+	LimitStringLength = "string length"
+)
+
+// LimitError is returned when contents exceed a configured limit, see [JSONConfig].
+type LimitError struct {
+	// Limit is the exceeded limit, see Limit* constants.
+	Limit string
+	// Max is the configured maximum value.
+	Max int
+	// Offset is the byte offset after which the error occurred.
+	Offset int64
+}
+
+// Error returns the error message, like "maximum nesting depth of 32 exceeded (at position 100)".
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("maximum %s of %d exceeded (at position %d)", err.Limit, err.Max, err.Offset)
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (*LimitError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// DuplicateKeyError is returned when duplicate keys are disallowed, and an object contains such a key.
+type DuplicateKeyError struct {
+	// Key is the duplicate key.
+	Key string
+	// Pointer is the JSON pointer (RFC 6901) of the duplicate key, like "/user/name".
+	Pointer string
+	// Offset is the byte offset after which the error occurred.
+	Offset int64
+}
+
+// Error returns the error message, like `duplicate key "name" (at position 20)`.
+func (err *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key %q (at position %d)", err.Key, err.Offset)
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 400.
+func (*DuplicateKeyError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// jsonPointerEscaper escapes a JSON pointer reference token.
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// jsonPointer converts a dotted field path (like "user.name") into a JSON pointer (like "/user/name").
+func jsonPointer(field string) string {
+	if field == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for part := range strings.SplitSeq(field, ".") {
+		sb.WriteByte('/')
+		sb.WriteString(jsonPointerEscaper.Replace(part))
+	}
+
+	return sb.String()
+}
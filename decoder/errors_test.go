@@ -0,0 +1,195 @@
+package decoder_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+type testNestedStruct struct {
+	User struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	} `json:"user"`
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name               string
+		decode             decoder.Decoder
+		reader             io.Reader
+		dest               any
+		expectedErr        error
+		expectedStatusCode int
+	}{
+		{
+			name:               "json syntax error",
+			decode:             decoder.DecodeJSON,
+			reader:             strings.NewReader(`{{"name":"John Doe"}`),
+			dest:               &testNestedStruct{},
+			expectedErr:        &decoder.SyntaxError{Format: decoder.FormatJSON, Offset: 2},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "json type mismatch error",
+			decode: decoder.DecodeJSON,
+			reader: strings.NewReader(`{"user":{"age":"42"}}`),
+			dest:   &testNestedStruct{},
+			expectedErr: &decoder.TypeMismatchError{
+				Field:    "user.age",
+				Pointer:  "/user/age",
+				Expected: "int",
+				Actual:   "string",
+				Offset:   19,
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "json empty body error",
+			decode:             decoder.DecodeJSON,
+			reader:             strings.NewReader(""),
+			dest:               &testNestedStruct{},
+			expectedErr:        &decoder.EmptyBodyError{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "json too large error",
+			decode:             decoder.DecodeJSON,
+			reader:             http.MaxBytesReader(nil, io.NopCloser(strings.NewReader(`{"user":{}}`)), 3),
+			dest:               &testNestedStruct{},
+			expectedErr:        &decoder.TooLargeError{Limit: 3},
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:               "json trailing data error",
+			decode:             decoder.DecodeJSON,
+			reader:             strings.NewReader(`{} {}`),
+			dest:               &testNestedStruct{},
+			expectedErr:        &decoder.TrailingDataError{Format: decoder.FormatJSON},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "xml syntax error",
+			decode:             decoder.DecodeXML,
+			reader:             strings.NewReader("<user>\n</usr>"),
+			dest:               &testDummyStruct{},
+			expectedErr:        &decoder.SyntaxError{Format: decoder.FormatXML, Line: 2},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "xml type mismatch error",
+			decode:             decoder.DecodeXML,
+			reader:             strings.NewReader("<user><Age>x</Age></user>"),
+			dest:               &struct{ Age int }{},
+			expectedErr:        &decoder.TypeMismatchError{Value: "x"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "form type mismatch error",
+			decode: decoder.DecodeForm,
+			reader: strings.NewReader("age=x"),
+			dest: &struct {
+				Age int `form:"age"`
+			}{},
+			expectedErr: &decoder.TypeMismatchError{
+				Field:    "age",
+				Pointer:  "/age",
+				Expected: "int",
+				Actual:   "string",
+				Value:    "x",
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "msgpack type mismatch error",
+			decode: decoder.DecodeMessagePack,
+			reader: bytes.NewReader([]byte{0x81, 0xa4, 'u', 's', 'e', 'r', 0x81, 0xa3, 'a', 'g', 'e', 0xa1, 'x'}),
+			dest:   &testNestedStruct{},
+			expectedErr: &decoder.TypeMismatchError{
+				Field:    "user.age",
+				Pointer:  "/user/age",
+				Expected: "int",
+				Actual:   "string",
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "cbor trailing data error",
+			decode:             decoder.DecodeCBOR,
+			reader:             bytes.NewReader([]byte{0xa0, 0xa0}),
+			dest:               &testNestedStruct{},
+			expectedErr:        &decoder.TrailingDataError{Format: decoder.FormatCBOR},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// act
+			err := test.decode(test.reader, test.dest)
+
+			// assert
+			if !assert.NotNil(t, err) {
+				return
+			}
+			target := reflect.New(reflect.TypeOf(test.expectedErr))
+			if !assert.True(t, errors.As(err, target.Interface())) {
+				return
+			}
+			actualErr := target.Elem().Interface().(error)
+			clearWrappedErr(actualErr)
+			assert.Equal(t, test.expectedErr, actualErr)
+			assert.Equal(t, test.expectedErr.Error(), err.Error())
+			statusCoder, ok := err.(interface{ StatusCode() int })
+			if assert.True(t, ok) {
+				assert.Equal(t, test.expectedStatusCode, statusCoder.StatusCode())
+			}
+		})
+	}
+}
+
+// clearWrappedErr resets the underlying error of given error, for comparison purposes.
+func clearWrappedErr(err error) {
+	switch e := err.(type) {
+	case *decoder.SyntaxError:
+		e.Err = nil
+	case *decoder.TypeMismatchError:
+		e.Err = nil
+	case *decoder.TooLargeError:
+		e.Err = nil
+	}
+}
+
+func TestUnknownFieldError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := &decoder.UnknownFieldError{Field: "extra"}
+
+	// act & assert
+	assert.Equal(t, `unknown field "extra"`, subject.Error())
+	assert.Equal(t, http.StatusBadRequest, subject.StatusCode())
+}
+
+func TestSyntaxError_Unwrap(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var dest testDummyStruct
+
+	// act
+	err := decoder.DecodeJSON(strings.NewReader(`{"Name":"John`), &dest)
+
+	// assert
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
@@ -0,0 +1,236 @@
+package decoder
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeForm decodes URL encoded form contents of a [io.Reader] into a variable,
+// which can be a pointer to a struct, to [url.Values], map[string][]string or map[string]string.
+//
+// Struct fields are matched by "form" tag (like `form:"user_name"`), or, if missing, by name, case insensitive.
+// A "-" tag skips the field. Embedded structs' fields are promoted.
+// Supported field types are: string, bool, ints, uints, floats, [time.Duration], [time.Time] (RFC 3339),
+// [encoding.TextUnmarshaler] implementations, pointers to and slices of these.
+func DecodeForm(r io.Reader, dest any) error {
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return &SyntaxError{Format: FormatForm, Err: err}
+	}
+
+	return assignForm(values, nil, dest)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	fileHeaderType      = reflect.TypeFor[*multipart.FileHeader]()
+)
+
+// assignForm assigns form values and files to dest.
+func assignForm(values map[string][]string, files map[string][]*multipart.FileHeader, dest any) error {
+	switch d := dest.(type) {
+	case *url.Values:
+		*d = values
+
+		return nil
+	case *map[string][]string:
+		*d = values
+
+		return nil
+	case *map[string]string:
+		*d = make(map[string]string, len(values))
+		for key, vals := range values {
+			if len(vals) > 0 {
+				(*d)[key] = vals[0]
+			}
+		}
+
+		return nil
+	}
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form: cannot decode into %T, a non-nil pointer to a struct is expected", dest)
+	}
+
+	return assignFormStruct(rv.Elem(), values, files)
+}
+
+func assignFormStruct(rv reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	rt := rv.Type()
+	for idx := range rt.NumField() {
+		field := rt.Field(idx)
+		tag := field.Tag.Get("form")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" {
+			fieldValue := rv.Field(idx)
+			if field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct {
+				if fieldValue.IsNil() {
+					if !fieldValue.CanSet() {
+						continue
+					}
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct && field.Type != timeType {
+				if err := assignFormStruct(fieldValue, values, files); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldValue := rv.Field(idx)
+		if fieldFiles := lookupForm(files, name, tag != ""); len(fieldFiles) > 0 {
+			if assigned := assignFormFiles(fieldValue, fieldFiles); assigned {
+				continue
+			}
+		}
+		fieldValues := lookupForm(values, name, tag != "")
+		if len(fieldValues) == 0 {
+			continue
+		}
+		if err := assignFormValues(fieldValue, fieldValues); err != nil {
+			return &TypeMismatchError{
+				Field:    name,
+				Pointer:  jsonPointer(name),
+				Expected: field.Type.String(),
+				Actual:   "string",
+				Value:    fieldValues[0],
+				Err:      err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupForm returns the values with given name; if not exact, name is matched case insensitive.
+func lookupForm[T any](values map[string][]T, name string, exact bool) []T {
+	if vals, found := values[name]; found || exact {
+		return vals
+	}
+	for key, vals := range values {
+		if strings.EqualFold(key, name) {
+			return vals
+		}
+	}
+
+	return nil
+}
+
+// assignFormFiles assigns files to a *multipart.FileHeader / []*multipart.FileHeader field.
+func assignFormFiles(fieldValue reflect.Value, files []*multipart.FileHeader) bool {
+	switch {
+	case fieldValue.Type() == fileHeaderType:
+		fieldValue.Set(reflect.ValueOf(files[0]))
+
+		return true
+	case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem() == fileHeaderType:
+		fieldValue.Set(reflect.ValueOf(files))
+
+		return true
+	default:
+		return false
+	}
+}
+
+func assignFormValues(fieldValue reflect.Value, values []string) error {
+	if fieldValue.Kind() == reflect.Slice && !reflect.PointerTo(fieldValue.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(fieldValue.Type(), len(values), len(values))
+		for idx, value := range values {
+			if err := assignFormValue(slice.Index(idx), value); err != nil {
+				return err
+			}
+		}
+		fieldValue.Set(slice)
+
+		return nil
+	}
+
+	return assignFormValue(fieldValue, values[0])
+}
+
+func assignFormValue(fieldValue reflect.Value, value string) error {
+	if fieldValue.Kind() == reflect.Pointer {
+		ptr := reflect.New(fieldValue.Type().Elem())
+		if err := assignFormValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		fieldValue.Set(ptr)
+
+		return nil
+	}
+	if unmarshaler, ok := fieldValue.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	if fieldValue.Type() == durationType {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetInt(int64(duration))
+
+		return nil
+	}
+
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(value)
+	case reflect.Bool:
+		if value == "on" { // checkbox
+			value = "true"
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, fieldValue.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fieldValue.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, fieldValue.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fieldValue.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, fieldValue.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fieldValue.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s", fieldValue.Type())
+	}
+
+	return nil
+}
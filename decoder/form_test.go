@@ -0,0 +1,137 @@
+package decoder_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+type testFormEmbedded struct {
+	Page int `form:"page"`
+}
+
+type testFormStruct struct {
+	testFormEmbedded
+
+	UserName string        `form:"user_name"`
+	Age      uint8         `form:"age"`
+	Score    float64       `form:"score"`
+	Active   bool          `form:"active"`
+	Timeout  time.Duration `form:"timeout"`
+	Since    time.Time     `form:"since"`
+	Tags     []string      `form:"tag"`
+	Limit    *int
+	Ignored  string `form:"-"`
+}
+
+func TestDecodeForm(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully decodes form into struct", testDecodeFormStruct)
+	t.Run("successfully decodes form into map", testDecodeFormMap)
+	t.Run("returns error", testDecodeFormErr)
+}
+
+func testDecodeFormStruct(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		r = strings.NewReader(
+			"user_name=John+Doe&age=42&score=9.5&active=on&timeout=2s&since=2026-01-02T03%3A04%3A05Z" +
+				"&tag=a&tag=b&LIMIT=10&page=3&Ignored=x",
+		)
+		dest  testFormStruct
+		limit = 10
+	)
+
+	// act
+	err := decoder.DecodeForm(r, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, testFormStruct{
+			testFormEmbedded: testFormEmbedded{Page: 3},
+			UserName:         "John Doe",
+			Age:              42,
+			Score:            9.5,
+			Active:           true,
+			Timeout:          2 * time.Second,
+			Since:            time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Tags:             []string{"a", "b"},
+			Limit:            &limit,
+		}, dest)
+	}
+}
+
+func testDecodeFormMap(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		values url.Values
+		m      map[string]string
+	)
+
+	// act
+	err1 := decoder.DecodeForm(strings.NewReader("a=1&a=2&b=3"), &values)
+	err2 := decoder.DecodeForm(strings.NewReader("a=1&a=2&b=3"), &m)
+
+	// assert
+	if assert.Nil(t, err1) {
+		assert.Equal(t, url.Values{"a": {"1", "2"}, "b": {"3"}}, values)
+	}
+	if assert.Nil(t, err2) {
+		assert.Equal(t, map[string]string{"a": "1", "b": "3"}, m)
+	}
+}
+
+func testDecodeFormErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{
+			name:          "returns empty body error",
+			input:         "",
+			expectedError: "body must not be empty",
+		},
+		{
+			name:          "returns malformed form error",
+			input:         "user_name=%zz",
+			expectedError: "badly-formed form",
+		},
+		{
+			name:          "returns invalid field value error",
+			input:         "age=300",
+			expectedError: `invalid value for the "age" field`,
+		},
+		{
+			name:          "returns invalid bool field value error",
+			input:         "active=maybe",
+			expectedError: `invalid value for the "active" field`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dest testFormStruct
+
+			// act
+			actualError := decoder.DecodeForm(strings.NewReader(test.input), &dest)
+
+			// assert
+			if assert.NotNil(t, actualError) {
+				assert.Equal(t, test.expectedError, actualError.Error())
+			}
+		})
+	}
+}
@@ -1,50 +1,235 @@
 package decoder
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"strings"
 )
 
 // DecodeJSON decodes JSON contents of a [io.Reader] into a variable.
+// Returned errors can be inspected with [errors.As], see [SyntaxError], [TypeMismatchError],
+// [UnknownFieldError], [EmptyBodyError], [TooLargeError], [TrailingDataError].
+// For stricter decoding, see [NewJSONDecoder].
 func DecodeJSON(r io.Reader, dest any) error {
-	dec := json.NewDecoder(r)
-	// dec.DisallowUnknownFields()
-	if err := dec.Decode(dest); err != nil {
-		var (
-			syntaxError        *json.SyntaxError
-			unmarshalTypeError *json.UnmarshalTypeError
-		)
-
-		switch {
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf("badly-formed JSON (at position %d)", syntaxError.Offset)
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("badly-formed JSON")
-		case errors.As(err, &unmarshalTypeError):
-			return fmt.Errorf(
-				"invalid value for the %q field (at position %d)",
-				unmarshalTypeError.Field,
-				unmarshalTypeError.Offset,
-			)
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-
-			return fmt.Errorf("unknown field %s", fieldName)
-		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
-		case err.Error() == "http: request body too large":
-			return errors.New("body too large")
-		default:
+	return decodeJSON(r, dest, JSONConfig{})
+}
+
+// JSONConfig holds the configuration for a JSON decoder, see [NewJSONDecoder].
+type JSONConfig struct {
+	// DisallowUnknownFields makes the decoder return an [*UnknownFieldError]
+	// if an object contains a key which does not match any destination struct field.
+	DisallowUnknownFields bool
+	// UseNumber makes the decoder unmarshal a number into an interface value as a [json.Number]
+	// instead of as a float64.
+	UseNumber bool
+	// MaxDepth is the maximum nesting depth of objects and arrays.
+	// If exceeded, a [*LimitError] is returned. Zero means no limit.
+	MaxDepth int
+	// MaxStringLength is the maximum length, in bytes, of a string (object keys included).
+	// If exceeded, a [*LimitError] is returned. Zero means no limit.
+	MaxStringLength int
+	// DisallowDuplicateKeys makes the decoder return a [*DuplicateKeyError]
+	// if an object contains the same key more than once.
+	DisallowDuplicateKeys bool
+	// AllowMultipleObjects makes the decoder accept concatenated values, like `{"a":1}{"a":2}`,
+	// case in which only the first value is decoded.
+	// By default, a [*TrailingDataError] is returned.
+	AllowMultipleObjects bool
+	// Backend is the JSON decoding implementation. Defaults to [StdJSONBackend].
+	Backend JSONBackend
+}
+
+// needsScan returns true if contents need to be scanned before being decoded.
+func (config JSONConfig) needsScan() bool {
+	return config.MaxDepth > 0 || config.MaxStringLength > 0 || config.DisallowDuplicateKeys
+}
+
+// NewJSONDecoder returns a JSON [Decoder] configured with given options.
+// It behaves like [DecodeJSON], with the extra constraints.
+// If depth, string length or duplicate keys checks are enabled, contents are scanned
+// (and thus, fully read into memory) before being decoded.
+func NewJSONDecoder(config JSONConfig) Decoder {
+	return func(r io.Reader, dest any) error {
+		return decodeJSON(r, dest, config)
+	}
+}
+
+func decodeJSON(r io.Reader, dest any, config JSONConfig) error {
+	if config.needsScan() {
+		data, err := readBody(r)
+		if err != nil {
 			return err
 		}
+		if err := scanJSON(data, config); err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	backend := config.Backend
+	if backend == nil {
+		backend = StdJSONBackend{}
+	}
+	dec := backend.NewDecoder(r, config)
+	if err := dec.Decode(dest); err != nil {
+		return translateJSONError(err)
+	}
+	if config.AllowMultipleObjects {
+		return nil
 	}
 	err := dec.Decode(&struct{}{})
 	if !errors.Is(err, io.EOF) {
-		return errors.New("json does not contain a single object")
+		return &TrailingDataError{Format: FormatJSON}
 	}
 
 	return nil
 }
+
+// jsonScanFrame holds the state of an object / array being scanned.
+type jsonScanFrame struct {
+	object    bool                // true for object, false for array
+	expectKey bool                // for objects, if next string token is a key
+	keys      map[string]struct{} // for objects, if duplicate keys are disallowed
+	segment   string              // current key / index, for JSON pointer
+	nextIdx   int                 // for arrays, next element's index
+}
+
+// scanJSON checks first JSON value from data against depth, string length and duplicate keys constraints.
+func scanJSON(data []byte, config JSONConfig) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // avoid float parsing
+	var stack []*jsonScanFrame
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return translateJSONError(err)
+		}
+
+		var parent *jsonScanFrame
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		if str, isStr := token.(string); isStr && config.MaxStringLength > 0 && len(str) > config.MaxStringLength {
+			return &LimitError{Limit: LimitStringLength, Max: config.MaxStringLength, Offset: dec.InputOffset()}
+		}
+		if key, isStr := token.(string); isStr && parent != nil && parent.object && parent.expectKey {
+			parent.expectKey = false
+			parent.segment = key
+			if parent.keys != nil {
+				if _, found := parent.keys[key]; found {
+					return &DuplicateKeyError{Key: key, Pointer: jsonScanPointer(stack), Offset: dec.InputOffset()}
+				}
+				parent.keys[key] = struct{}{}
+			}
+
+			continue
+		}
+
+		if delim, isDelim := token.(json.Delim); isDelim && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+		} else { // a value starts
+			if parent != nil {
+				if parent.object {
+					parent.expectKey = true
+				} else {
+					parent.segment = strconv.Itoa(parent.nextIdx)
+					parent.nextIdx++
+				}
+			}
+			if isDelim && (delim == '{' || delim == '[') {
+				if config.MaxDepth > 0 && len(stack) >= config.MaxDepth {
+					return &LimitError{Limit: LimitNestingDepth, Max: config.MaxDepth, Offset: dec.InputOffset()}
+				}
+				frame := &jsonScanFrame{object: delim == '{', expectKey: delim == '{'}
+				if frame.object && config.DisallowDuplicateKeys {
+					frame.keys = make(map[string]struct{})
+				}
+				stack = append(stack, frame)
+			}
+		}
+		if len(stack) == 0 {
+			return nil
+		}
+	}
+}
+
+// jsonScanPointer returns the JSON pointer of the current key / index of the innermost frame.
+func jsonScanPointer(stack []*jsonScanFrame) string {
+	var sb strings.Builder
+	for _, frame := range stack {
+		sb.WriteByte('/')
+		sb.WriteString(jsonPointerEscaper.Replace(frame.segment))
+	}
+
+	return sb.String()
+}
+
+// unknownFieldErrPrefix is the prefix of [json.Decoder] unknown field errors, which are not typed.
+const unknownFieldErrPrefix = "json: unknown field "
+
+// stdJSONUnknownField returns the field name of an [encoding/json] unknown field error.
+// As encoding/json does not provide a typed error for it, its message is matched;
+// backends having a typed error should return an [*UnknownFieldError] instead, which is passed through.
+func stdJSONUnknownField(err error) (string, bool) {
+	fieldName, found := strings.CutPrefix(err.Error(), unknownFieldErrPrefix)
+	if !found {
+		return "", false
+	}
+	if unquoted, unquoteErr := strconv.Unquote(fieldName); unquoteErr == nil {
+		fieldName = unquoted
+	}
+
+	return fieldName, true
+}
+
+// translateJSONError converts an [encoding/json] error into one of this package's errors.
+// This package's errors (and other typed errors having a StatusCode method) are returned as they are.
+func translateJSONError(err error) error {
+	var (
+		typedError         interface{ StatusCode() int }
+		syntaxError        *json.SyntaxError
+		unmarshalTypeError *json.UnmarshalTypeError
+		maxBytesError      *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &typedError):
+		return err
+	case errors.As(err, &syntaxError):
+		return &SyntaxError{Format: FormatJSON, Offset: syntaxError.Offset, Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &SyntaxError{Format: FormatJSON, Err: err}
+	case errors.As(err, &unmarshalTypeError):
+		return newJSONTypeMismatchError(unmarshalTypeError)
+	case errors.Is(err, io.EOF):
+		return &EmptyBodyError{}
+	case errors.As(err, &maxBytesError):
+		return &TooLargeError{Limit: maxBytesError.Limit, Err: err}
+	default:
+		if fieldName, isUnknownField := stdJSONUnknownField(err); isUnknownField {
+			return &UnknownFieldError{Field: fieldName}
+		}
+
+		return err
+	}
+}
+
+// newJSONTypeMismatchError creates a [TypeMismatchError] from a [json.UnmarshalTypeError].
+func newJSONTypeMismatchError(err *json.UnmarshalTypeError) *TypeMismatchError {
+	mismatchErr := &TypeMismatchError{
+		Field:   err.Field,
+		Pointer: jsonPointer(err.Field),
+		Actual:  err.Value,
+		Offset:  err.Offset,
+		Err:     err,
+	}
+	if err.Type != nil {
+		mismatchErr.Expected = err.Type.String()
+	}
+
+	return mismatchErr
+}
@@ -0,0 +1,46 @@
+package decoder
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONBackend is a JSON decoding implementation, see [JSONConfig.Backend].
+// The default one is [StdJSONBackend]. Others can be plugged in for better performance, like [JSONv2Backend]
+// (available with Go 1.27+ and GOEXPERIMENT=jsonv2), or an adapter of a third-party codec.
+//
+// Errors are translated the same way whatever the backend is: [encoding/json] errors
+// (like [*json.SyntaxError], [*json.UnmarshalTypeError], [io.ErrUnexpectedEOF]) are converted
+// into this package's errors, while this package's errors are returned as they are.
+// So an adapter should return either [encoding/json] like errors, or this package's errors.
+//
+// To use a backend for all JSON requests, register it into the [Registry]:
+//
+//	registry.Register(decoder.MediaTypeJSON, decoder.NewJSONDecoder(decoder.JSONConfig{Backend: backend}))
+type JSONBackend interface {
+	// NewDecoder returns a decoder of the JSON values read from given reader,
+	// honouring config's DisallowUnknownFields and UseNumber options.
+	NewDecoder(r io.Reader, config JSONConfig) JSONStreamDecoder
+}
+
+// JSONStreamDecoder decodes consecutive JSON values from a stream.
+type JSONStreamDecoder interface {
+	// Decode decodes next JSON value into dest. It returns [io.EOF] if there are no more values.
+	Decode(dest any) error
+}
+
+// StdJSONBackend is the [encoding/json] based [JSONBackend].
+type StdJSONBackend struct{}
+
+// NewDecoder returns a [json.Decoder] reading from given reader.
+func (StdJSONBackend) NewDecoder(r io.Reader, config JSONConfig) JSONStreamDecoder {
+	dec := json.NewDecoder(r)
+	if config.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if config.UseNumber {
+		dec.UseNumber()
+	}
+
+	return dec
+}
@@ -0,0 +1,110 @@
+package decoder_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+// mockJSONBackend is a [decoder.JSONBackend] which returns configured error.
+type mockJSONBackend struct {
+	err    error
+	config decoder.JSONConfig
+}
+
+func (backend *mockJSONBackend) NewDecoder(_ io.Reader, config decoder.JSONConfig) decoder.JSONStreamDecoder {
+	backend.config = config
+
+	return backend
+}
+
+func (backend *mockJSONBackend) Decode(any) error {
+	return backend.err
+}
+
+func TestJSONConfig_Backend(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes config to backend", testJSONConfigBackendConfig)
+	t.Run("translates backend errors", testJSONConfigBackendErr)
+}
+
+func testJSONConfigBackendConfig(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		backend = &mockJSONBackend{err: io.EOF}
+		config  = decoder.JSONConfig{DisallowUnknownFields: true, UseNumber: true, AllowMultipleObjects: true}
+		dest    testDummyStruct
+	)
+	config.Backend = backend
+	subject := decoder.NewJSONDecoder(config)
+
+	// act
+	err := subject(strings.NewReader(`{}`), &dest)
+
+	// assert
+	var emptyErr *decoder.EmptyBodyError
+	assert.True(t, errors.As(err, &emptyErr))
+	assert.True(t, backend.config.DisallowUnknownFields)
+	assert.True(t, backend.config.UseNumber)
+}
+
+func testJSONConfigBackendErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name        string
+		backendErr  error
+		expectedErr error
+	}{
+		{
+			name:        "encoding/json like error is translated",
+			backendErr:  &json.UnmarshalTypeError{Value: "string", Field: "user.age", Offset: 10},
+			expectedErr: &decoder.TypeMismatchError{Field: "user.age", Pointer: "/user/age", Actual: "string", Offset: 10},
+		},
+		{
+			name:        "unexpected EOF is translated",
+			backendErr:  io.ErrUnexpectedEOF,
+			expectedErr: &decoder.SyntaxError{Format: decoder.FormatJSON, Err: io.ErrUnexpectedEOF},
+		},
+		{
+			name:        "package error is returned as it is",
+			backendErr:  &decoder.SyntaxError{Format: decoder.FormatJSON, Offset: 3, Err: io.ErrUnexpectedEOF},
+			expectedErr: &decoder.SyntaxError{Format: decoder.FormatJSON, Offset: 3, Err: io.ErrUnexpectedEOF},
+		},
+		{
+			name:        "other error is returned as it is",
+			backendErr:  errors.New("intentionally triggered backend error"),
+			expectedErr: errors.New("intentionally triggered backend error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				subject = decoder.NewJSONDecoder(decoder.JSONConfig{Backend: &mockJSONBackend{err: test.backendErr}})
+				dest    testDummyStruct
+			)
+
+			// act
+			err := subject(strings.NewReader(`{}`), &dest)
+
+			// assert
+			if mismatchErr, ok := err.(*decoder.TypeMismatchError); ok {
+				mismatchErr.Err = nil
+			}
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package decoder_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/decoder"
+)
+
+// benchJSONBackends holds the benchmarked JSON backends, by name.
+var benchJSONBackends = map[string]decoder.JSONBackend{
+	"std": decoder.StdJSONBackend{},
+}
+
+type benchAddress struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	ZipCode string `json:"zipCode"`
+	Country string `json:"country"`
+}
+
+type benchOrderItem struct {
+	SKU       string            `json:"sku"`
+	Name      string            `json:"name"`
+	Quantity  int               `json:"quantity"`
+	UnitPrice float64           `json:"unitPrice"`
+	Tags      []string          `json:"tags"`
+	Attrs     map[string]string `json:"attrs"`
+}
+
+type benchOrder struct {
+	ID        string           `json:"id"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Customer  benchCustomer    `json:"customer"`
+	Items     []benchOrderItem `json:"items"`
+	Total     float64          `json:"total"`
+	Paid      bool             `json:"paid"`
+	Notes     *string          `json:"notes"`
+}
+
+type benchCustomer struct {
+	ID      int64        `json:"id"`
+	Name    string       `json:"name"`
+	Email   string       `json:"email"`
+	Address benchAddress `json:"address"`
+}
+
+type benchLogin struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	RememberMe bool   `json:"rememberMe"`
+}
+
+// benchJSONCase is a representative JSON payload, and its destination factory.
+type benchJSONCase struct {
+	name    string
+	payload []byte
+	newDest func() any
+}
+
+// benchJSONCases returns representative JSON payloads: a small flat object, a medium nested one
+// (decoded into a struct, and into a map), and a large array of small objects.
+func benchJSONCases(b *testing.B) []benchJSONCase {
+	b.Helper()
+
+	order := benchOrder{
+		ID:        "ord-7f3c2a91",
+		CreatedAt: time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC),
+		Customer: benchCustomer{
+			ID:    123456,
+			Name:  "John Doe",
+			Email: "john.doe@example.com",
+			Address: benchAddress{
+				Street:  "221B Baker Street",
+				City:    "London",
+				ZipCode: "NW1 6XE",
+				Country: "UK",
+			},
+		},
+		Total: 1234.56,
+		Paid:  true,
+	}
+	for idx := range 20 {
+		order.Items = append(order.Items, benchOrderItem{
+			SKU:       fmt.Sprintf("SKU-%05d", idx),
+			Name:      fmt.Sprintf("Product with a reasonably long descriptive name #%d", idx),
+			Quantity:  idx + 1,
+			UnitPrice: 9.99 * float64(idx+1),
+			Tags:      []string{"electronics", "sale", "new"},
+			Attrs:     map[string]string{"color": "black", "size": "M"},
+		})
+	}
+	orderPayload, err := json.Marshal(order)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var events bytes.Buffer
+	events.WriteByte('[')
+	for idx := range 500 {
+		if idx > 0 {
+			events.WriteByte(',')
+		}
+		fmt.Fprintf(&events, `{"id":%d,"type":"page_view","ts":%d,"path":"/products/%d","durationMs":%d.5}`,
+			idx, 1715941800+idx, idx%50, idx*3)
+	}
+	events.WriteByte(']')
+
+	return []benchJSONCase{
+		{
+			name:    "small_struct",
+			payload: []byte(`{"username":"john.doe@example.com","password":"s3cr3t-P@ssw0rd","rememberMe":true}`),
+			newDest: func() any { return new(benchLogin) },
+		},
+		{
+			name:    "medium_struct",
+			payload: orderPayload,
+			newDest: func() any { return new(benchOrder) },
+		},
+		{
+			name:    "medium_map",
+			payload: orderPayload,
+			newDest: func() any { return new(map[string]any) },
+		},
+		{
+			name:    "large_array",
+			payload: events.Bytes(),
+			newDest: func() any { return new([]map[string]any) },
+		},
+	}
+}
+
+// BenchmarkJSONBackend compares JSON backends' throughput and allocations.
+// Run with GOEXPERIMENT=jsonv2 (Go 1.27+) to include the json/v2 backend:
+//
+//	GOEXPERIMENT=jsonv2 go test -run=^$ -bench=BenchmarkJSONBackend -benchmem ./decoder/
+//
+// Note that with GOEXPERIMENT=jsonv2, [encoding/json] itself is implemented upon json/v2.
+func BenchmarkJSONBackend(b *testing.B) {
+	for _, test := range benchJSONCases(b) {
+		for _, backendName := range []string{"std", "v2"} {
+			backend, found := benchJSONBackends[backendName]
+			if !found {
+				continue
+			}
+			subject := decoder.NewJSONDecoder(decoder.JSONConfig{Backend: backend})
+			b.Run(test.name+"/"+backendName, func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(test.payload)))
+				b.ResetTimer()
+
+				for range b.N {
+					if err := subject(bytes.NewReader(test.payload), test.newDest()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
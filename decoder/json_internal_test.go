@@ -0,0 +1,32 @@
+package decoder
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+// TestStdJSONUnknownField fails if encoding/json changes its unknown field error message,
+// which is matched, as it is not typed.
+func TestStdJSONUnknownField(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	dec := json.NewDecoder(strings.NewReader(`{"name":"John","extra":1}`))
+	dec.DisallowUnknownFields()
+	var dest struct {
+		Name string `json:"name"`
+	}
+	decodeErr := dec.Decode(&dest)
+
+	// act
+	fieldName, isUnknownField := stdJSONUnknownField(decodeErr)
+	err := translateJSONError(decodeErr)
+
+	// assert
+	assert.True(t, isUnknownField)
+	assert.Equal(t, "extra", fieldName)
+	assert.Equal(t, error(&UnknownFieldError{Field: "extra"}), err)
+}
@@ -1,8 +1,11 @@
 package decoder_test
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -66,6 +69,7 @@ func testDecodeJSONErr(t *testing.T) {
 	var dest testDummyStruct
 	tests := [...]struct {
 		name          string
+		decode        decoder.Decoder // DecodeJSON if nil
 		reader        io.Reader
 		expectedError string
 	}{
@@ -84,11 +88,12 @@ func testDecodeJSONErr(t *testing.T) {
 			reader:        strings.NewReader(`{"Name":123}`),
 			expectedError: `invalid value for the "Name" field (at position 11)`,
 		},
-		// {
-		// 	name:          "returns extra field error",
-		// 	reader:        strings.NewReader(`{"Name":"John Doe","ExtraField":"trigger error"}`),
-		// 	expectedError: `unknown field "ExtraField"`,
-		// },
+		{
+			name:          "returns extra field error",
+			decode:        decoder.NewJSONDecoder(decoder.JSONConfig{DisallowUnknownFields: true}),
+			reader:        strings.NewReader(`{"Name":"John Doe","ExtraField":"trigger error"}`),
+			expectedError: `unknown field "ExtraField"`,
+		},
 		{
 			name:          "returns malformed json error at position",
 			reader:        strings.NewReader(`{{"Name":"John Doe"}`),
@@ -108,8 +113,13 @@ func testDecodeJSONErr(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			decode := test.decode
+			if decode == nil {
+				decode = decoder.DecodeJSON
+			}
+
 			// act
-			actualError := decoder.DecodeJSON(test.reader, &dest)
+			actualError := decode(test.reader, &dest)
 
 			// assert
 			if assert.NotNil(t, actualError) {
@@ -118,3 +128,160 @@ func testDecodeJSONErr(t *testing.T) {
 		})
 	}
 }
+
+func TestNewJSONDecoder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decodes with default config like DecodeJSON", testNewJSONDecoderDefault)
+	t.Run("uses number", testNewJSONDecoderUseNumber)
+	t.Run("allows multiple objects", testNewJSONDecoderAllowMultipleObjects)
+	t.Run("passes strict checks", testNewJSONDecoderStrictSuccess)
+	t.Run("returns strict checks errors", testNewJSONDecoderStrictErr)
+}
+
+func testNewJSONDecoderDefault(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewJSONDecoder(decoder.JSONConfig{})
+		dest    testDummyStruct
+	)
+
+	// act
+	err1 := subject(strings.NewReader(`{"Name":"John Doe","ExtraField":"ignored"}`), &dest)
+	err2 := subject(strings.NewReader(`{"Name":"John Doe"}{"Name":"Jane Doe"}`), &dest)
+
+	// assert
+	assert.Nil(t, err1)
+	assert.Equal(t, "John Doe", dest.Name)
+	var trailingErr *decoder.TrailingDataError
+	assert.True(t, errors.As(err2, &trailingErr))
+}
+
+func testNewJSONDecoderUseNumber(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewJSONDecoder(decoder.JSONConfig{UseNumber: true})
+		dest    map[string]any
+	)
+
+	// act
+	err := subject(strings.NewReader(`{"id":12345678901234567890}`), &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, json.Number("12345678901234567890"), dest["id"])
+	}
+}
+
+func testNewJSONDecoderAllowMultipleObjects(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewJSONDecoder(decoder.JSONConfig{AllowMultipleObjects: true, MaxDepth: 1})
+		dest    testDummyStruct
+	)
+
+	// act
+	err := subject(strings.NewReader(`{"Name":"John Doe"} {"Name":"Jane Doe"} [[[]]]`), &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
+
+func testNewJSONDecoderStrictSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewJSONDecoder(decoder.JSONConfig{
+			MaxDepth:              3,
+			MaxStringLength:       8,
+			DisallowDuplicateKeys: true,
+		})
+		dest map[string]any
+	)
+
+	// act
+	err := subject(strings.NewReader(`{"a":{"b":[1,"John Doe"]},"b":{"a":1,"b":[1,2]},"c":{}}`), &dest)
+
+	// assert
+	assert.Nil(t, err)
+}
+
+func testNewJSONDecoderStrictErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := decoder.NewJSONDecoder(decoder.JSONConfig{
+		MaxDepth:              3,
+		MaxStringLength:       8,
+		DisallowDuplicateKeys: true,
+	})
+	tests := [...]struct {
+		name        string
+		input       string
+		expectedErr error
+	}{
+		{
+			name:        "too deep",
+			input:       `{"a":[[{"b":1}]]}`,
+			expectedErr: &decoder.LimitError{Limit: decoder.LimitNestingDepth, Max: 3, Offset: 8},
+		},
+		{
+			name:        "too long string value",
+			input:       `{"a":"John Doe Jr."}`,
+			expectedErr: &decoder.LimitError{Limit: decoder.LimitStringLength, Max: 8, Offset: 19},
+		},
+		{
+			name:        "too long key",
+			input:       `{"abcdefghi":1}`,
+			expectedErr: &decoder.LimitError{Limit: decoder.LimitStringLength, Max: 8, Offset: 12},
+		},
+		{
+			name:        "duplicate key at root",
+			input:       `{"a":1,"b":2,"a":3}`,
+			expectedErr: &decoder.DuplicateKeyError{Key: "a", Pointer: "/a", Offset: 16},
+		},
+		{
+			name:        "duplicate key nested",
+			input:       `{"a":[{"x":1},{"x":1,"x/y":2,"x/y":3}]}`,
+			expectedErr: &decoder.DuplicateKeyError{Key: "x/y", Pointer: "/a/1/x~1y", Offset: 34},
+		},
+		{
+			name:        "malformed",
+			input:       `{"a":}`,
+			expectedErr: &decoder.SyntaxError{Format: decoder.FormatJSON, Offset: 6},
+		},
+		{
+			name:        "empty",
+			input:       ``,
+			expectedErr: &decoder.EmptyBodyError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dest map[string]any
+
+			// act
+			err := subject(strings.NewReader(test.input), &dest)
+
+			// assert
+			if assert.NotNil(t, err) {
+				assert.Equal(t, test.expectedErr.Error(), err.Error())
+				target := reflect.New(reflect.TypeOf(test.expectedErr))
+				assert.True(t, errors.As(err, target.Interface()))
+				if dupErr, ok := err.(*decoder.DuplicateKeyError); ok {
+					assert.Equal(t, test.expectedErr, error(dupErr))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+//go:build go1.27 && goexperiment.jsonv2
+
+package decoder_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func init() {
+	benchJSONBackends["v2"] = decoder.JSONv2Backend{}
+}
+
+type testJSONv2Struct struct {
+	Name  string `json:"name"`
+	Age   int    `json:"age"`
+	Inner struct {
+		Zip int `json:"zip"`
+	} `json:"inner"`
+	Items []struct {
+		Qty int `json:"qty"`
+	} `json:"items"`
+	Any any `json:"any"`
+}
+
+func TestJSONv2Backend(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decodes like std backend", testJSONv2BackendSuccess)
+	t.Run("translates errors like std backend", testJSONv2BackendErr)
+	t.Run("returns json/v2 specific errors", testJSONv2BackendErrSpecific)
+}
+
+func testJSONv2BackendSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		input   = `{"name":"John Doe","age":30,"inner":{"zip":123},"items":[{"qty":1},{"qty":2}],"any":1.5}`
+		config  = decoder.JSONConfig{DisallowUnknownFields: true, UseNumber: true}
+		stdDest testJSONv2Struct
+		v2Dest  testJSONv2Struct
+	)
+	stdSubject := decoder.NewJSONDecoder(config)
+	config.Backend = decoder.JSONv2Backend{}
+	v2Subject := decoder.NewJSONDecoder(config)
+
+	// act
+	stdErr := stdSubject(strings.NewReader(input), &stdDest)
+	v2Err := v2Subject(strings.NewReader(input), &v2Dest)
+
+	// assert
+	assert.Nil(t, stdErr)
+	assert.Nil(t, v2Err)
+	assert.Equal(t, stdDest, v2Dest)
+	assert.Equal(t, json.Number("1.5"), v2Dest.Any)
+}
+
+func testJSONv2BackendErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	inputs := [...]string{
+		``,
+		`   `,
+		`{"name":"John Doe"`,
+		`{"name":}`,
+		`{"age":"30"}`,
+		`{"age":1.5}`,
+		`{"age":300000000000000000000}`,
+		`{"name":12}`,
+		`{"name":true}`,
+		`{"inner":{"zip":"x"}}`,
+		`{"items":[{"qty":1},{"qty":"x"}],"age":"x"}`,
+		`[1]`,
+		`{"extra":1}`,
+		`{"name":"John Doe"}{"name":"Jane Doe"}`,
+		`{"name":"John Doe"} x`,
+	}
+	config := decoder.JSONConfig{DisallowUnknownFields: true}
+	stdSubject := decoder.NewJSONDecoder(config)
+	config.Backend = decoder.JSONv2Backend{}
+	v2Subject := decoder.NewJSONDecoder(config)
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			var stdDest, v2Dest testJSONv2Struct
+
+			// act
+			stdErr := stdSubject(strings.NewReader(input), &stdDest)
+			v2Err := v2Subject(strings.NewReader(input), &v2Dest)
+
+			// assert
+			if assert.NotNil(t, stdErr) && assert.NotNil(t, v2Err) {
+				assert.Equal(t, reflect.TypeOf(stdErr), reflect.TypeOf(v2Err))
+				assert.Equal(t, stdErr.Error(), v2Err.Error())
+				var stdMismatchErr, v2MismatchErr *decoder.TypeMismatchError
+				if errors.As(stdErr, &stdMismatchErr) && errors.As(v2Err, &v2MismatchErr) {
+					stdMismatchErr.Err, v2MismatchErr.Err = nil, nil
+					assert.Equal(t, stdMismatchErr, v2MismatchErr)
+				}
+			}
+		})
+	}
+}
+
+func testJSONv2BackendErrSpecific(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := decoder.NewJSONDecoder(decoder.JSONConfig{
+		DisallowUnknownFields: true,
+		Backend:               decoder.JSONv2Backend{},
+	})
+	tests := [...]struct {
+		name        string
+		input       string
+		expectedErr error
+	}{
+		{
+			name:        "duplicate key",
+			input:       `{"name":"John Doe","name":"Jane Doe"}`,
+			expectedErr: &decoder.DuplicateKeyError{Key: "name", Pointer: "/name", Offset: 25},
+		},
+		{
+			name:        "case-insensitive key",
+			input:       `{"Name":"John Doe"}`,
+			expectedErr: &decoder.UnknownFieldError{Field: "Name"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var dest testJSONv2Struct
+
+			// act
+			err := subject(strings.NewReader(test.input), &dest)
+
+			// assert
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
@@ -0,0 +1,616 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math/big"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fsScheme is the URI scheme of schemas loaded from a [Loader]'s file system.
+const fsScheme = "fs://"
+
+// defaultBaseURI is the base URI of schemas compiled with [Compile], not having an $id.
+const defaultBaseURI = "mem:///schema.json"
+
+// Subschema keywords, used to walk schemas.
+var (
+	schemaKeywords = []string{
+		"additionalProperties", "items", "contains", "not", "if", "then", "else",
+		"propertyNames", "unevaluatedItems", "unevaluatedProperties", "contentSchema",
+	}
+	schemaArrayKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	schemaMapKeywords   = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"}
+)
+
+// compiler compiles raw JSON schemas into [schemaNode]s, resolving references.
+// Locations are represented as document URI + "#" + JSON pointer within the document.
+type compiler struct {
+	fsys      fs.FS                  // file system to load referenced documents from, may be nil
+	scanned   bool                   // whether fsys was scanned for documents' $id
+	docs      map[string]any         // document URI => raw JSON
+	resources map[string]string      // resource base URI => location
+	anchors   map[string]string      // resource base URI#anchor => location
+	bases     map[string]string      // location => base URI
+	nodes     map[string]*schemaNode // location => compiled node
+}
+
+func newCompiler(fsys fs.FS) *compiler {
+	return &compiler{
+		fsys:      fsys,
+		docs:      make(map[string]any),
+		resources: make(map[string]string),
+		anchors:   make(map[string]string),
+		bases:     make(map[string]string),
+		nodes:     make(map[string]*schemaNode),
+	}
+}
+
+// parseJSON parses a JSON document, keeping numbers' precision.
+func parseJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, errors.New("invalid character after top-level value")
+	}
+
+	return raw, nil
+}
+
+// addDoc registers a document and the resources / anchors it contains.
+func (c *compiler) addDoc(uri string, raw any) {
+	c.docs[uri] = raw
+	c.resources[uri] = uri + "#"
+	c.walk(raw, uri, "", uri)
+}
+
+func (c *compiler) walk(raw any, doc, ptr, base string) {
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return
+	}
+	loc := doc + "#" + ptr
+	if id, ok := obj["$id"].(string); ok {
+		base, _ = splitFragment(resolveURI(base, id))
+		c.resources[base] = loc
+	}
+	c.bases[loc] = base
+	for _, keyword := range [...]string{"$anchor", "$dynamicAnchor"} {
+		if anchor, ok := obj[keyword].(string); ok {
+			c.anchors[base+"#"+anchor] = loc
+		}
+	}
+
+	for _, keyword := range schemaKeywords {
+		if sub, found := obj[keyword]; found {
+			c.walk(sub, doc, ptr+"/"+escapePointer(keyword), base)
+		}
+	}
+	for _, keyword := range schemaArrayKeywords {
+		if subs, ok := obj[keyword].([]any); ok {
+			for idx, sub := range subs {
+				c.walk(sub, doc, ptr+"/"+keyword+"/"+strconv.Itoa(idx), base)
+			}
+		}
+	}
+	for _, keyword := range schemaMapKeywords {
+		if subs, ok := obj[keyword].(map[string]any); ok {
+			for name, sub := range subs {
+				c.walk(sub, doc, ptr+"/"+escapePointer(keyword)+"/"+escapePointer(name), base)
+			}
+		}
+	}
+}
+
+// loadDoc loads the document with given URI from the file system.
+func (c *compiler) loadDoc(uri string) error {
+	if c.fsys == nil {
+		return fmt.Errorf("cannot load %q, no file system to load from", uri)
+	}
+	if filePath, isFS := strings.CutPrefix(uri, fsScheme+"/"); isFS {
+		return c.loadFile(filePath)
+	}
+	if c.scanned {
+		return nil
+	}
+	c.scanned = true // look for a document declaring this URI as $id
+
+	return fs.WalkDir(c.fsys, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || path.Ext(filePath) != ".json" {
+			return nil
+		}
+		if _, loaded := c.docs[fsURI(filePath)]; !loaded {
+			_ = c.loadFile(filePath) // files which are not valid JSON are skipped
+		}
+
+		return nil
+	})
+}
+
+func (c *compiler) loadFile(filePath string) error {
+	data, err := fs.ReadFile(c.fsys, filePath)
+	if err != nil {
+		return err
+	}
+	raw, err := parseJSON(data)
+	if err != nil {
+		return fmt.Errorf("invalid schema %q: %w", filePath, err)
+	}
+	c.addDoc(fsURI(filePath), raw)
+
+	return nil
+}
+
+// locate returns the location (and its base URI) of given reference, relative to given base URI.
+func (c *compiler) locate(base, ref string) (string, string, error) {
+	resBase, fragment := splitFragment(resolveURI(base, ref))
+	resLoc, found := c.resources[resBase]
+	if !found {
+		if err := c.loadDoc(resBase); err != nil {
+			return "", "", err
+		}
+		if resLoc, found = c.resources[resBase]; !found {
+			return "", "", fmt.Errorf("cannot resolve %q", ref)
+		}
+	}
+
+	switch {
+	case fragment == "":
+		return resLoc, resBase, nil
+	case strings.HasPrefix(fragment, "/"):
+		return resLoc + fragment, resBase, nil
+	default:
+		loc, found := c.anchors[resBase+"#"+fragment]
+		if !found {
+			return "", "", fmt.Errorf("cannot resolve anchor of %q", ref)
+		}
+
+		return loc, resBase, nil
+	}
+}
+
+// rawAt returns the raw JSON at given location.
+func (c *compiler) rawAt(loc string) (any, error) {
+	doc, ptr, _ := strings.Cut(loc, "#")
+	raw, found := c.docs[doc]
+	if !found {
+		return nil, fmt.Errorf("unknown document %q", doc)
+	}
+	if ptr == "" {
+		return raw, nil
+	}
+	for token := range strings.SplitSeq(ptr[1:], "/") {
+		token = unescapePointer(token)
+		switch value := raw.(type) {
+		case map[string]any:
+			if raw, found = value[token]; !found {
+				return nil, fmt.Errorf("invalid reference %q", loc)
+			}
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(value) {
+				return nil, fmt.Errorf("invalid reference %q", loc)
+			}
+			raw = value[idx]
+		default:
+			return nil, fmt.Errorf("invalid reference %q", loc)
+		}
+	}
+
+	return raw, nil
+}
+
+// compile compiles the schema at given location.
+func (c *compiler) compile(loc, base string) (*schemaNode, error) {
+	if node, found := c.nodes[loc]; found {
+		return node, nil
+	}
+	raw, err := c.rawAt(loc)
+	if err != nil {
+		return nil, err
+	}
+	node := new(schemaNode)
+	c.nodes[loc] = node // register before compiling children, for recursive schemas
+
+	switch value := raw.(type) {
+	case bool:
+		node.boolSchema = &value
+
+		return node, nil
+	case map[string]any:
+		if nodeBase, found := c.bases[loc]; found {
+			base = nodeBase
+		} else if id, ok := value["$id"].(string); ok {
+			base, _ = splitFragment(resolveURI(base, id))
+		}
+		kc := keywordCompiler{c: c, obj: value, loc: loc, base: base, node: node}
+		if err := kc.compileKeywords(); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	default:
+		return nil, fmt.Errorf("invalid schema at %q, object or boolean expected", loc)
+	}
+}
+
+// checkCycles returns an error if any compiled schema applies itself, directly or through other
+// in place applicators ("$ref", "$dynamicRef", "allOf", "if", ...), to the same instance location,
+// as validating against it would never end, like {"$ref": "#"}.
+func (c *compiler) checkCycles() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	var (
+		states = make(map[*schemaNode]int, len(c.nodes))
+		locs   = make(map[*schemaNode]string, len(c.nodes))
+		visit  func(node *schemaNode) *schemaNode
+	)
+	for loc, node := range c.nodes {
+		if prevLoc, found := locs[node]; !found || loc < prevLoc {
+			locs[node] = loc
+		}
+	}
+	visit = func(node *schemaNode) *schemaNode {
+		switch states[node] {
+		case visiting:
+			return node
+		case visited:
+			return nil
+		}
+		states[node] = visiting
+		for _, sub := range node.inPlaceSubschemas() {
+			if cycleNode := visit(sub); cycleNode != nil {
+				return cycleNode
+			}
+		}
+		states[node] = visited
+
+		return nil
+	}
+
+	for _, loc := range slices.Sorted(maps.Keys(c.nodes)) {
+		if cycleNode := visit(c.nodes[loc]); cycleNode != nil {
+			return fmt.Errorf("invalid schema at %q, it is applied to itself in an infinite loop", locs[cycleNode])
+		}
+	}
+
+	return nil
+}
+
+// keywordCompiler compiles keywords of a schema object.
+type keywordCompiler struct {
+	c    *compiler
+	obj  map[string]any
+	loc  string
+	base string
+	node *schemaNode
+	err  error
+}
+
+func (kc *keywordCompiler) fail(keyword, expected string) {
+	if kc.err == nil {
+		kc.err = fmt.Errorf("invalid %q keyword at %q, %s expected", keyword, kc.loc, expected)
+	}
+}
+
+func (kc *keywordCompiler) schema(keyword string) *schemaNode {
+	if _, found := kc.obj[keyword]; !found || kc.err != nil {
+		return nil
+	}
+	node, err := kc.c.compile(kc.loc+"/"+escapePointer(keyword), kc.base)
+	if err != nil {
+		kc.err = err
+	}
+
+	return node
+}
+
+func (kc *keywordCompiler) schemaArray(keyword string) []*schemaNode {
+	raw, found := kc.obj[keyword]
+	if !found || kc.err != nil {
+		return nil
+	}
+	subs, ok := raw.([]any)
+	if !ok || len(subs) == 0 {
+		kc.fail(keyword, "non-empty array")
+
+		return nil
+	}
+	nodes := make([]*schemaNode, len(subs))
+	for idx := range subs {
+		node, err := kc.c.compile(kc.loc+"/"+keyword+"/"+strconv.Itoa(idx), kc.base)
+		if err != nil {
+			kc.err = err
+
+			return nil
+		}
+		nodes[idx] = node
+	}
+
+	return nodes
+}
+
+func (kc *keywordCompiler) schemaMap(keyword string) map[string]*schemaNode {
+	raw, found := kc.obj[keyword]
+	if !found || kc.err != nil {
+		return nil
+	}
+	subs, ok := raw.(map[string]any)
+	if !ok {
+		kc.fail(keyword, "object")
+
+		return nil
+	}
+	nodes := make(map[string]*schemaNode, len(subs))
+	for name := range subs {
+		node, err := kc.c.compile(kc.loc+"/"+keyword+"/"+escapePointer(name), kc.base)
+		if err != nil {
+			kc.err = err
+
+			return nil
+		}
+		nodes[name] = node
+	}
+
+	return nodes
+}
+
+func (kc *keywordCompiler) ref(keyword string) *schemaNode {
+	raw, found := kc.obj[keyword]
+	if !found || kc.err != nil {
+		return nil
+	}
+	ref, ok := raw.(string)
+	if !ok {
+		kc.fail(keyword, "string")
+
+		return nil
+	}
+	loc, base, err := kc.c.locate(kc.base, ref)
+	if err != nil {
+		kc.err = fmt.Errorf("invalid %q keyword at %q: %w", keyword, kc.loc, err)
+
+		return nil
+	}
+	node, err := kc.c.compile(loc, base)
+	if err != nil {
+		kc.err = err
+	}
+
+	return node
+}
+
+func (kc *keywordCompiler) number(keyword string) *number {
+	raw, found := kc.obj[keyword]
+	if !found || kc.err != nil {
+		return nil
+	}
+	num, ok := toNumber(raw)
+	if !ok {
+		kc.fail(keyword, "number")
+	}
+
+	return num
+}
+
+func (kc *keywordCompiler) count(keyword string) int {
+	raw, found := kc.obj[keyword]
+	if !found || kc.err != nil {
+		return -1
+	}
+	num, ok := toNumber(raw)
+	if !ok || !num.rat.IsInt() || num.rat.Sign() < 0 || !num.rat.Num().IsInt64() {
+		kc.fail(keyword, "non-negative integer")
+
+		return -1
+	}
+
+	return int(num.rat.Num().Int64())
+}
+
+func (kc *keywordCompiler) strings(keyword string) []string {
+	raw, found := kc.obj[keyword]
+	if !found || kc.err != nil {
+		return nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		kc.fail(keyword, "array of strings")
+
+		return nil
+	}
+	strs := make([]string, len(items))
+	for idx, item := range items {
+		if strs[idx], ok = item.(string); !ok {
+			kc.fail(keyword, "array of strings")
+
+			return nil
+		}
+	}
+
+	return strs
+}
+
+func (kc *keywordCompiler) regexp(keyword, pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil && kc.err == nil {
+		kc.err = fmt.Errorf("invalid %q keyword at %q: %w", keyword, kc.loc, err)
+	}
+
+	return re
+}
+
+func (kc *keywordCompiler) compileKeywords() error {
+	node, obj := kc.node, kc.obj
+	node.ref = kc.ref("$ref")
+	node.dynamicRef = kc.ref("$dynamicRef")
+
+	node.allOf = kc.schemaArray("allOf")
+	node.anyOf = kc.schemaArray("anyOf")
+	node.oneOf = kc.schemaArray("oneOf")
+	node.not = kc.schema("not")
+	node.ifSchema = kc.schema("if")
+	node.thenSchema = kc.schema("then")
+	node.elseSchema = kc.schema("else")
+	node.dependentSchemas = kc.schemaMap("dependentSchemas")
+	node.prefixItems = kc.schemaArray("prefixItems")
+	node.items = kc.schema("items")
+	node.contains = kc.schema("contains")
+	node.properties = kc.schemaMap("properties")
+	for pattern, sub := range kc.schemaMap("patternProperties") {
+		node.patternProperties = append(node.patternProperties, patternSchema{
+			pattern: pattern,
+			re:      kc.regexp("patternProperties", pattern),
+			schema:  sub,
+		})
+	}
+	node.additionalProperties = kc.schema("additionalProperties")
+	node.propertyNames = kc.schema("propertyNames")
+	node.unevaluatedItems = kc.schema("unevaluatedItems")
+	node.unevaluatedProperties = kc.schema("unevaluatedProperties")
+
+	switch typ := obj["type"].(type) {
+	case nil:
+	case string:
+		node.types = []string{typ}
+	default:
+		node.types = kc.strings("type")
+	}
+	if enum, found := obj["enum"]; found {
+		if node.enum, found = enum.([]any); !found {
+			kc.fail("enum", "array")
+		}
+	}
+	node.constValue, node.hasConst = obj["const"]
+
+	node.multipleOf = kc.number("multipleOf")
+	node.maximum = kc.number("maximum")
+	node.exclusiveMaximum = kc.number("exclusiveMaximum")
+	node.minimum = kc.number("minimum")
+	node.exclusiveMinimum = kc.number("exclusiveMinimum")
+
+	node.maxLength = kc.count("maxLength")
+	node.minLength = kc.count("minLength")
+	if pattern, found := obj["pattern"]; found {
+		if patternStr, ok := pattern.(string); ok {
+			node.pattern = kc.regexp("pattern", patternStr)
+		} else {
+			kc.fail("pattern", "string")
+		}
+	}
+
+	node.maxItems = kc.count("maxItems")
+	node.minItems = kc.count("minItems")
+	node.uniqueItems, _ = obj["uniqueItems"].(bool)
+	node.maxContains = kc.count("maxContains")
+	node.minContains = kc.count("minContains")
+
+	node.maxProperties = kc.count("maxProperties")
+	node.minProperties = kc.count("minProperties")
+	node.required = kc.strings("required")
+	if depRequired, found := obj["dependentRequired"].(map[string]any); found {
+		node.dependentRequired = make(map[string][]string, len(depRequired))
+		for name := range depRequired {
+			sub := keywordCompiler{obj: depRequired, loc: kc.loc + "/dependentRequired"}
+			node.dependentRequired[name] = sub.strings(name)
+			if sub.err != nil && kc.err == nil {
+				kc.err = sub.err
+			}
+		}
+	}
+
+	return kc.err
+}
+
+// number is a JSON number.
+type number struct {
+	rat  *big.Rat
+	text string
+}
+
+// toNumber converts a JSON number value (json.Number, float64, ints) to a [number].
+func toNumber(value any) (*number, bool) {
+	var text string
+	switch v := value.(type) {
+	case json.Number:
+		text = v.String()
+	case float64:
+		text = strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		text = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case int:
+		text = strconv.Itoa(v)
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	case uint64:
+		text = strconv.FormatUint(v, 10)
+	default:
+		return nil, false
+	}
+	rat, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, false
+	}
+
+	return &number{rat: rat, text: text}, true
+}
+
+// resolveURI resolves given reference against given base URI.
+func resolveURI(base, ref string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// splitFragment splits an URI into the part without fragment, and the (unescaped) fragment.
+func splitFragment(uri string) (string, string) {
+	withoutFragment, fragment, _ := strings.Cut(uri, "#")
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+
+	return withoutFragment, fragment
+}
+
+// fsURI returns the URI of a file system path.
+func fsURI(filePath string) string {
+	return fsScheme + "/" + path.Clean(filePath)
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+func unescapePointer(token string) string {
+	return pointerUnescaper.Replace(token)
+}
@@ -0,0 +1,182 @@
+// Package jsonschema provides JSON Schema (draft 2020-12) validation, and a [decoder.Decoder]
+// which validates contents before decoding them.
+//
+// All the assertion and applicator keywords are supported, including "unevaluatedProperties" /
+// "unevaluatedItems". "format" is treated as an annotation (it is not asserted), like the specification
+// recommends by default. "$dynamicRef" is resolved like "$ref". Patterns use Go's [regexp] syntax.
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/actforgood/xtransport/decoder"
+)
+
+// Schema is a compiled JSON Schema. It is concurrent safe to use.
+type Schema struct {
+	root *schemaNode
+}
+
+// Compile compiles given JSON Schema.
+// References must be resolvable within the schema itself; for schemas referencing other files, see [Loader].
+// Schemas which would be applied to themselves endlessly, like {"$ref": "#"}, are rejected.
+func Compile(data []byte) (*Schema, error) {
+	raw, err := parseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	comp := newCompiler(nil)
+	comp.addDoc(defaultBaseURI, raw)
+	root, err := comp.compile(defaultBaseURI+"#", defaultBaseURI)
+	if err != nil {
+		return nil, err
+	}
+	if err := comp.checkCycles(); err != nil {
+		return nil, err
+	}
+
+	return &Schema{root: root}, nil
+}
+
+// Validate validates given instance, which is expected to be a JSON decoded value
+// (preferably with [encoding/json.Decoder.UseNumber], for precise numbers).
+// A [*ValidationError] holding all the violations is returned if instance is not valid.
+func (schema *Schema) Validate(instance any) error {
+	var violations []Violation
+	if valid, _ := schema.root.validate(instance, "", "", &violations); valid {
+		return nil
+	}
+
+	return &ValidationError{Violations: violations}
+}
+
+// Violation is a JSON Schema constraint violated by an instance.
+type Violation struct {
+	// InstanceLocation is the JSON pointer of the invalid value, like "/user/age" ("" for the root value).
+	InstanceLocation string
+	// KeywordLocation is the JSON pointer of the violated keyword, relative to schema's root,
+	// following references, like "/properties/user/$ref/properties/age/minimum".
+	KeywordLocation string
+	// Message describes the violation.
+	Message string
+}
+
+// String returns the violation as text, like "/user/age: value must be >= 0".
+func (violation Violation) String() string {
+	instLoc := violation.InstanceLocation
+	if instLoc == "" {
+		instLoc = "(root)"
+	}
+
+	return instLoc + ": " + violation.Message
+}
+
+// ValidationError is returned when an instance does not conform to a schema.
+// It can be mapped to a 422 Unprocessable Entity HTTP response.
+type ValidationError struct {
+	// Violations holds all the violations.
+	Violations []Violation
+}
+
+// Error returns the error message.
+func (err *ValidationError) Error() string {
+	violations := make([]string, len(err.Violations))
+	for idx, violation := range err.Violations {
+		violations[idx] = violation.String()
+	}
+
+	return "json schema validation failed: " + strings.Join(violations, "; ")
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 422.
+func (err *ValidationError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Loader loads and compiles JSON Schemas from a file system, like an [embed.FS] or an [os.DirFS].
+// Relative references ("$ref": "common.json#/$defs/id") are resolved against the referencing file's path,
+// absolute ones ("$ref": "https://example.com/common.json") against the $id declared by files
+// in the file system. Compiled schemas are cached. It is concurrent safe to use.
+type Loader struct {
+	fsys    fs.FS
+	comp    *compiler
+	schemas map[string]*Schema
+	mu      sync.Mutex
+}
+
+// NewLoader instantiates a new [Loader] upon given file system.
+func NewLoader(fsys fs.FS) *Loader {
+	return &Loader{
+		fsys:    fsys,
+		comp:    newCompiler(fsys),
+		schemas: make(map[string]*Schema),
+	}
+}
+
+// Load returns the compiled schema from given path (like "schemas/user.json").
+func (loader *Loader) Load(path string) (*Schema, error) {
+	uri := fsURI(path)
+
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	if schema, found := loader.schemas[uri]; found {
+		return schema, nil
+	}
+	if _, loaded := loader.comp.docs[uri]; !loaded {
+		if err := loader.comp.loadDoc(uri); err != nil {
+			return nil, err
+		}
+	}
+	root, err := loader.comp.compile(uri+"#", uri)
+	if err == nil {
+		err = loader.comp.checkCycles()
+	}
+	if err != nil {
+		loader.comp = newCompiler(loader.fsys) // discard partially compiled schemas
+
+		return nil, err
+	}
+	schema := &Schema{root: root}
+	loader.schemas[uri] = schema
+
+	return schema, nil
+}
+
+// NewDecoder returns a [decoder.Decoder] which validates JSON contents against given schema,
+// and, if valid, decodes them with next decoder ([decoder.DecodeJSON] if nil).
+// It can be used for both HTTP requests' bodies and broker messages' bodies:
+//
+//	validatingDecoder := jsonschema.NewDecoder(schema, nil)
+//	err := validatingDecoder(xhttp.GetRequestBody(w, r), &dest)
+//	// or
+//	err := validatingDecoder(bytes.NewReader(msg.Body), &dest)
+//
+// Contents' errors are returned as [decoder] typed errors, and violations as a [*ValidationError].
+func NewDecoder(schema *Schema, next decoder.Decoder) decoder.Decoder {
+	if next == nil {
+		next = decoder.DecodeJSON
+	}
+	decodeInstance := decoder.NewJSONDecoder(decoder.JSONConfig{UseNumber: true})
+
+	return func(r io.Reader, dest any) error {
+		var (
+			buf      bytes.Buffer
+			instance any
+		)
+		if err := decodeInstance(io.TeeReader(r, &buf), &instance); err != nil {
+			return err
+		}
+		if err := schema.Validate(instance); err != nil {
+			return err
+		}
+
+		return next(&buf, dest)
+	}
+}
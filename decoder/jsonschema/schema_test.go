@@ -0,0 +1,249 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/actforgood/xtransport/broker"
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/decoder/jsonschema"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+//go:embed testdata
+var testSchemasFS embed.FS
+
+type testUser struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Age     int    `json:"age"`
+	Address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	} `json:"address"`
+}
+
+func TestLoader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("loads schema with relative and absolute references", testLoaderReferences)
+	t.Run("caches compiled schemas", testLoaderCache)
+	t.Run("returns error for missing file", testLoaderMissingFile)
+	t.Run("returns error for unresolvable reference", testLoaderUnresolvableRef)
+	t.Run("returns error for cyclic schema", testLoaderCyclicSchema)
+}
+
+func testLoaderReferences(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := jsonschema.NewLoader(testSchemasFS)
+	schema, err := subject.Load("testdata/schemas/user.json")
+	assert.RequireNil(t, err)
+
+	// act
+	errValid := schema.Validate(parseInstance(t,
+		`{"name":"John Doe","email":"john@example.com","address":{"city":"Paris","zip":"75001"}}`,
+	))
+	errInvalid := schema.Validate(parseInstance(t,
+		`{"name":"","email":"john","age":-1,"address":{"zip":"7500"},"extra":true}`,
+	))
+
+	// assert
+	assert.Nil(t, errValid)
+	var validationErr *jsonschema.ValidationError
+	if assert.True(t, errors.As(errInvalid, &validationErr)) {
+		assert.Equal(t, []jsonschema.Violation{
+			{"/address", "/properties/address/$ref/required", `missing required property "city"`},
+			{"/address/zip", "/properties/address/$ref/properties/zip/$ref/pattern", `value does not match pattern "^[0-9]{5}$"`},
+			{"/age", "/properties/age/minimum", "value must be >= 0"},
+			{"/email", "/properties/email/$ref/pattern", `value does not match pattern "^[^@]+@[^@]+$"`},
+			{"/extra", "/additionalProperties", `property "extra" is not allowed`},
+			{"/name", "/properties/name/minLength", "length must be >= 1, got 0"},
+		}, validationErr.Violations)
+		assert.Equal(t, http.StatusUnprocessableEntity, validationErr.StatusCode())
+		assert.True(t, strings.HasPrefix(
+			validationErr.Error(),
+			`json schema validation failed: /address: missing required property "city"; /address/zip: `,
+		))
+	}
+}
+
+func testLoaderCache(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	fsys := fstest.MapFS{
+		"a.json": &fstest.MapFile{Data: []byte(`{"$ref":"b.json"}`)},
+		"b.json": &fstest.MapFile{Data: []byte(`{"type":"string"}`)},
+	}
+	subject := jsonschema.NewLoader(fsys)
+
+	// act
+	schema1, err1 := subject.Load("a.json")
+	delete(fsys, "b.json")
+	schema2, err2 := subject.Load("./a.json")
+
+	// assert
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.True(t, schema1 == schema2)
+	assert.NotNil(t, schema2.Validate(1))
+}
+
+func testLoaderMissingFile(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := jsonschema.NewLoader(testSchemasFS)
+
+	// act
+	schema, err := subject.Load("testdata/schemas/missing.json")
+
+	// assert
+	assert.Nil(t, schema)
+	assert.NotNil(t, err)
+}
+
+func testLoaderUnresolvableRef(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := jsonschema.NewLoader(testSchemasFS)
+
+	// act
+	schema, err := subject.Load("testdata/schemas/invalid.json")
+
+	// assert
+	assert.Nil(t, schema)
+	assert.NotNil(t, err)
+}
+
+func TestNewDecoder(t *testing.T) {
+	t.Parallel()
+
+	schema, err := jsonschema.NewLoader(testSchemasFS).Load("testdata/schemas/user.json")
+	assert.RequireNil(t, err)
+	subject := jsonschema.NewDecoder(schema, nil)
+
+	t.Run("decodes valid request body", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		var (
+			body = io.NopCloser(strings.NewReader(`{"name":"John Doe","email":"john@example.com","age":42}`))
+			dest testUser
+		)
+
+		// act
+		err := subject(http.MaxBytesReader(nil, body, 1024), &dest)
+
+		// assert
+		if assert.Nil(t, err) {
+			assert.Equal(t, "John Doe", dest.Name)
+			assert.Equal(t, 42, dest.Age)
+		}
+	})
+
+	t.Run("decodes valid message body", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		var (
+			msg  = broker.Message{Body: []byte(`{"name":"John Doe","email":"john@example.com","address":{"city":"Paris"}}`)}
+			dest testUser
+		)
+
+		// act
+		err := subject(bytes.NewReader(msg.Body), &dest)
+
+		// assert
+		if assert.Nil(t, err) {
+			assert.Equal(t, "Paris", dest.Address.City)
+		}
+	})
+
+	t.Run("returns validation error", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		var dest testUser
+
+		// act
+		err := subject(strings.NewReader(`{"name":"John Doe"}`), &dest)
+
+		// assert
+		var validationErr *jsonschema.ValidationError
+		if assert.True(t, errors.As(err, &validationErr)) {
+			assert.Equal(t, 1, len(validationErr.Violations))
+		}
+		assert.Equal(t, "", dest.Name)
+	})
+
+	t.Run("returns decoder errors", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		var (
+			dest        testUser
+			syntaxErr   *decoder.SyntaxError
+			tooLargeErr *decoder.TooLargeError
+		)
+		body := io.NopCloser(strings.NewReader(`{"name":"John Doe","email":"john@example.com"}`))
+
+		// act
+		err1 := subject(strings.NewReader(`{"name":`), &dest)
+		err2 := subject(http.MaxBytesReader(nil, body, 10), &dest)
+
+		// assert
+		assert.True(t, errors.As(err1, &syntaxErr))
+		assert.True(t, errors.As(err2, &tooLargeErr))
+	})
+
+	t.Run("decodes with next decoder", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		var (
+			strict = jsonschema.NewDecoder(
+				schema,
+				decoder.NewJSONDecoder(decoder.JSONConfig{DisallowUnknownFields: true}),
+			)
+			dest struct {
+				Name string `json:"name"`
+			}
+			unknownFieldErr *decoder.UnknownFieldError
+		)
+
+		// act
+		err := strict(strings.NewReader(`{"name":"John Doe","email":"john@example.com"}`), &dest)
+
+		// assert
+		assert.True(t, errors.As(err, &unknownFieldErr))
+	})
+}
+
+func testLoaderCyclicSchema(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := jsonschema.NewLoader(testSchemasFS)
+
+	// act
+	schema, err := subject.Load("testdata/schemas/cyclic.json")
+	validSchema, errValid := subject.Load("testdata/schemas/user.json")
+
+	// assert
+	assert.Nil(t, schema)
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "it is applied to itself in an infinite loop"))
+	}
+	assert.Nil(t, errValid)
+	assert.NotNil(t, validSchema)
+}
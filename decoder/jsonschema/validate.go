@@ -0,0 +1,544 @@
+package jsonschema
+
+import (
+	"fmt"
+	"maps"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaNode is a compiled schema.
+type schemaNode struct {
+	boolSchema *bool
+
+	ref        *schemaNode
+	dynamicRef *schemaNode
+
+	allOf                 []*schemaNode
+	anyOf                 []*schemaNode
+	oneOf                 []*schemaNode
+	not                   *schemaNode
+	ifSchema              *schemaNode
+	thenSchema            *schemaNode
+	elseSchema            *schemaNode
+	dependentSchemas      map[string]*schemaNode
+	prefixItems           []*schemaNode
+	items                 *schemaNode
+	contains              *schemaNode
+	properties            map[string]*schemaNode
+	patternProperties     []patternSchema
+	additionalProperties  *schemaNode
+	propertyNames         *schemaNode
+	unevaluatedItems      *schemaNode
+	unevaluatedProperties *schemaNode
+
+	types      []string
+	enum       []any
+	constValue any
+	hasConst   bool
+
+	multipleOf       *number
+	maximum          *number
+	exclusiveMaximum *number
+	minimum          *number
+	exclusiveMinimum *number
+
+	maxLength int // -1 if not set, like the other counts
+	minLength int
+	pattern   *regexp.Regexp
+
+	maxItems    int
+	minItems    int
+	uniqueItems bool
+	maxContains int
+	minContains int
+
+	maxProperties     int
+	minProperties     int
+	required          []string
+	dependentRequired map[string][]string
+}
+
+// inPlaceSubschemas returns the subschemas applied to the same instance location as the schema.
+func (node *schemaNode) inPlaceSubschemas() []*schemaNode {
+	subs := make([]*schemaNode, 0, 6+len(node.allOf)+len(node.anyOf)+len(node.oneOf)+len(node.dependentSchemas))
+	for _, sub := range [...]*schemaNode{
+		node.ref, node.dynamicRef, node.not, node.ifSchema, node.thenSchema, node.elseSchema,
+	} {
+		if sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	subs = append(subs, node.allOf...)
+	subs = append(subs, node.anyOf...)
+	subs = append(subs, node.oneOf...)
+	for _, name := range slices.Sorted(maps.Keys(node.dependentSchemas)) {
+		subs = append(subs, node.dependentSchemas[name])
+	}
+
+	return subs
+}
+
+// patternSchema is a "patternProperties" entry.
+type patternSchema struct {
+	pattern string
+	re      *regexp.Regexp
+	schema  *schemaNode
+}
+
+// annotations holds the properties / items evaluated by a schema, needed by "unevaluated*" keywords.
+type annotations struct {
+	props    map[string]struct{}
+	items    map[int]struct{}
+	allItems bool
+}
+
+func (ann *annotations) addProp(name string) {
+	if ann.props == nil {
+		ann.props = make(map[string]struct{})
+	}
+	ann.props[name] = struct{}{}
+}
+
+func (ann *annotations) addItem(idx int) {
+	if ann.items == nil {
+		ann.items = make(map[int]struct{})
+	}
+	ann.items[idx] = struct{}{}
+}
+
+func (ann *annotations) merge(other *annotations) {
+	if other == nil {
+		return
+	}
+	for name := range other.props {
+		ann.addProp(name)
+	}
+	for idx := range other.items {
+		ann.addItem(idx)
+	}
+	ann.allItems = ann.allItems || other.allItems
+}
+
+// validation holds the state of validating an instance against a schema node.
+type validation struct {
+	node       *schemaNode
+	instance   any
+	instLoc    string
+	kwLoc      string
+	violations *[]Violation
+	ann        annotations
+	valid      bool
+}
+
+func (v *validation) fail(keyword, format string, args ...any) {
+	v.failAt(v.instLoc, keyword, format, args...)
+}
+
+func (v *validation) failAt(instLoc, keyword, format string, args ...any) {
+	v.valid = false
+	*v.violations = append(*v.violations, Violation{
+		InstanceLocation: instLoc,
+		KeywordLocation:  v.kwLoc + "/" + keyword,
+		Message:          fmt.Sprintf(format, args...),
+	})
+}
+
+// inPlace applies given subschema to the same instance, collecting violations and annotations.
+func (v *validation) inPlace(node *schemaNode, keyword string) bool {
+	ok, ann := node.validate(v.instance, v.instLoc, v.kwLoc+"/"+keyword, v.violations)
+	if ok {
+		v.ann.merge(ann)
+	} else {
+		v.valid = false
+	}
+
+	return ok
+}
+
+// probe applies given subschema to the same instance, without collecting violations.
+func (v *validation) probe(node *schemaNode, keyword string) (bool, *annotations) {
+	var discarded []Violation
+
+	return node.validate(v.instance, v.instLoc, v.kwLoc+"/"+keyword, &discarded)
+}
+
+// child applies given subschema to a child instance.
+func (v *validation) child(node *schemaNode, instance any, instToken, keyword string) bool {
+	ok, _ := node.validate(instance, v.instLoc+"/"+escapePointer(instToken), v.kwLoc+"/"+keyword, v.violations)
+	if !ok {
+		v.valid = false
+	}
+
+	return ok
+}
+
+// validate validates given instance, appending violations, and returning
+// whether instance is valid and the evaluated properties / items.
+func (node *schemaNode) validate(instance any, instLoc, kwLoc string, violations *[]Violation) (bool, *annotations) {
+	v := &validation{
+		node:       node,
+		instance:   instance,
+		instLoc:    instLoc,
+		kwLoc:      kwLoc,
+		violations: violations,
+		valid:      true,
+	}
+	if node.boolSchema != nil {
+		if !*node.boolSchema {
+			v.valid = false
+			*violations = append(*violations, Violation{
+				InstanceLocation: instLoc,
+				KeywordLocation:  kwLoc,
+				Message:          "no value is allowed",
+			})
+		}
+
+		return v.valid, nil
+	}
+
+	v.validateApplicators()
+	v.validateGeneric()
+	switch value := instance.(type) {
+	case string:
+		v.validateString(value)
+	case []any:
+		v.validateArray(value)
+	case map[string]any:
+		v.validateObject(value)
+	default:
+		if num, ok := toNumber(instance); ok {
+			v.validateNumber(num)
+		}
+	}
+
+	return v.valid, &v.ann
+}
+
+func (v *validation) validateApplicators() {
+	node := v.node
+	if node.ref != nil {
+		v.inPlace(node.ref, "$ref")
+	}
+	if node.dynamicRef != nil {
+		v.inPlace(node.dynamicRef, "$dynamicRef")
+	}
+	for idx, sub := range node.allOf {
+		v.inPlace(sub, "allOf/"+strconv.Itoa(idx))
+	}
+
+	if len(node.anyOf) > 0 {
+		matched := 0
+		for idx, sub := range node.anyOf {
+			if ok, ann := v.probe(sub, "anyOf/"+strconv.Itoa(idx)); ok {
+				matched++
+				v.ann.merge(ann)
+			}
+		}
+		if matched == 0 {
+			v.fail("anyOf", "value does not match any of the schemas")
+		}
+	}
+
+	if len(node.oneOf) > 0 {
+		var (
+			matched    []int
+			matchedAnn *annotations
+		)
+		for idx, sub := range node.oneOf {
+			if ok, ann := v.probe(sub, "oneOf/"+strconv.Itoa(idx)); ok {
+				matched = append(matched, idx)
+				matchedAnn = ann
+			}
+		}
+		switch len(matched) {
+		case 0:
+			v.fail("oneOf", "value does not match any of the schemas")
+		case 1:
+			v.ann.merge(matchedAnn)
+		default:
+			v.fail("oneOf", "value matches more than one schema (%v)", matched)
+		}
+	}
+
+	if node.not != nil {
+		if ok, _ := v.probe(node.not, "not"); ok {
+			v.fail("not", "value must not match the schema")
+		}
+	}
+
+	if node.ifSchema != nil {
+		ok, ann := v.probe(node.ifSchema, "if")
+		switch {
+		case ok:
+			v.ann.merge(ann)
+			if node.thenSchema != nil {
+				v.inPlace(node.thenSchema, "then")
+			}
+		case node.elseSchema != nil:
+			v.inPlace(node.elseSchema, "else")
+		}
+	}
+}
+
+func (v *validation) validateGeneric() {
+	node := v.node
+	if len(node.types) > 0 && !slices.ContainsFunc(node.types, func(typ string) bool {
+		return isType(v.instance, typ)
+	}) {
+		if len(node.types) == 1 {
+			v.fail("type", "expected %s, got %s", node.types[0], typeOf(v.instance))
+		} else {
+			v.fail("type", "expected one of %s, got %s", strings.Join(node.types, ", "), typeOf(v.instance))
+		}
+	}
+	if node.enum != nil && !slices.ContainsFunc(node.enum, func(value any) bool {
+		return equal(v.instance, value)
+	}) {
+		v.fail("enum", "value must be one of the enumerated values")
+	}
+	if node.hasConst && !equal(v.instance, node.constValue) {
+		v.fail("const", "value must be equal to the constant")
+	}
+}
+
+func (v *validation) validateNumber(num *number) {
+	node := v.node
+	if node.multipleOf != nil && node.multipleOf.rat.Sign() != 0 {
+		if !new(big.Rat).Quo(num.rat, node.multipleOf.rat).IsInt() {
+			v.fail("multipleOf", "value must be a multiple of %s", node.multipleOf.text)
+		}
+	}
+	if node.maximum != nil && num.rat.Cmp(node.maximum.rat) > 0 {
+		v.fail("maximum", "value must be <= %s", node.maximum.text)
+	}
+	if node.exclusiveMaximum != nil && num.rat.Cmp(node.exclusiveMaximum.rat) >= 0 {
+		v.fail("exclusiveMaximum", "value must be < %s", node.exclusiveMaximum.text)
+	}
+	if node.minimum != nil && num.rat.Cmp(node.minimum.rat) < 0 {
+		v.fail("minimum", "value must be >= %s", node.minimum.text)
+	}
+	if node.exclusiveMinimum != nil && num.rat.Cmp(node.exclusiveMinimum.rat) <= 0 {
+		v.fail("exclusiveMinimum", "value must be > %s", node.exclusiveMinimum.text)
+	}
+}
+
+func (v *validation) validateString(value string) {
+	node := v.node
+	if node.maxLength >= 0 || node.minLength >= 0 {
+		length := utf8.RuneCountInString(value)
+		if node.maxLength >= 0 && length > node.maxLength {
+			v.fail("maxLength", "length must be <= %d, got %d", node.maxLength, length)
+		}
+		if node.minLength >= 0 && length < node.minLength {
+			v.fail("minLength", "length must be >= %d, got %d", node.minLength, length)
+		}
+	}
+	if node.pattern != nil && !node.pattern.MatchString(value) {
+		v.fail("pattern", "value does not match pattern %q", node.pattern.String())
+	}
+}
+
+func (v *validation) validateArray(items []any) {
+	node := v.node
+	if node.maxItems >= 0 && len(items) > node.maxItems {
+		v.fail("maxItems", "array must have at most %d items, got %d", node.maxItems, len(items))
+	}
+	if node.minItems >= 0 && len(items) < node.minItems {
+		v.fail("minItems", "array must have at least %d items, got %d", node.minItems, len(items))
+	}
+	if node.uniqueItems {
+	uniqueLoop:
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if equal(items[i], items[j]) {
+					v.fail("uniqueItems", "array items at %d and %d are equal", i, j)
+
+					break uniqueLoop
+				}
+			}
+		}
+	}
+
+	for idx, sub := range node.prefixItems {
+		if idx >= len(items) {
+			break
+		}
+		v.child(sub, items[idx], strconv.Itoa(idx), "prefixItems/"+strconv.Itoa(idx))
+		v.ann.addItem(idx)
+	}
+	if node.items != nil {
+		for idx := len(node.prefixItems); idx < len(items); idx++ {
+			v.child(node.items, items[idx], strconv.Itoa(idx), "items")
+		}
+		v.ann.allItems = true
+	}
+
+	if node.contains != nil {
+		matched := 0
+		for idx, item := range items {
+			var discarded []Violation
+			ok, _ := node.contains.validate(item, v.instLoc+"/"+strconv.Itoa(idx), v.kwLoc+"/contains", &discarded)
+			if ok {
+				matched++
+				v.ann.addItem(idx)
+			}
+		}
+		minContains := 1
+		if node.minContains >= 0 {
+			minContains = node.minContains
+		}
+		if matched < minContains {
+			v.fail("contains", "array must contain at least %d matching items, got %d", minContains, matched)
+		}
+		if node.maxContains >= 0 && matched > node.maxContains {
+			v.fail("maxContains", "array must contain at most %d matching items, got %d", node.maxContains, matched)
+		}
+	}
+
+	if node.unevaluatedItems != nil && !v.ann.allItems {
+		for idx, item := range items {
+			if _, evaluated := v.ann.items[idx]; !evaluated {
+				v.child(node.unevaluatedItems, item, strconv.Itoa(idx), "unevaluatedItems")
+			}
+		}
+		v.ann.allItems = true
+	}
+}
+
+func (v *validation) validateObject(obj map[string]any) {
+	node := v.node
+	if node.maxProperties >= 0 && len(obj) > node.maxProperties {
+		v.fail("maxProperties", "object must have at most %d properties, got %d", node.maxProperties, len(obj))
+	}
+	if node.minProperties >= 0 && len(obj) < node.minProperties {
+		v.fail("minProperties", "object must have at least %d properties, got %d", node.minProperties, len(obj))
+	}
+	for _, name := range node.required {
+		if _, found := obj[name]; !found {
+			v.fail("required", "missing required property %q", name)
+		}
+	}
+	names := slices.Sorted(maps.Keys(obj))
+	for _, name := range names {
+		for _, dependency := range node.dependentRequired[name] {
+			if _, found := obj[dependency]; !found {
+				v.fail("dependentRequired", "property %q requires property %q", name, dependency)
+			}
+		}
+		if sub, found := node.dependentSchemas[name]; found {
+			v.inPlace(sub, "dependentSchemas/"+escapePointer(name))
+		}
+	}
+
+	for _, name := range names {
+		value := obj[name]
+		if node.propertyNames != nil {
+			var discarded []Violation
+			if ok, _ := node.propertyNames.validate(name, v.instLoc, v.kwLoc+"/propertyNames", &discarded); !ok {
+				v.fail("propertyNames", "property name %q is invalid", name)
+			}
+		}
+		matched := false
+		if sub, found := node.properties[name]; found {
+			matched = true
+			v.child(sub, value, name, "properties/"+escapePointer(name))
+			v.ann.addProp(name)
+		}
+		for _, pattern := range node.patternProperties {
+			if pattern.re.MatchString(name) {
+				matched = true
+				v.child(pattern.schema, value, name, "patternProperties/"+escapePointer(pattern.pattern))
+				v.ann.addProp(name)
+			}
+		}
+		if !matched && node.additionalProperties != nil {
+			v.applyToUnmatchedProp(node.additionalProperties, "additionalProperties", name, value)
+		}
+	}
+
+	if node.unevaluatedProperties != nil {
+		for _, name := range names {
+			if _, evaluated := v.ann.props[name]; !evaluated {
+				v.applyToUnmatchedProp(node.unevaluatedProperties, "unevaluatedProperties", name, obj[name])
+			}
+		}
+	}
+}
+
+// applyToUnmatchedProp applies "additionalProperties" / "unevaluatedProperties" subschema to a property.
+func (v *validation) applyToUnmatchedProp(sub *schemaNode, keyword, name string, value any) {
+	if sub.boolSchema != nil && !*sub.boolSchema {
+		v.failAt(v.instLoc+"/"+escapePointer(name), keyword, "property %q is not allowed", name)
+	} else {
+		v.child(sub, value, name, keyword)
+	}
+	v.ann.addProp(name)
+}
+
+// typeOf returns the JSON type of given value.
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		if num, ok := toNumber(value); ok {
+			if num.rat.IsInt() {
+				return "integer"
+			}
+
+			return "number"
+		}
+
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// isType checks if given value is of given JSON type.
+func isType(value any, typ string) bool {
+	actual := typeOf(value)
+
+	return actual == typ || (typ == "number" && actual == "integer")
+}
+
+// equal checks if 2 JSON values are equal.
+func equal(a, b any) bool {
+	switch aValue := a.(type) {
+	case nil, bool, string:
+		return a == b
+	case []any:
+		bValue, ok := b.([]any)
+
+		return ok && slices.EqualFunc(aValue, bValue, equal)
+	case map[string]any:
+		bValue, ok := b.(map[string]any)
+		if !ok || len(aValue) != len(bValue) {
+			return false
+		}
+		for key, aItem := range aValue {
+			bItem, found := bValue[key]
+			if !found || !equal(aItem, bItem) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		aNum, aOk := toNumber(a)
+		bNum, bOk := toNumber(b)
+
+		return aOk && bOk && aNum.rat.Cmp(bNum.rat) == 0
+	}
+}
@@ -0,0 +1,291 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder/jsonschema"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+// parseInstance decodes given JSON, keeping numbers' precision.
+func parseInstance(t *testing.T, data string) any {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+	dec.UseNumber()
+	var instance any
+	assert.RequireNil(t, dec.Decode(&instance))
+
+	return instance
+}
+
+func TestSchema_Validate(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name               string
+		schema             string
+		instance           string
+		expectedViolations []jsonschema.Violation
+	}{
+		{
+			name:     "true schema",
+			schema:   `true`,
+			instance: `{"a":1}`,
+		},
+		{
+			name:               "false schema",
+			schema:             `false`,
+			instance:           `1`,
+			expectedViolations: []jsonschema.Violation{{"", "", "no value is allowed"}},
+		},
+		{
+			name:     "valid type, enum, const",
+			schema:   `{"type":["integer","null"],"enum":[1,2.0,null],"const":2}`,
+			instance: `2`,
+		},
+		{
+			name:     "invalid type, enum, const",
+			schema:   `{"properties":{"a":{"type":"integer"},"b":{"enum":[1,[2]]},"c":{"const":{"x":1}}}}`,
+			instance: `{"a":1.5,"b":[3],"c":{"x":2}}`,
+			expectedViolations: []jsonschema.Violation{
+				{"/a", "/properties/a/type", "expected integer, got number"},
+				{"/b", "/properties/b/enum", "value must be one of the enumerated values"},
+				{"/c", "/properties/c/const", "value must be equal to the constant"},
+			},
+		},
+		{
+			name: "numbers",
+			schema: `{"prefixItems":[{"multipleOf":0.1},{"maximum":10},{"exclusiveMaximum":10},` +
+				`{"minimum":1},{"exclusiveMinimum":1},{"multipleOf":0.1}]}`,
+			instance: `[0.35, 10.5, 10, 0.99, 1, 0.3]`,
+			expectedViolations: []jsonschema.Violation{
+				{"/0", "/prefixItems/0/multipleOf", "value must be a multiple of 0.1"},
+				{"/1", "/prefixItems/1/maximum", "value must be <= 10"},
+				{"/2", "/prefixItems/2/exclusiveMaximum", "value must be < 10"},
+				{"/3", "/prefixItems/3/minimum", "value must be >= 1"},
+				{"/4", "/prefixItems/4/exclusiveMinimum", "value must be > 1"},
+			},
+		},
+		{
+			name:     "strings",
+			schema:   `{"items":{"type":"string","minLength":2,"maxLength":3,"pattern":"^[a-zé]+$"}}`,
+			instance: `["éé","a","abcd","AB"]`,
+			expectedViolations: []jsonschema.Violation{
+				{"/1", "/items/minLength", "length must be >= 2, got 1"},
+				{"/2", "/items/maxLength", "length must be <= 3, got 4"},
+				{"/3", "/items/pattern", `value does not match pattern "^[a-zé]+$"`},
+			},
+		},
+		{
+			name:     "arrays",
+			schema:   `{"minItems":4,"maxItems":1,"uniqueItems":true,"contains":{"type":"string"},"maxContains":0}`,
+			instance: `[1,"a",1.0]`,
+			expectedViolations: []jsonschema.Violation{
+				{"", "/maxItems", "array must have at most 1 items, got 3"},
+				{"", "/minItems", "array must have at least 4 items, got 3"},
+				{"", "/uniqueItems", "array items at 0 and 2 are equal"},
+				{"", "/maxContains", "array must contain at most 0 matching items, got 1"},
+			},
+		},
+		{
+			name:     "contains",
+			schema:   `{"contains":{"const":1},"minContains":2}`,
+			instance: `[1,2]`,
+			expectedViolations: []jsonschema.Violation{
+				{"", "/contains", "array must contain at least 2 matching items, got 1"},
+			},
+		},
+		{
+			name: "objects",
+			schema: `{"minProperties":4,"maxProperties":1,"required":["a","z"],"dependentRequired":{"a":["y"]},` +
+				`"propertyNames":{"maxLength":1},"patternProperties":{"^b":{"type":"integer"}},` +
+				`"additionalProperties":false}`,
+			instance: `{"a":1,"bb":"x"}`,
+			expectedViolations: []jsonschema.Violation{
+				{"", "/maxProperties", "object must have at most 1 properties, got 2"},
+				{"", "/minProperties", "object must have at least 4 properties, got 2"},
+				{"", "/required", `missing required property "z"`},
+				{"", "/dependentRequired", `property "a" requires property "y"`},
+				{"/a", "/additionalProperties", `property "a" is not allowed`},
+				{"", "/propertyNames", `property name "bb" is invalid`},
+				{"/bb", "/patternProperties/^b/type", "expected integer, got string"},
+			},
+		},
+		{
+			name: "applicators",
+			schema: `{"allOf":[{"type":"object"}],"anyOf":[{"required":["x"]},{"required":["y"]}],` +
+				`"oneOf":[{"required":["a"]},{"required":["b"]}],"not":{"required":["c"]},` +
+				`"if":{"required":["d"]},"then":{"required":["e"]},"else":{"required":["f"]}}`,
+			instance: `{"a":1,"b":2,"c":3,"d":4}`,
+			expectedViolations: []jsonschema.Violation{
+				{"", "/anyOf", "value does not match any of the schemas"},
+				{"", "/oneOf", "value matches more than one schema ([0 1])"},
+				{"", "/not", "value must not match the schema"},
+				{"", "/then/required", `missing required property "e"`},
+			},
+		},
+		{
+			name:     "else and dependentSchemas",
+			schema:   `{"if":{"required":["d"]},"else":{"required":["f"]},"dependentSchemas":{"a":{"maxProperties":0}}}`,
+			instance: `{"a":1}`,
+			expectedViolations: []jsonschema.Violation{
+				{"", "/else/required", `missing required property "f"`},
+				{"", "/dependentSchemas/a/maxProperties", "object must have at most 0 properties, got 1"},
+			},
+		},
+		{
+			name: "unevaluated properties",
+			schema: `{"properties":{"a":true},"allOf":[{"properties":{"b":true}}],` +
+				`"anyOf":[{"properties":{"c":true}},{"properties":{"d":false}}],` +
+				`"unevaluatedProperties":false}`,
+			instance: `{"a":1,"b":2,"c":3,"e":4}`,
+			expectedViolations: []jsonschema.Violation{
+				{"/e", "/unevaluatedProperties", `property "e" is not allowed`},
+			},
+		},
+		{
+			name:     "unevaluated items",
+			schema:   `{"prefixItems":[true],"contains":{"type":"string"},"unevaluatedItems":{"type":"integer"}}`,
+			instance: `[null,"a",1,true]`,
+			expectedViolations: []jsonschema.Violation{
+				{"/3", "/unevaluatedItems/type", "expected integer, got boolean"},
+			},
+		},
+		{
+			name: "local references and anchors",
+			schema: `{"$defs":{"node":{"$anchor":"node","type":"object",` +
+				`"properties":{"value":{"type":"integer"},"next":{"$ref":"#node"}}}},"$ref":"#/$defs/node"}`,
+			instance: `{"value":1,"next":{"value":2,"next":{"value":"3"}}}`,
+			expectedViolations: []jsonschema.Violation{
+				{
+					"/next/next/value",
+					"/$ref/properties/next/$ref/properties/next/$ref/properties/value/type",
+					"expected integer, got string",
+				},
+			},
+		},
+		{
+			name:     "recursive schema",
+			schema:   `{"required":["name"],"properties":{"children":{"items":{"$ref":"#"}}}}`,
+			instance: `{"name":"root","children":[{"name":"child","children":[{}]}]}`,
+			expectedViolations: []jsonschema.Violation{
+				{"/children/0/children/0", "/properties/children/items/$ref/properties/children/items/$ref/required",
+					`missing required property "name"`},
+			},
+		},
+		{
+			name: "embedded resource references",
+			schema: `{"$id":"https://example.com/root.json","properties":{"a":{"$ref":"item.json"}},` +
+				`"$defs":{"item":{"$id":"item.json","type":"string"}}}`,
+			instance: `{"a":1}`,
+			expectedViolations: []jsonschema.Violation{
+				{"/a", "/properties/a/$ref/type", "expected string, got integer"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject, err := jsonschema.Compile([]byte(test.schema))
+			assert.RequireNil(t, err)
+
+			// act
+			err = subject.Validate(parseInstance(t, test.instance))
+
+			// assert
+			if test.expectedViolations == nil {
+				assert.Nil(t, err)
+
+				return
+			}
+			var validationErr *jsonschema.ValidationError
+			if assert.True(t, errors.As(err, &validationErr)) {
+				assert.Equal(t, test.expectedViolations, validationErr.Violations)
+			}
+		})
+	}
+}
+
+func TestCompile_invalidSchema(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name          string
+		schema        string
+		expectedError string
+	}{
+		{
+			name:          "malformed json",
+			schema:        `{"type":`,
+			expectedError: "invalid schema: unexpected EOF",
+		},
+		{
+			name:          "invalid schema type",
+			schema:        `{"not":1}`,
+			expectedError: `invalid schema at "mem:///schema.json#/not", object or boolean expected`,
+		},
+		{
+			name:          "invalid items schema",
+			schema:        `{"items":[{}]}`,
+			expectedError: `invalid schema at "mem:///schema.json#/items", object or boolean expected`,
+		},
+		{
+			name:          "invalid count",
+			schema:        `{"minLength":-1}`,
+			expectedError: `invalid "minLength" keyword at "mem:///schema.json#", non-negative integer expected`,
+		},
+		{
+			name:          "invalid pattern",
+			schema:        `{"pattern":"(?<"}`,
+			expectedError: "invalid \"pattern\" keyword at \"mem:///schema.json#\": error parsing regexp: ",
+		},
+		{
+			name:          "unresolvable reference",
+			schema:        `{"$ref":"#/$defs/missing"}`,
+			expectedError: `invalid reference "mem:///schema.json#/$defs/missing"`,
+		},
+		{
+			name:          "self reference",
+			schema:        `{"$ref":"#"}`,
+			expectedError: `invalid schema at "mem:///schema.json#", it is applied to itself in an infinite loop`,
+		},
+		{
+			name:          "definitions references cycle",
+			schema:        `{"$defs":{"a":{"$ref":"#/$defs/a"}},"$ref":"#/$defs/a"}`,
+			expectedError: `invalid schema at "mem:///schema.json#/$defs/a", it is applied to itself in an infinite loop`,
+		},
+		{
+			name:          "allOf and dynamic reference cycle",
+			schema:        `{"$dynamicAnchor":"node","allOf":[{"anyOf":[{"$dynamicRef":"#node"}]}]}`,
+			expectedError: `invalid schema at "mem:///schema.json#", it is applied to itself in an infinite loop`,
+		},
+		{
+			name:          "unresolvable external reference",
+			schema:        `{"$ref":"other.json"}`,
+			expectedError: `invalid "$ref" keyword at "mem:///schema.json#": cannot load "mem:///other.json", no file system to load from`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			subject, err := jsonschema.Compile([]byte(test.schema))
+
+			// assert
+			assert.Nil(t, subject)
+			if assert.NotNil(t, err) {
+				assert.True(t, bytes.HasPrefix([]byte(err.Error()), []byte(test.expectedError)))
+			}
+		})
+	}
+}
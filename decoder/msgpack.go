@@ -0,0 +1,289 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// maxBinaryDepth is the maximum nesting depth of arrays/maps accepted by binary decoders.
+const maxBinaryDepth = 1000
+
+// newMessagePackSyntaxError returns a new [*SyntaxError] for MessagePack format.
+func newMessagePackSyntaxError() error {
+	return &SyntaxError{Format: FormatMessagePack}
+}
+
+// DecodeMessagePack decodes MessagePack contents of a [io.Reader] into a variable.
+// Destination is populated like [DecodeJSON] does (so "json" struct tags are honoured),
+// with binary values mapped to []byte, and timestamp extension values to [time.Time].
+func DecodeMessagePack(r io.Reader, dest any) error {
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	parser := msgpackParser{data: data}
+	value, err := parser.parse(0)
+	if err != nil {
+		return err
+	}
+	if parser.pos != len(data) {
+		return &TrailingDataError{Format: FormatMessagePack}
+	}
+
+	return assignGeneric(value, dest)
+}
+
+// assignGeneric assigns a generic value (as produced by binary parsers) to dest,
+// through JSON, so that the same decoding rules as for JSON apply.
+func assignGeneric(value, dest any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		if errors.As(err, &unmarshalTypeError) {
+			mismatchErr := newJSONTypeMismatchError(unmarshalTypeError)
+			mismatchErr.Offset = 0 // offset within intermediary JSON is meaningless
+
+			return mismatchErr
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// msgpackParser parses MessagePack encoded data into generic values.
+type msgpackParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *msgpackParser) next(n int) ([]byte, error) {
+	if n < 0 || n > len(p.data)-p.pos {
+		return nil, newMessagePackSyntaxError()
+	}
+	b := p.data[p.pos : p.pos+n]
+	p.pos += n
+
+	return b, nil
+}
+
+func (p *msgpackParser) uint(size int) (uint64, error) {
+	b, err := p.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (p *msgpackParser) length(size int) (int, error) {
+	n, err := p.uint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(p.data)-p.pos) { // each element takes at least 1 byte
+		return 0, newMessagePackSyntaxError()
+	}
+
+	return int(n), nil
+}
+
+func (p *msgpackParser) parse(depth int) (any, error) {
+	if depth > maxBinaryDepth {
+		return nil, newMessagePackSyntaxError()
+	}
+	b, err := p.next(1)
+	if err != nil {
+		return nil, err
+	}
+	format := b[0]
+	switch {
+	case format <= 0x7f: // positive fixint
+		return int64(format), nil
+	case format >= 0xe0: // negative fixint
+		return int64(int8(format)), nil
+	case format >= 0x80 && format <= 0x8f: // fixmap
+		return p.parseMap(int(format&0x0f), depth)
+	case format >= 0x90 && format <= 0x9f: // fixarray
+		return p.parseArray(int(format&0x0f), depth)
+	case format >= 0xa0 && format <= 0xbf: // fixstr
+		return p.parseStr(int(format & 0x1f))
+	}
+
+	switch format {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: // bin 8/16/32
+		n, err := p.length(1 << (format - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		bin, err := p.next(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte(nil), bin...), nil
+	case 0xc7, 0xc8, 0xc9: // ext 8/16/32
+		n, err := p.length(1 << (format - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+
+		return p.parseExt(n)
+	case 0xca: // float 32
+		n, err := p.uint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 0xcb: // float 64
+		n, err := p.uint(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(n), nil
+	case 0xcc, 0xcd, 0xce, 0xcf: // uint 8/16/32/64
+		return p.uint(1 << (format - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3: // int 8/16/32/64
+		size := 1 << (format - 0xd0)
+		n, err := p.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+
+		return int64(n<<shift) >> shift, nil // sign extension
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext 1/2/4/8/16
+		return p.parseExt(1 << (format - 0xd4))
+	case 0xd9, 0xda, 0xdb: // str 8/16/32
+		n, err := p.length(1 << (format - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return p.parseStr(n)
+	case 0xdc, 0xdd: // array 16/32
+		n, err := p.length(2 << (format - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return p.parseArray(n, depth)
+	case 0xde, 0xdf: // map 16/32
+		n, err := p.length(2 << (format - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return p.parseMap(n, depth)
+	default: // 0xc1 is never used
+		return nil, newMessagePackSyntaxError()
+	}
+}
+
+func (p *msgpackParser) parseStr(n int) (any, error) {
+	b, err := p.next(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+func (p *msgpackParser) parseArray(n, depth int) (any, error) {
+	arr := make([]any, 0, min(n, len(p.data)-p.pos))
+	for range n {
+		value, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+	}
+
+	return arr, nil
+}
+
+func (p *msgpackParser) parseMap(n, depth int) (any, error) {
+	m := make(map[string]any, min(n, len(p.data)-p.pos))
+	for range n {
+		key, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[genericMapKey(key)] = value
+	}
+
+	return m, nil
+}
+
+// parseExt parses an extension of given data length; only the timestamp (-1) extension is supported.
+func (p *msgpackParser) parseExt(n int) (any, error) {
+	typ, err := p.next(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != -1 {
+		return nil, fmt.Errorf("unsupported MessagePack extension type %d", int8(typ[0]))
+	}
+
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		n := binary.BigEndian.Uint64(data)
+		nsec, sec := int64(n>>34), int64(n&0x3ffffffff)
+
+		return time.Unix(sec, nsec).UTC(), nil
+	case 12:
+		nsec := int64(binary.BigEndian.Uint32(data[:4]))
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+
+		return time.Unix(sec, nsec).UTC(), nil
+	default:
+		return nil, newMessagePackSyntaxError()
+	}
+}
+
+// genericMapKey returns the string representation of a map key.
+func genericMapKey(key any) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case []byte:
+		return string(k)
+	default:
+		return fmt.Sprint(k)
+	}
+}
@@ -0,0 +1,161 @@
+package decoder_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+type testBinaryStruct struct {
+	Name string    `json:"name"`
+	Age  int       `json:"age"`
+	Tags []string  `json:"tags"`
+	Bin  []byte    `json:"bin"`
+	Neg  int       `json:"neg"`
+	Big  uint16    `json:"big"`
+	F    float64   `json:"f"`
+	OK   bool      `json:"ok"`
+	Nil  *string   `json:"nil"`
+	TS   time.Time `json:"ts"`
+}
+
+var expectedTestBinaryStruct = testBinaryStruct{
+	Name: "John Doe",
+	Age:  42,
+	Tags: []string{"a", "b"},
+	Bin:  []byte{1, 2},
+	Neg:  -200,
+	Big:  300,
+	F:    1.5,
+	OK:   true,
+	TS:   time.Unix(100, 0).UTC(),
+}
+
+func TestDecodeMessagePack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully decodes msgpack", testDecodeMessagePackSuccess)
+	t.Run("returns error", testDecodeMessagePackErr)
+}
+
+func testDecodeMessagePackSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		r = bytes.NewReader(concatBytes(
+			[]byte{0x8a}, // fixmap with 10 entries
+			[]byte{0xa4}, []byte("name"), []byte{0xa8}, []byte("John Doe"),
+			[]byte{0xa3}, []byte("age"), []byte{0x2a},
+			[]byte{0xa4}, []byte("tags"), []byte{0x92, 0xa1, 'a', 0xa1, 'b'},
+			[]byte{0xa3}, []byte("bin"), []byte{0xc4, 0x02, 0x01, 0x02},
+			[]byte{0xa3}, []byte("neg"), []byte{0xd1, 0xff, 0x38},
+			[]byte{0xa3}, []byte("big"), []byte{0xcd, 0x01, 0x2c},
+			[]byte{0xa1}, []byte("f"), []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0},
+			[]byte{0xa2}, []byte("ok"), []byte{0xc3},
+			[]byte{0xa3}, []byte("nil"), []byte{0xc0},
+			[]byte{0xa2}, []byte("ts"), []byte{0xd6, 0xff, 0, 0, 0, 0x64},
+		))
+		dest testBinaryStruct
+	)
+
+	// act
+	err := decoder.DecodeMessagePack(r, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, expectedTestBinaryStruct, dest)
+	}
+}
+
+func testDecodeMessagePackErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name          string
+		input         []byte
+		expectedError string
+	}{
+		{
+			name:          "returns empty body error",
+			input:         []byte{},
+			expectedError: "body must not be empty",
+		},
+		{
+			name:          "returns multi objects error",
+			input:         []byte{0x80, 0xc0},
+			expectedError: "MessagePack does not contain a single object",
+		},
+		{
+			name:          "returns malformed error for truncated data",
+			input:         []byte{0xa4, 'n'},
+			expectedError: "badly-formed MessagePack",
+		},
+		{
+			name:          "returns malformed error for never used format",
+			input:         []byte{0xc1},
+			expectedError: "badly-formed MessagePack",
+		},
+		{
+			name:          "returns malformed error for too large declared length",
+			input:         []byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0xc0},
+			expectedError: "badly-formed MessagePack",
+		},
+		{
+			name:          "returns malformed error for too deep nesting",
+			input:         append(bytes.Repeat([]byte{0x91}, 1001), 0xc0),
+			expectedError: "badly-formed MessagePack",
+		},
+		{
+			name:          "returns unsupported extension error",
+			input:         []byte{0xd4, 0x01, 0x00},
+			expectedError: "unsupported MessagePack extension type 1",
+		},
+		{
+			name:          "returns invalid field type error",
+			input:         concatBytes([]byte{0x81, 0xa4}, []byte("name"), []byte{0x01}),
+			expectedError: `invalid value for the "name" field`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dest testBinaryStruct
+
+			// act
+			actualError := decoder.DecodeMessagePack(bytes.NewReader(test.input), &dest)
+
+			// assert
+			if assert.NotNil(t, actualError) {
+				assert.Equal(t, test.expectedError, actualError.Error())
+			}
+		})
+	}
+}
+
+func TestDecodeMessagePack_intoMap(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		r    = strings.NewReader("\x82\x01\xa3one\xa3two\x02") // {1: "one", "two": 2}
+		dest map[string]any
+	)
+
+	// act
+	err := decoder.DecodeMessagePack(r, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]any{"1": "one", "two": float64(2)}, dest)
+	}
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
@@ -0,0 +1,71 @@
+package decoder
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+const defaultMultipartMaxMemory int64 = 32 << 20 // 32 Mb
+
+// NewMultipartDecoder returns a [Decoder] for multipart/form-data contents with given boundary.
+// Up to maxMemory bytes (32Mb if not positive) of file parts are stored in memory.
+//
+// Destination can be a **[multipart.Form], case in which file parts exceeding maxMemory are stored
+// on disk, in temporary files, and the caller is responsible for calling [multipart.Form.RemoveAll].
+// Destination can also be any destination accepted by [DecodeForm], case in which
+// struct fields of type *[multipart.FileHeader] / []*[multipart.FileHeader] receive the files.
+// In the latter case, files are kept in memory only, a [*TooLargeError] being returned
+// if they exceed maxMemory.
+// A [*TooLargeError] is also returned if non-file parts exceed maxMemory plus 10Mb reserved for them,
+// see [multipart.Reader.ReadForm].
+func NewMultipartDecoder(boundary string, maxMemory int64) (Decoder, error) {
+	if boundary == "" {
+		return nil, errors.New("multipart boundary is missing")
+	}
+	if maxMemory <= 0 {
+		maxMemory = defaultMultipartMaxMemory
+	}
+
+	return func(r io.Reader, dest any) error {
+		form, err := multipart.NewReader(r, boundary).ReadForm(maxMemory)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return &TooLargeError{Limit: maxBytesErr.Limit, Err: err}
+			}
+			if errors.Is(err, multipart.ErrMessageTooLarge) { // non-file parts exceed the memory budget
+				return &TooLargeError{Limit: maxMemory, Err: err}
+			}
+
+			return &SyntaxError{Format: FormatMultipartForm, Err: err}
+		}
+		if formDest, ok := dest.(**multipart.Form); ok {
+			*formDest = form
+
+			return nil
+		}
+
+		if filesSize(form) > maxMemory { // some files were stored on disk
+			_ = form.RemoveAll()
+
+			return &TooLargeError{Limit: maxMemory, Err: multipart.ErrMessageTooLarge}
+		}
+
+		return assignForm(form.Value, form.File, dest)
+	}, nil
+}
+
+// filesSize returns the total size of form's files.
+// Files are stored in memory as long as their total size does not exceed the max memory.
+func filesSize(form *multipart.Form) int64 {
+	var size int64
+	for _, fileHeaders := range form.File {
+		for _, fileHeader := range fileHeaders {
+			size += fileHeader.Size
+		}
+	}
+
+	return size
+}
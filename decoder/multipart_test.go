@@ -0,0 +1,176 @@
+package decoder_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+type testMultipartStruct struct {
+	Name   string                  `form:"name"`
+	Avatar *multipart.FileHeader   `form:"avatar"`
+	Docs   []*multipart.FileHeader `form:"doc"`
+}
+
+func TestMultipartDecoder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully decodes into struct", testMultipartDecoderStruct)
+	t.Run("successfully decodes into form", testMultipartDecoderForm)
+	t.Run("returns error for missing boundary", testMultipartDecoderNoBoundary)
+	t.Run("returns error for malformed content", testMultipartDecoderMalformed)
+	t.Run("returns error for values exceeding memory", testMultipartDecoderValuesExceedingMemory)
+}
+
+// newTestMultipartBody returns a multipart body with a "name" field, an "avatar" file, and 2 "doc" files.
+func newTestMultipartBody(t *testing.T) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	assert.RequireNil(t, writer.WriteField("name", "John Doe"))
+	for _, file := range [...][2]string{{"avatar", "avatar.png"}, {"doc", "a.txt"}, {"doc", "b.txt"}} {
+		part, err := writer.CreateFormFile(file[0], file[1])
+		assert.RequireNil(t, err)
+		_, err = part.Write([]byte("contents of " + file[1]))
+		assert.RequireNil(t, err)
+	}
+	assert.RequireNil(t, writer.Close())
+
+	return body, writer.Boundary()
+}
+
+func testMultipartDecoderStruct(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	body, boundary := newTestMultipartBody(t)
+	subject, err := decoder.NewMultipartDecoder(boundary, 0)
+	assert.RequireNil(t, err)
+	var dest testMultipartStruct
+
+	// act
+	err = subject(body, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+		if assert.NotNil(t, dest.Avatar) {
+			assert.Equal(t, "avatar.png", dest.Avatar.Filename)
+			file, err := dest.Avatar.Open()
+			if assert.Nil(t, err) {
+				contents, _ := io.ReadAll(file)
+				_ = file.Close()
+				assert.Equal(t, "contents of avatar.png", string(contents))
+			}
+		}
+		if assert.Equal(t, 2, len(dest.Docs)) {
+			assert.Equal(t, "a.txt", dest.Docs[0].Filename)
+			assert.Equal(t, "b.txt", dest.Docs[1].Filename)
+		}
+	}
+}
+
+func testMultipartDecoderForm(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	body, boundary := newTestMultipartBody(t)
+	var dest *multipart.Form
+
+	// act
+	err := decoder.DefaultRegistry.Decode("multipart/form-data; boundary="+boundary, body, &dest)
+
+	// assert
+	if assert.Nil(t, err) && assert.NotNil(t, dest) {
+		assert.Equal(t, []string{"John Doe"}, dest.Value["name"])
+		assert.Equal(t, 2, len(dest.File["doc"]))
+		assert.Nil(t, dest.RemoveAll())
+	}
+}
+
+func TestMultipartDecoder_structDestinationFilesExceedingMemory(t *testing.T) {
+	// not parallel, as TMPDIR env is changed, in order to check no temporary file is left behind
+
+	// arrange
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	body, boundary := newTestMultipartBody(t)
+	subject, err := decoder.NewMultipartDecoder(boundary, 1)
+	assert.RequireNil(t, err)
+	var dest testMultipartStruct
+
+	// act
+	err = subject(body, &dest)
+
+	// assert
+	var tooLargeErr *decoder.TooLargeError
+	if assert.True(t, errors.As(err, &tooLargeErr)) {
+		assert.Equal(t, int64(1), tooLargeErr.Limit)
+	}
+	assert.Nil(t, dest.Avatar)
+	tmpFiles, err := os.ReadDir(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(tmpFiles))
+}
+
+func testMultipartDecoderValuesExceedingMemory(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	assert.RequireNil(t, writer.WriteField("name", strings.Repeat("a", 10<<20+16)))
+	assert.RequireNil(t, writer.Close())
+	subject, err := decoder.NewMultipartDecoder(writer.Boundary(), 1)
+	assert.RequireNil(t, err)
+	var dest testMultipartStruct
+
+	// act
+	err = subject(body, &dest)
+
+	// assert
+	var tooLargeErr *decoder.TooLargeError
+	if assert.True(t, errors.As(err, &tooLargeErr)) {
+		assert.Equal(t, int64(1), tooLargeErr.Limit)
+		assert.True(t, errors.Is(err, multipart.ErrMessageTooLarge))
+	}
+	assert.Equal(t, "", dest.Name)
+}
+
+func testMultipartDecoderNoBoundary(t *testing.T) {
+	t.Parallel()
+
+	// act
+	subject, err := decoder.NewMultipartDecoder("", 0)
+
+	// assert
+	assert.Nil(t, subject)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "multipart boundary is missing", err.Error())
+	}
+}
+
+func testMultipartDecoderMalformed(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject, err := decoder.NewMultipartDecoder("xyz", 0)
+	assert.RequireNil(t, err)
+	var dest testMultipartStruct
+
+	// act
+	err = subject(strings.NewReader("not a multipart body"), &dest)
+
+	// assert
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "badly-formed multipart form", err.Error())
+	}
+}
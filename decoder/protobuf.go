@@ -0,0 +1,26 @@
+package decoder
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// DecodeProtobuf decodes protobuf (binary wire format) contents of a [io.Reader] into a variable,
+// which must be a [proto.Message].
+func DecodeProtobuf(r io.Reader, dest any) error {
+	msg, ok := dest.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: cannot decode into %T, a proto.Message is expected", dest)
+	}
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return &SyntaxError{Format: FormatProtobuf, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package decoder_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestDecodeProtobuf(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully decodes protobuf", testDecodeProtobufSuccess)
+	t.Run("returns error for non proto.Message destination", testDecodeProtobufErrDest)
+	t.Run("returns error for malformed protobuf", testDecodeProtobufErrMalformed)
+}
+
+func testDecodeProtobufSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	data, err := proto.Marshal(wrapperspb.String("John Doe"))
+	assert.RequireNil(t, err)
+	dest := new(wrapperspb.StringValue)
+
+	// act
+	err = decoder.DecodeProtobuf(bytes.NewReader(data), dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.GetValue())
+	}
+}
+
+func testDecodeProtobufErrDest(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var dest testDummyStruct
+
+	// act
+	err := decoder.DecodeProtobuf(strings.NewReader("\x0a\x01a"), &dest)
+
+	// assert
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "protobuf: cannot decode into *decoder_test.testDummyStruct, a proto.Message is expected", err.Error())
+	}
+}
+
+func testDecodeProtobufErrMalformed(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	dest := new(wrapperspb.StringValue)
+
+	// act
+	err := decoder.DecodeProtobuf(strings.NewReader("\x0a\x05a"), dest)
+
+	// assert
+	var syntaxErr *decoder.SyntaxError
+	if assert.True(t, errors.As(err, &syntaxErr)) {
+		assert.Equal(t, "badly-formed protobuf", syntaxErr.Error())
+		assert.NotNil(t, syntaxErr.Unwrap())
+	}
+}
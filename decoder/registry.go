@@ -0,0 +1,175 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Media types of the built-in decoders.
+const (
+	MediaTypeJSON          = "application/json"
+	MediaTypeXML           = "application/xml"
+	MediaTypeTextXML       = "text/xml"
+	MediaTypeForm          = "application/x-www-form-urlencoded"
+	MediaTypeMultipartForm = "multipart/form-data"
+	MediaTypeMessagePack   = "application/msgpack"
+	MediaTypeMessagePackX  = "application/x-msgpack"
+	MediaTypeCBOR          = "application/cbor"
+	MediaTypeProtobuf      = "application/protobuf"
+	MediaTypeProtobufX     = "application/x-protobuf"
+)
+
+// ErrUnsupportedMediaType is the error matched (through [errors.Is]) by [*UnsupportedMediaTypeError].
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// UnsupportedMediaTypeError is returned when there is no decoder registered for a content type.
+// It can be mapped to a 415 Unsupported Media Type HTTP response.
+type UnsupportedMediaTypeError struct {
+	// ContentType is the unsupported content type.
+	ContentType string
+}
+
+// Error returns the error message.
+func (err *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q", err.ContentType)
+}
+
+// Is returns true for [ErrUnsupportedMediaType] target.
+func (err *UnsupportedMediaTypeError) Is(target error) bool {
+	return target == ErrUnsupportedMediaType
+}
+
+// StatusCode returns the HTTP status code corresponding to this error, 415.
+func (err *UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// DecoderFactory returns a [Decoder] for given media type parameters (like "charset", "boundary").
+type DecoderFactory func(params map[string]string) (Decoder, error)
+
+// Registry maps media types to decoders.
+// Lookup is performed by exact media type, and then, for structured syntax suffixes
+// (like "application/problem+json"), by the suffix's media type ("application/json").
+// It is concurrent safe to use.
+type Registry struct {
+	factories        map[string]DecoderFactory
+	defaultMediaType string
+	mu               sync.RWMutex
+}
+
+// NewRegistry instantiates a new, empty, [Registry].
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]DecoderFactory)}
+}
+
+// NewDefaultRegistry instantiates a new [Registry], with the built-in decoders registered
+// (JSON, XML, url encoded form, multipart form, MessagePack, CBOR, protobuf),
+// and JSON as default media type.
+func NewDefaultRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register(MediaTypeJSON, DecodeJSON)
+	reg.Register(MediaTypeXML, DecodeXML)
+	reg.Register(MediaTypeTextXML, DecodeXML)
+	reg.Register(MediaTypeForm, DecodeForm)
+	reg.RegisterFactory(MediaTypeMultipartForm, func(params map[string]string) (Decoder, error) {
+		return NewMultipartDecoder(params["boundary"], 0)
+	})
+	reg.Register(MediaTypeMessagePack, DecodeMessagePack)
+	reg.Register(MediaTypeMessagePackX, DecodeMessagePack)
+	reg.Register(MediaTypeCBOR, DecodeCBOR)
+	reg.Register(MediaTypeProtobuf, DecodeProtobuf)
+	reg.Register(MediaTypeProtobufX, DecodeProtobuf)
+	reg.SetDefaultMediaType(MediaTypeJSON)
+
+	return reg
+}
+
+// DefaultRegistry is the registry used by [DecodeRequest].
+var DefaultRegistry = NewDefaultRegistry()
+
+// Register registers given decoder for given media type, replacing any previously registered one.
+func (reg *Registry) Register(mediaType string, dec Decoder) {
+	reg.RegisterFactory(mediaType, func(map[string]string) (Decoder, error) {
+		return dec, nil
+	})
+}
+
+// RegisterFactory registers given decoder factory for given media type, replacing any previously registered one.
+// It is useful for decoders which depend on media type parameters, like multipart's "boundary".
+func (reg *Registry) RegisterFactory(mediaType string, factory DecoderFactory) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.factories[strings.ToLower(mediaType)] = factory
+}
+
+// SetDefaultMediaType sets the media type used when the content type is empty.
+// If not set, an empty content type results in an [*UnsupportedMediaTypeError].
+func (reg *Registry) SetDefaultMediaType(mediaType string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.defaultMediaType = mediaType
+}
+
+// MediaTypes returns the registered media types.
+func (reg *Registry) MediaTypes() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(reg.factories))
+}
+
+// Lookup returns the decoder for given content type (like "application/json; charset=utf-8").
+// An [*UnsupportedMediaTypeError] is returned if there is no decoder registered for it.
+func (reg *Registry) Lookup(contentType string) (Decoder, error) {
+	reg.mu.RLock()
+	if strings.TrimSpace(contentType) == "" {
+		contentType = reg.defaultMediaType
+	}
+	reg.mu.RUnlock()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, &UnsupportedMediaTypeError{ContentType: contentType}
+	}
+
+	reg.mu.RLock()
+	factory, found := reg.factories[mediaType]
+	if !found {
+		if idx := strings.LastIndexByte(mediaType, '+'); idx > 0 {
+			slashIdx := strings.IndexByte(mediaType, '/')
+			factory, found = reg.factories[mediaType[:slashIdx+1]+mediaType[idx+1:]]
+		}
+	}
+	reg.mu.RUnlock()
+	if !found {
+		return nil, &UnsupportedMediaTypeError{ContentType: contentType}
+	}
+
+	return factory(params)
+}
+
+// Decode decodes contents of given reader into dest, with the decoder registered for given content type.
+func (reg *Registry) Decode(contentType string, r io.Reader, dest any) error {
+	dec, err := reg.Lookup(contentType)
+	if err != nil {
+		return err
+	}
+
+	return dec(r, dest)
+}
+
+// DecodeRequest decodes the body of given request into dest, with the decoder
+// registered in [DefaultRegistry] for request's Content-Type.
+// Request body should be limited, see [github.com/actforgood/xtransport/http.GetRequestBody].
+// An [*UnsupportedMediaTypeError] is returned if Content-Type is not supported.
+func DecodeRequest(r *http.Request, dest any) error {
+	return DefaultRegistry.Decode(r.Header.Get("Content-Type"), r.Body, dest)
+}
@@ -0,0 +1,180 @@
+package decoder_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decodes by exact media type", testRegistryExactMediaType)
+	t.Run("decodes by structured syntax suffix", testRegistrySuffixMediaType)
+	t.Run("decodes with default media type", testRegistryDefaultMediaType)
+	t.Run("returns unsupported media type error", testRegistryUnsupportedMediaType)
+	t.Run("custom decoder replaces built-in one", testRegistryCustomDecoder)
+	t.Run("returns registered media types", testRegistryMediaTypes)
+}
+
+func testRegistryExactMediaType(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewDefaultRegistry()
+		dest    testDummyStruct
+	)
+
+	// act
+	err := subject.Decode(
+		"application/xml; charset=utf-8",
+		strings.NewReader(`<testDummyStruct><Name>John Doe</Name></testDummyStruct>`),
+		&dest,
+	)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
+
+func testRegistrySuffixMediaType(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewDefaultRegistry()
+		dest    testDummyStruct
+	)
+
+	// act
+	err := subject.Decode("application/vnd.api+JSON", strings.NewReader(`{"Name":"John Doe"}`), &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
+
+func testRegistryDefaultMediaType(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = decoder.NewDefaultRegistry()
+		dest    testDummyStruct
+	)
+
+	// act
+	err := subject.Decode("", strings.NewReader(`{"Name":"John Doe"}`), &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
+
+func testRegistryUnsupportedMediaType(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name        string
+		subject     *decoder.Registry
+		contentType string
+	}{
+		{
+			name:        "not registered media type",
+			subject:     decoder.NewDefaultRegistry(),
+			contentType: "text/plain",
+		},
+		{
+			name:        "invalid content type",
+			subject:     decoder.NewDefaultRegistry(),
+			contentType: "application/json; charset",
+		},
+		{
+			name:        "empty content type without default",
+			subject:     decoder.NewRegistry(),
+			contentType: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dest testDummyStruct
+
+			// act
+			err := test.subject.Decode(test.contentType, strings.NewReader(`{"Name":"John Doe"}`), &dest)
+
+			// assert
+			var mediaTypeErr *decoder.UnsupportedMediaTypeError
+			if assert.True(t, errors.As(err, &mediaTypeErr)) {
+				assert.Equal(t, test.contentType, mediaTypeErr.ContentType)
+				assert.Equal(t, http.StatusUnsupportedMediaType, mediaTypeErr.StatusCode())
+			}
+			assert.True(t, errors.Is(err, decoder.ErrUnsupportedMediaType))
+		})
+	}
+}
+
+func testRegistryCustomDecoder(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject  = decoder.NewDefaultRegistry()
+		dest     testDummyStruct
+		expected = errors.New("intentionally triggered custom decoder error")
+	)
+	subject.Register(decoder.MediaTypeJSON, func(io.Reader, any) error {
+		return expected
+	})
+
+	// act
+	err := subject.Decode(decoder.MediaTypeJSON, strings.NewReader(`{"Name":"John Doe"}`), &dest)
+
+	// assert
+	assert.Equal(t, expected, err)
+}
+
+func testRegistryMediaTypes(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := decoder.NewRegistry()
+	subject.Register("Application/JSON", decoder.DecodeJSON)
+	subject.Register(decoder.MediaTypeCBOR, decoder.DecodeCBOR)
+
+	// act
+	result := subject.MediaTypes()
+
+	// assert
+	assert.Equal(t, []string{"application/cbor", "application/json"}, result)
+}
+
+func TestDecodeRequest(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		req  = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=John+Doe"))
+		dest testDummyStruct
+	)
+	req.Header.Set("Content-Type", decoder.MediaTypeForm)
+
+	// act
+	err := decoder.DecodeRequest(req, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
@@ -0,0 +1,148 @@
+package decoder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ElementError is yielded by streaming decoders when an element cannot be decoded.
+type ElementError struct {
+	// Index is the 0 based index of the element.
+	Index int
+	// Line is the 1 based line number of the element, for NDJSON (0 otherwise).
+	Line int
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error message, like `element 3: invalid value for the "name" field (at position 11)`.
+func (err *ElementError) Error() string {
+	if err.Line > 0 {
+		return fmt.Sprintf("element %d (line %d): %s", err.Index, err.Line, err.Err)
+	}
+
+	return fmt.Sprintf("element %d: %s", err.Index, err.Err)
+}
+
+// Unwrap returns the underlying error.
+func (err *ElementError) Unwrap() error {
+	return err.Err
+}
+
+// DecodeJSONArray returns an iterator over the elements of a top-level JSON array read from given reader.
+// Elements are decoded one at a time, so memory usage does not depend on array's size.
+//
+// An element which cannot be assigned to T yields an [*ElementError] and iteration continues
+// with next element. Malformed contents yield an [*ElementError] (or a [*SyntaxError] if contents
+// are not an array at all) and iteration stops. Other errors, like [*EmptyBodyError], [*TooLargeError],
+// [*TrailingDataError], are yielded as they are, and iteration stops.
+// Reported positions are relative to the element's start.
+//
+// Example:
+//
+//	for item, err := range decoder.DecodeJSONArray[Item](r.Body) {
+//		if err != nil {
+//			// handle / collect error, break or continue
+//			continue
+//		}
+//		// process item
+//	}
+func DecodeJSONArray[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		dec := json.NewDecoder(r)
+		token, err := dec.Token()
+		if err != nil {
+			yield(zero, translateJSONError(err))
+
+			return
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			yield(zero, &SyntaxError{
+				Format: FormatJSON,
+				Offset: dec.InputOffset(),
+				Err:    errors.New("expected a JSON array"),
+			})
+
+			return
+		}
+
+		for idx := 0; dec.More(); idx++ {
+			var elem T
+			if err := dec.Decode(&elem); err != nil {
+				translatedErr := translateJSONError(err)
+				var mismatchErr *TypeMismatchError
+				if errors.As(translatedErr, &mismatchErr) { // the element was consumed, we can continue
+					if !yield(zero, &ElementError{Index: idx, Err: translatedErr}) {
+						return
+					}
+
+					continue
+				}
+				var tooLargeErr *TooLargeError
+				if !errors.As(translatedErr, &tooLargeErr) {
+					translatedErr = &ElementError{Index: idx, Err: translatedErr}
+				}
+				yield(zero, translatedErr)
+
+				return
+			}
+			if !yield(elem, nil) {
+				return
+			}
+		}
+
+		if _, err := dec.Token(); err != nil { // closing ']'
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			yield(zero, translateJSONError(err))
+
+			return
+		}
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			yield(zero, &TrailingDataError{Format: FormatJSON})
+		}
+	}
+}
+
+// DecodeNDJSON returns an iterator over the values of newline delimited JSON (NDJSON / JSON Lines)
+// contents read from given reader. Values are decoded one at a time, so memory usage depends only
+// on the longest line. Blank lines are skipped (and not counted as elements).
+//
+// A line which cannot be decoded yields an [*ElementError] and iteration continues with next line.
+// Read errors, like [*TooLargeError], are yielded as they are, and iteration stops.
+// Reported positions are relative to the line's start. Empty contents yield nothing.
+func DecodeNDJSON[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		reader := bufio.NewReader(r)
+		for idx, lineNo := 0, 1; ; lineNo++ {
+			line, readErr := reader.ReadBytes('\n')
+			if readErr != nil && !errors.Is(readErr, io.EOF) {
+				yield(zero, translateReadError(readErr))
+
+				return
+			}
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				var elem T
+				if err := DecodeJSON(bytes.NewReader(line), &elem); err != nil {
+					if !yield(zero, &ElementError{Index: idx, Line: lineNo, Err: err}) {
+						return
+					}
+				} else if !yield(elem, nil) {
+					return
+				}
+				idx++
+			}
+			if readErr != nil { // EOF
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package decoder_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+type testStreamResult struct {
+	values []testDummyStruct
+	errs   []string
+}
+
+func collectStream(seq func(func(testDummyStruct, error) bool)) testStreamResult {
+	var result testStreamResult
+	for value, err := range seq {
+		if err != nil {
+			result.errs = append(result.errs, err.Error())
+
+			continue
+		}
+		result.values = append(result.values, value)
+	}
+
+	return result
+}
+
+func TestDecodeJSONArray(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name     string
+		reader   io.Reader
+		expected testStreamResult
+	}{
+		{
+			name:   "yields all elements",
+			reader: strings.NewReader(` [{"Name":"John Doe"}, {"Name":"Jane Doe"}] `),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}, {Name: "Jane Doe"}},
+			},
+		},
+		{
+			name:     "yields nothing for empty array",
+			reader:   strings.NewReader(`[]`),
+			expected: testStreamResult{},
+		},
+		{
+			name:   "continues after invalid element",
+			reader: strings.NewReader(`[{"Name":"John Doe"},{"Name":1},{"Name":"Jane Doe"}]`),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}, {Name: "Jane Doe"}},
+				errs:   []string{`element 1: invalid value for the "Name" field (at position 9)`},
+			},
+		},
+		{
+			name:   "stops at malformed element",
+			reader: strings.NewReader(`[{"Name":"John Doe"},{"Name":}, {"Name":"Jane Doe"}]`),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}},
+				errs:   []string{"element 1: badly-formed JSON (at position 30)"},
+			},
+		},
+		{
+			name:   "stops at truncated contents",
+			reader: strings.NewReader(`[{"Name":"John Doe"}`),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}},
+				errs:   []string{"element 1: badly-formed JSON (at position 20)"},
+			},
+		},
+		{
+			name:     "returns error for non array contents",
+			reader:   strings.NewReader(`{"Name":"John Doe"}`),
+			expected: testStreamResult{errs: []string{"badly-formed JSON (at position 1)"}},
+		},
+		{
+			name:     "returns error for empty contents",
+			reader:   strings.NewReader(``),
+			expected: testStreamResult{errs: []string{"body must not be empty"}},
+		},
+		{
+			name:   "returns error for trailing data",
+			reader: strings.NewReader(`[{"Name":"John Doe"}] []`),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}},
+				errs:   []string{"json does not contain a single object"},
+			},
+		},
+		{
+			name: "returns error for too large contents",
+			reader: http.MaxBytesReader(
+				nil,
+				io.NopCloser(strings.NewReader(`[{"Name":"John Doe"},{"Name":"Jane Doe"}]`)),
+				25,
+			),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}},
+				errs:   []string{"body too large"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// act
+			result := collectStream(decoder.DecodeJSONArray[testDummyStruct](test.reader))
+
+			// assert
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestDecodeJSONArray_break(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		reader = strings.NewReader(`[{"Name":"John Doe"},{"Name":"Jane Doe"},{"Name":"Jim Doe"}]`)
+		names  []string
+	)
+
+	// act
+	for value, err := range decoder.DecodeJSONArray[testDummyStruct](reader) {
+		assert.Nil(t, err)
+		names = append(names, value.Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+
+	// assert
+	assert.Equal(t, []string{"John Doe", "Jane Doe"}, names)
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name     string
+		reader   io.Reader
+		expected testStreamResult
+	}{
+		{
+			name:   "yields all values, skipping blank lines",
+			reader: strings.NewReader("{\"Name\":\"John Doe\"}\r\n\n  \n{\"Name\":\"Jane Doe\"}"),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}, {Name: "Jane Doe"}},
+			},
+		},
+		{
+			name:     "yields nothing for empty contents",
+			reader:   strings.NewReader(""),
+			expected: testStreamResult{},
+		},
+		{
+			name: "continues after invalid lines",
+			reader: strings.NewReader(
+				"{\"Name\":\"John Doe\"}\n\n{\"Name\":1}\n{\"Name\":\n{} {}\n{\"Name\":\"Jane Doe\"}\n",
+			),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}, {Name: "Jane Doe"}},
+				errs: []string{
+					`element 1 (line 3): invalid value for the "Name" field (at position 9)`,
+					"element 2 (line 4): badly-formed JSON",
+					"element 3 (line 5): json does not contain a single object",
+				},
+			},
+		},
+		{
+			name: "returns error for too large contents",
+			reader: http.MaxBytesReader(
+				nil,
+				io.NopCloser(strings.NewReader("{\"Name\":\"John Doe\"}\n{\"Name\":\"Jane Doe\"}\n")),
+				25,
+			),
+			expected: testStreamResult{
+				values: []testDummyStruct{{Name: "John Doe"}},
+				errs:   []string{"body too large"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// act
+			result := collectStream(decoder.DecodeNDJSON[testDummyStruct](test.reader))
+
+			// assert
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestElementError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	reader := strings.NewReader("{\"Name\":1}\n")
+
+	// act
+	for _, err := range decoder.DecodeNDJSON[testDummyStruct](reader) {
+		// assert
+		var (
+			elemErr     *decoder.ElementError
+			mismatchErr *decoder.TypeMismatchError
+		)
+		if assert.True(t, errors.As(err, &elemErr)) {
+			assert.Equal(t, 0, elemErr.Index)
+			assert.Equal(t, 1, elemErr.Line)
+		}
+		if assert.True(t, errors.As(err, &mismatchErr)) {
+			assert.Equal(t, "/Name", mismatchErr.Pointer)
+		}
+	}
+}
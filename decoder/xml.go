@@ -0,0 +1,53 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// DecodeXML decodes XML contents of a [io.Reader] into a variable.
+func DecodeXML(r io.Reader, dest any) error {
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(dest); err != nil {
+		var syntaxError *xml.SyntaxError
+		switch {
+		case errors.As(err, &syntaxError):
+			return &SyntaxError{Format: FormatXML, Line: syntaxError.Line, Err: err}
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return &SyntaxError{Format: FormatXML, Err: err}
+		default:
+			var numError *strconv.NumError
+			if errors.As(err, &numError) {
+				return &TypeMismatchError{Value: numError.Num, Err: err}
+			}
+
+			return err
+		}
+	}
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return &SyntaxError{Format: FormatXML, Err: err}
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		case xml.Comment, xml.ProcInst:
+			continue
+		}
+
+		return &TrailingDataError{Format: FormatXML}
+	}
+}
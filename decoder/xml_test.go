@@ -0,0 +1,83 @@
+package decoder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/decoder"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestDecodeXML(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully decodes xml", testDecodeXMLSuccess)
+	t.Run("returns error", testDecodeXMLErr)
+}
+
+func testDecodeXMLSuccess(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		r = strings.NewReader(`<?xml version="1.0"?>
+<user><Name>John Doe</Name></user>
+<!-- trailing comment -->
+`)
+		dest testDummyStruct
+	)
+
+	// act
+	err := decoder.DecodeXML(r, &dest)
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(t, "John Doe", dest.Name)
+	}
+}
+
+func testDecodeXMLErr(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	tests := [...]struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{
+			name:          "returns empty body error",
+			input:         "",
+			expectedError: "body must not be empty",
+		},
+		{
+			name:          "returns multi root elements error",
+			input:         `<user><Name>John Doe</Name></user><user><Name>Jane Doe</Name></user>`,
+			expectedError: "xml does not contain a single root element",
+		},
+		{
+			name:          "returns malformed xml error at line",
+			input:         "<user>\n<Name>John Doe</Nam></user>",
+			expectedError: "badly-formed XML (at line 2)",
+		},
+		{
+			name:          "returns malformed xml error generic",
+			input:         "  \n",
+			expectedError: "badly-formed XML",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dest testDummyStruct
+
+			// act
+			actualError := decoder.DecodeXML(strings.NewReader(test.input), &dest)
+
+			// assert
+			if assert.NotNil(t, actualError) {
+				assert.Equal(t, test.expectedError, actualError.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/actforgood/xerr"
+)
+
+// TrustAllProxies is a list of CIDRs matching any address.
+// Using it means forwarding headers are always honoured, which may lead to ip spoofing,
+// if the service is not exclusively reachable through proxies.
+var TrustAllProxies = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/0"),
+	netip.MustParsePrefix("::/0"),
+}
+
+// DefaultClientIPResolver is the resolver used by [GetClientIP], and by middlewares
+// not configured with a resolver of their own. It trusts no proxy, so forwarding headers
+// are ignored, and the request's remote address is used.
+// Services behind proxies should configure a resolver aware of their infrastructure's proxies.
+//
+// Usage example:
+//
+//	trustedProxies, err := httpTransport.ParseTrustedProxies("10.0.0.0/8", "192.168.1.10")
+//	if err != nil {
+//	    // handle error
+//	}
+//	resolver := httpTransport.NewClientIPResolver(trustedProxies...)
+//	handler = middleware.AccessLogWithConfig(handler, logger, middleware.AccessLogConfig{ClientIPResolver: resolver})
+var DefaultClientIPResolver = NewClientIPResolver()
+
+// ClientIPResolverConfig holds the configuration for a [ClientIPResolver].
+type ClientIPResolverConfig struct {
+	// TrustedProxies are the proxies whose forwarding header is honoured, see [ParseTrustedProxies].
+	// If empty, forwarding header is ignored and remote address is used.
+	TrustedProxies []netip.Prefix
+	// Header is the single forwarding header set by the trusted proxies, like "X-Forwarded-For",
+	// "Forwarded" (RFC 7239), or "X-Real-Ip". Other forwarding headers are ignored, as a proxy
+	// usually passes through, unchanged, the ones it does not set, so they may be spoofed by the client.
+	// Defaults to "X-Forwarded-For".
+	Header string
+}
+
+// ClientIPResolver resolves the client ip of a request.
+// The configured forwarding header is honoured only if the request comes from a trusted proxy.
+// Its list of addresses is walked from right to left, skipping trusted hops,
+// the first untrusted address being considered the client ip.
+type ClientIPResolver struct {
+	trustedProxies []netip.Prefix
+	header         string
+}
+
+// NewClientIPResolver instantiates a new client ip resolver which trusts given proxies,
+// and honours the X-Forwarded-For header set by them.
+// If no proxy is provided, forwarding header is ignored and remote address is used.
+func NewClientIPResolver(trustedProxies ...netip.Prefix) *ClientIPResolver {
+	return NewClientIPResolverWithConfig(ClientIPResolverConfig{TrustedProxies: trustedProxies})
+}
+
+// NewClientIPResolverWithConfig instantiates a new client ip resolver with given configuration.
+func NewClientIPResolverWithConfig(config ClientIPResolverConfig) *ClientIPResolver {
+	if config.Header == "" {
+		config.Header = "X-Forwarded-For"
+	}
+
+	return &ClientIPResolver{
+		trustedProxies: config.TrustedProxies,
+		header:         http.CanonicalHeaderKey(config.Header),
+	}
+}
+
+// ParseTrustedProxies parses a list of CIDRs or single ip addresses.
+func ParseTrustedProxies(proxies ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, xerr.Wrapf(err, "invalid trusted proxy CIDR %q", proxy)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, xerr.Wrapf(err, "invalid trusted proxy ip %q", proxy)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// ClientIP returns the client ip of the request.
+// The zero [netip.Addr] is returned if no valid ip could be determined.
+func (res *ClientIPResolver) ClientIP(r *http.Request) netip.Addr {
+	remoteAddr := parseIPWithOptionalPort(r.RemoteAddr)
+	if !res.isTrusted(remoteAddr) {
+		return remoteAddr
+	}
+
+	values := r.Header.Values(res.header)
+	if len(values) == 0 {
+		return remoteAddr
+	}
+	if res.header == "Forwarded" {
+		return res.walkHops(parseForwardedFor(values), remoteAddr)
+	}
+
+	return res.walkHops(splitHeaderList(values), remoteAddr)
+}
+
+// walkHops walks the hops from right to left, returning the first untrusted address.
+// If an invalid hop is encountered, the last valid address seen is returned.
+// If all hops are trusted, the leftmost one is returned.
+func (res *ClientIPResolver) walkHops(hops []string, remoteAddr netip.Addr) netip.Addr {
+	clientIP := remoteAddr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr := parseIPWithOptionalPort(hops[i])
+		if !addr.IsValid() {
+			break
+		}
+		clientIP = addr
+		if !res.isTrusted(addr) {
+			break
+		}
+	}
+
+	return clientIP
+}
+
+func (res *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	for _, prefix := range res.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitHeaderList splits comma separated header values.
+func splitHeaderList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		for item := range strings.SplitSeq(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+
+	return list
+}
+
+// parseForwardedFor extracts the "for" parameter of each Forwarded header element.
+// See RFC 7239.
+func parseForwardedFor(values []string) []string {
+	elements := splitHeaderList(values)
+	hops := make([]string, 0, len(elements))
+	for _, element := range elements {
+		var forValue string
+		for pair := range strings.SplitSeq(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if found && strings.EqualFold(key, "for") {
+				forValue = strings.Trim(value, `"`)
+
+				break
+			}
+		}
+		hops = append(hops, forValue)
+	}
+
+	return hops
+}
+
+// parseIPWithOptionalPort parses formats like "1.2.3.4", "1.2.3.4:80", "::1", "[::1]", "[::1]:80".
+// IPv4-mapped IPv6 addresses are unmapped.
+func parseIPWithOptionalPort(value string) netip.Addr {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return netip.Addr{}
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap()
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	} else {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap()
+	}
+
+	return netip.Addr{}
+}
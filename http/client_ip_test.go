@@ -0,0 +1,182 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	httpTransport "github.com/actforgood/xtransport/http"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid CIDRs and ips", testParseTrustedProxiesValid)
+	t.Run("invalid CIDR", testParseTrustedProxiesInvalid)
+}
+
+func testParseTrustedProxiesValid(t *testing.T) {
+	t.Parallel()
+
+	// act
+	prefixes, err := httpTransport.ParseTrustedProxies("10.1.2.3/8", " 192.168.1.10", "::ffff:172.16.0.1", "2001:db8::/32")
+
+	// assert
+	if assert.Nil(t, err) {
+		assert.Equal(
+			t,
+			[]netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.168.1.10/32"),
+				netip.MustParsePrefix("172.16.0.1/32"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			},
+			prefixes,
+		)
+	}
+}
+
+func testParseTrustedProxiesInvalid(t *testing.T) {
+	t.Parallel()
+
+	// act
+	prefixes, err := httpTransport.ParseTrustedProxies("10.0.0.0/8", "not-an-ip")
+
+	// assert
+	assert.NotNil(t, err)
+	assert.Nil(t, prefixes)
+}
+
+func TestClientIPResolver(t *testing.T) {
+	t.Parallel()
+
+	trustedProxies, err := httpTransport.ParseTrustedProxies("10.0.0.0/8", "2001:db8::/32")
+	assert.RequireNil(t, err)
+
+	tests := [...]struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string]string
+		expectedIP string
+	}{
+		{
+			name:       "untrusted remote address ignores headers",
+			remoteAddr: "203.0.113.5:1234",
+			headers: map[string]string{
+				"X-Real-Ip":       "1.2.3.4",
+				"X-Forwarded-For": "5.6.7.8",
+				"Forwarded":       "for=9.9.9.9",
+			},
+			expectedIP: "203.0.113.5",
+		},
+		{
+			name:       "trusted remote address with no headers",
+			remoteAddr: "10.0.0.1:1234",
+			expectedIP: "10.0.0.1",
+		},
+		{
+			name:       "forged X-Real-Ip and Forwarded are ignored",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Real-Ip":       "1.2.3.4",
+				"Forwarded":       "for=5.6.7.8",
+				"X-Forwarded-For": "203.0.113.7",
+			},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:       "configured X-Real-Ip header is honoured",
+			header:     "x-real-ip",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-Ip": "1.2.3.4", "X-Forwarded-For": "5.6.7.8"},
+			expectedIP: "1.2.3.4",
+		},
+		{
+			name:       "configured header missing",
+			header:     "X-Real-Ip",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "5.6.7.8"},
+			expectedIP: "10.0.0.1",
+		},
+		{
+			name:       "XFF is walked from right skipping trusted hops",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, 8.8.8.8, 10.0.0.2, 10.0.0.3"},
+			expectedIP: "8.8.8.8",
+		},
+		{
+			name:       "XFF all trusted returns leftmost",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.5, 10.0.0.2"},
+			expectedIP: "10.0.0.5",
+		},
+		{
+			name:       "XFF invalid hop stops the walk",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "8.8.8.8, garbage, 10.0.0.2"},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			name:       "configured Forwarded header is honoured",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"Forwarded":       `for=192.0.2.60;proto=http;by=203.0.113.43, For="[2001:db8:cafe::17]:4711"`,
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			expectedIP: "192.0.2.60",
+		},
+		{
+			name:       "Forwarded with untrusted ipv6",
+			header:     "Forwarded",
+			remoteAddr: "[2001:db8::1]:443",
+			headers:    map[string]string{"Forwarded": `for="[2001:dead::17]:4711", for=10.0.0.3`},
+			expectedIP: "2001:dead::17",
+		},
+		{
+			name:       "Forwarded with unknown identifier",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"Forwarded": `for=unknown, for=10.0.0.3:80`},
+			expectedIP: "10.0.0.3",
+		},
+		{
+			name:       "IPv4-mapped IPv6 remote address is unmapped",
+			remoteAddr: "[::ffff:10.0.0.1]:1234",
+			headers:    map[string]string{"X-Forwarded-For": "8.8.8.8"},
+			expectedIP: "8.8.8.8",
+		},
+		{
+			name:       "invalid remote address",
+			remoteAddr: "invalid",
+			headers:    map[string]string{"X-Real-Ip": "1.2.3.4"},
+			expectedIP: "invalid IP",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			subject := httpTransport.NewClientIPResolverWithConfig(httpTransport.ClientIPResolverConfig{
+				TrustedProxies: trustedProxies,
+				Header:         test.header,
+			})
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/ip", nil)
+			req.RemoteAddr = test.remoteAddr
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			// act
+			actualIP := subject.ClientIP(req)
+
+			// assert
+			assert.Equal(t, test.expectedIP, actualIP.String())
+		})
+	}
+}
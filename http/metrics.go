@@ -0,0 +1,16 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/actforgood/xtransport/metrics"
+)
+
+// Metrics can be registered as metrics endpoint (like "GET /metrics").
+// It exposes the metrics from given registry in Prometheus text exposition format.
+func Metrics(registry *metrics.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = registry.WriteText(w) // nothing to do upon a write error, client is most likely gone.
+	}
+}
@@ -1,9 +1,16 @@
 package middleware
 
 import (
+	"crypto/tls"
+	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/actforgood/xtransport"
@@ -13,41 +20,6 @@ import (
 // AccessLevel is the log level used for access logs.
 var AccessLevel slog.Level = slog.LevelError + 4
 
-// http.ResponseWriter.
-type statusAwareResponseWriter struct {
-	origW      http.ResponseWriter // original response writer
-	statusCode int                 // captures response status code
-	bodySize   int                 // captures response body length
-}
-
-func (w *statusAwareResponseWriter) Header() http.Header {
-	return w.origW.Header()
-}
-
-func (w *statusAwareResponseWriter) Write(data []byte) (int, error) {
-	n, err := w.origW.Write(data)
-	w.bodySize += n
-
-	return n, err
-}
-
-func (w *statusAwareResponseWriter) WriteHeader(code int) {
-	w.statusCode = code
-	w.origW.WriteHeader(code)
-}
-
-func (w *statusAwareResponseWriter) StatusCode() int {
-	if w.statusCode == 0 {
-		return http.StatusOK
-	}
-
-	return w.statusCode
-}
-
-func (w *statusAwareResponseWriter) BodySize() int {
-	return w.bodySize
-}
-
 // AccessRequestCallback is a function type through which you can specify a callback
 // to to indicate whether a request should be logged or not, and to modify the request before logging.
 //
@@ -68,55 +40,265 @@ func (w *statusAwareResponseWriter) BodySize() int {
 //	}
 type AccessRequestCallbeck func(r *http.Request) bool
 
-// AccessLog is a decorator/middleware that extracts/ads a correlation id
-// from/to request/response.
+// AccessLogField identifies a field (or a group of related fields) emitted by the access log.
+// Fields can be combined with bitwise OR.
+type AccessLogField uint32
+
+const (
+	// AccessLogMethod represents the "method" field.
+	AccessLogMethod AccessLogField = 1 << iota
+	// AccessLogPath represents the "path" field.
+	AccessLogPath
+	// AccessLogQuery represents the "query" field.
+	AccessLogQuery
+	// AccessLogTook represents the "took" field.
+	AccessLogTook
+	// AccessLogUserAgent represents the "userAgent" field.
+	AccessLogUserAgent
+	// AccessLogIP represents the "ip" field.
+	AccessLogIP
+	// AccessLogStatusCode represents the "statusCode" field.
+	AccessLogStatusCode
+	// AccessLogCorrelationID represents the "correlationId" field.
+	AccessLogCorrelationID
+	// AccessLogAuthUsername represents the "authUsername" field.
+	AccessLogAuthUsername
+	// AccessLogContentLength represents the "reqContentLength", "respContentLength" / "respBodyLength" fields.
+	AccessLogContentLength
+	// AccessLogPattern represents the "pattern" field, the matched [http.ServeMux] pattern.
+	// If access log does not decorate the mux directly, see [RoutePattern].
+	AccessLogPattern
+	// AccessLogProtocol represents the "protocol" field.
+	AccessLogProtocol
+	// AccessLogTLSVersion represents the "tlsVersion" field.
+	AccessLogTLSVersion
+	// AccessLogBytesIn represents the "bytesIn" field, the no. of request body bytes read.
+	AccessLogBytesIn
+	// AccessLogBytesOut represents the "bytesOut" field, the no. of response body bytes written.
+	AccessLogBytesOut
+)
+
+// DefaultAccessLogFields are the fields emitted by default by the access log.
+const DefaultAccessLogFields = AccessLogMethod | AccessLogPath | AccessLogQuery | AccessLogTook |
+	AccessLogUserAgent | AccessLogIP | AccessLogStatusCode | AccessLogCorrelationID |
+	AccessLogAuthUsername | AccessLogContentLength
+
+// AccessLogConfig holds the configuration for [AccessLogWithConfig] middleware.
+type AccessLogConfig struct {
+	// Callback, if set, is called to decide whether a request should be logged, see [AccessRequestCallbeck].
+	Callback AccessRequestCallbeck
+	// Fields are the emitted fields. Defaults to [DefaultAccessLogFields].
+	Fields AccessLogField
+	// RequestHeaders is the allow-list of request headers to be logged, as "reqHeader.<Name>" fields.
+	RequestHeaders []string
+	// ResponseHeaders is the allow-list of response headers to be logged, as "respHeader.<Name>" fields.
+	ResponseHeaders []string
+	// RedactHeaders are the headers (like "Authorization") whose values are logged as "[REDACTED]".
+	RedactHeaders []string
+	// SuccessSampleRate is the fraction (0, 1) of successful (status < 400) requests to be logged.
+	// Client / server error responses and slow requests are always logged.
+	// Zero value (or a value >= 1) means no sampling is applied, all requests get logged.
+	SuccessSampleRate float64
+	// SlowThreshold, if set, marks requests which took longer as slow ("slow" field),
+	// such requests being always logged.
+	SlowThreshold time.Duration
+	// StatusLevels holds the log level per status class (1 for 1xx, 2 for 2xx, ..., 5 for 5xx).
+	// A missing class is logged with [AccessLevel].
+	StatusLevels map[int]slog.Level
+	// FieldNames allows renaming the fields emitted through the logger, like {"statusCode": "status"}.
+	// Headers fields' prefixes can be renamed through "reqHeader" / "respHeader" keys.
+	FieldNames map[string]string
+	// Formatter, if set, formats the entries which are written to Output instead of being logged
+	// through the logger. See [CommonLogFormatter], [ECSJSONFormatter], [SlogFormatter].
+	Formatter AccessLogFormatter
+	// BodyCapture, if set, enables request / response bodies capture, see [AccessLogBodyCapture].
+	// It should be used for debugging purposes only, as it impacts performance.
+	BodyCapture *AccessLogBodyCapture
+	// ClientIPResolver resolves the logged client ip. Defaults to [httpTransport.DefaultClientIPResolver],
+	// which trusts no proxy.
+	ClientIPResolver *httpTransport.ClientIPResolver
+	// Output is where formatted entries are written to. Defaults to [os.Stdout].
+	// Writes are serialized. Use [AccessLogFileWriter] for a buffered, rotation friendly, file output.
+	Output io.Writer
+}
+
+// AccessLog is a decorator/middleware that logs requests.
 func AccessLog(next http.Handler, logger *slog.Logger, callback AccessRequestCallbeck) http.Handler {
+	return AccessLogWithConfig(next, logger, AccessLogConfig{Callback: callback})
+}
+
+// AccessLogWithConfig is a decorator/middleware that logs requests,
+// configured through given config (sampling, levels, fields, format).
+// The logger is used to log the entries, unless a formatter is configured,
+// case in which it is used only to report output errors.
+func AccessLogWithConfig(next http.Handler, logger *slog.Logger, config AccessLogConfig) http.Handler {
+	if config.Fields == 0 {
+		config.Fields = DefaultAccessLogFields
+	}
+	output := config.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	var outputMu sync.Mutex
+	if config.BodyCapture != nil && config.BodyCapture.MaxBytes <= 0 {
+		bodyCapture := *config.BodyCapture
+		bodyCapture.MaxBytes = defaultAccessLogBodyMaxBytes
+		config.BodyCapture = &bodyCapture
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, origReq *http.Request) {
 		now := time.Now().UTC()
-		newW := &statusAwareResponseWriter{origW: w}
+		newW := NewResponseWriter(w)
+		origReq = origReq.WithContext(contextWithRoutePatternHolder(contextWithPrincipalHolder(origReq.Context())))
+		var bodyCounter *countingReadCloser
+		if config.Fields&AccessLogBytesIn != 0 && origReq.Body != nil && origReq.Body != http.NoBody {
+			bodyCounter = &countingReadCloser{ReadCloser: origReq.Body}
+			origReq.Body = bodyCounter
+		}
+		var reqBody, respBody *captureBuffer
+		if config.BodyCapture != nil {
+			respBody = &captureBuffer{max: config.BodyCapture.MaxBytes}
+			newW.tee = respBody
+			if origReq.Body != nil && origReq.Body != http.NoBody {
+				reqBody = &captureBuffer{max: config.BodyCapture.MaxBytes}
+				origReq.Body = newTeeReadCloser(origReq.Body, reqBody)
+			}
+		}
 		next.ServeHTTP(newW, origReq)
+		took := time.Since(now)
+
+		statusCode := newW.StatusCode()
+		slow := config.SlowThreshold > 0 && took >= config.SlowThreshold
+		if !slow && statusCode < http.StatusBadRequest &&
+			config.SuccessSampleRate > 0 && config.SuccessSampleRate < 1 &&
+			rand.Float64() >= config.SuccessSampleRate {
+			return // sampled out
+		}
 
 		r := origReq
-		if callback != nil {
+		if config.Callback != nil {
 			r = origReq.Clone(r.Context())
-			if !callback(r) {
+			if !config.Callback(r) {
 				return // skip logging for this request
 			}
 		}
 
-		logParams := make([]any, 0, 12*2)
-		logParams = append(logParams,
-			[]any{
-				"lvl", "ACCESS",
-				"method", r.Method,
-				"path", r.URL.Path,
-				"took", time.Since(now).String(),
-				"userAgent", r.Header.Get("User-Agent"),
-				"ip", httpTransport.GetClientIP(r).String(),
-				"statusCode", newW.StatusCode(),
-			}...,
-		)
-		if r.URL.RawQuery != "" {
-			logParams = append(logParams, "query", r.URL.RawQuery)
+		entry := AccessLogEntry{
+			Fields:            config.Fields,
+			Time:              now,
+			Method:            r.Method,
+			Path:              r.URL.Path,
+			Query:             r.URL.RawQuery,
+			Pattern:           routePattern(r),
+			Protocol:          r.Proto,
+			Took:              took,
+			Slow:              slow,
+			UserAgent:         r.Header.Get("User-Agent"),
+			Referer:           r.Header.Get("Referer"),
+			IP:                clientIP(config.ClientIPResolver, r),
+			StatusCode:        statusCode,
+			CorrelationID:     xtransport.CorrelationIDFromContext(r.Context()),
+			ReqContentLength:  parseContentLength(r.Header.Get("Content-Length")),
+			RespContentLength: parseContentLength(w.Header().Get("Content-Length")),
+			BytesOut:          newW.BytesWritten(),
 		}
-		correlationID := xtransport.CorrelationIDFromContext(r.Context())
-		if correlationID != "" {
-			logParams = append(logParams, "correlationId", correlationID)
+		if r.TLS != nil {
+			entry.TLSVersion = tls.VersionName(r.TLS.Version)
 		}
-		if r.URL.User.Username() != "" {
-			logParams = append(logParams, "authUsername", r.URL.User.Username())
+		if principal, found := PrincipalFromContext(r.Context()); found {
+			entry.AuthUsername = principal.Subject
+		} else {
+			entry.AuthUsername = r.URL.User.Username()
 		}
-		if r.Header.Get("Content-Length") != "" {
-			reqContentLen, _ := strconv.Atoi(r.Header.Get("Content-Length"))
-			logParams = append(logParams, "reqContentLength", reqContentLen)
+		if bodyCounter != nil {
+			entry.BytesIn = bodyCounter.n
 		}
-		if w.Header().Get("Content-Length") != "" {
-			respContentLen, _ := strconv.Atoi(w.Header().Get("Content-Length"))
-			logParams = append(logParams, "respContentLength", respContentLen)
-		} else {
-			logParams = append(logParams, "respBodyLength", newW.BodySize())
+		entry.RequestHeaders = accessLogHeaders(r.Header, config.RequestHeaders, config.RedactHeaders)
+		entry.ResponseHeaders = accessLogHeaders(w.Header(), config.ResponseHeaders, config.RedactHeaders)
+		if config.BodyCapture != nil && config.BodyCapture.matches(r, statusCode) {
+			if reqBody != nil {
+				entry.RequestBody = config.BodyCapture.format(reqBody, r.Header.Get("Content-Type"))
+			}
+			entry.ResponseBody = config.BodyCapture.format(respBody, w.Header().Get("Content-Type"))
+		}
+		var found bool
+		if entry.Level, found = config.StatusLevels[statusCode/100]; !found {
+			entry.Level = AccessLevel
 		}
 
-		logger.Log(r.Context(), AccessLevel, "access log", logParams...)
+		if config.Formatter == nil {
+			logger.Log(r.Context(), entry.Level, "access log", entry.slogParams(config.FieldNames)...)
+
+			return
+		}
+		buf := accessLogBufPool.Get().(*[]byte)
+		*buf = config.Formatter.Format((*buf)[:0], &entry)
+		outputMu.Lock()
+		_, err := output.Write(*buf)
+		outputMu.Unlock()
+		accessLogBufPool.Put(buf)
+		if err != nil {
+			logger.Error("could not write access log", "err", err)
+		}
 	})
 }
+
+// accessLogHeaders returns the values of given allow-listed headers, redacting the ones to be redacted.
+func accessLogHeaders(header http.Header, names, redactNames []string) []AccessLogHeader {
+	var headers []AccessLogHeader
+	for _, name := range names {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		name = http.CanonicalHeaderKey(name)
+		if slices.ContainsFunc(redactNames, func(redactName string) bool {
+			return strings.EqualFold(redactName, name)
+		}) {
+			value = accessLogRedacted
+		}
+		headers = append(headers, AccessLogHeader{name, value})
+	}
+
+	return headers
+}
+
+// parseContentLength parses a Content-Length header value, returning -1 if missing / invalid.
+func parseContentLength(value string) int64 {
+	if value == "" {
+		return -1
+	}
+	contentLength, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return contentLength
+}
+
+// countingReadCloser is an [io.ReadCloser] decorator which counts the bytes read.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read reads from the decorated reader, counting the bytes read.
+func (rc *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := rc.ReadCloser.Read(p)
+	rc.n += int64(n)
+
+	return n, err
+}
+
+// clientIP returns the client ip resolved by given resolver, or by [httpTransport.DefaultClientIPResolver]
+// if nil, or empty string if it could not be determined.
+func clientIP(resolver *httpTransport.ClientIPResolver, r *http.Request) string {
+	if resolver == nil {
+		resolver = httpTransport.DefaultClientIPResolver
+	}
+	if addr := resolver.ClientIP(r); addr.IsValid() {
+		return addr.String()
+	}
+
+	return ""
+}
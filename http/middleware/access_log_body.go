@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultAccessLogBodyMaxBytes = 4 * 1024 // 4Kb
+
+	// accessLogRedacted is the value which replaces redacted values.
+	accessLogRedacted = "[REDACTED]"
+	// accessLogTruncatedSuffix is appended to bodies exceeding the captured max size.
+	accessLogTruncatedSuffix = "...[truncated]"
+)
+
+// AccessLogBodyCapture configures the capture of request and response bodies by access log.
+// It is meant for debugging purposes, captured bodies being attached to the entry
+// as "reqBody" / "respBody" fields.
+//
+// Bodies are captured while they are read by the handler / written to the client,
+// thus request body limits set through [github.com/actforgood/xtransport/http.GetRequestBody] still apply:
+// only what the handler actually reads is captured.
+type AccessLogBodyCapture struct {
+	// MaxBytes is the max no. of bytes captured per body, the rest being marked as truncated.
+	// Defaults to 4Kb.
+	MaxBytes int
+	// Routes, if set, restricts the capture to requests matching one of them.
+	// A route is matched against the [http.ServeMux] pattern (like "POST /users/{id}"), and request's path.
+	Routes []string
+	// StatusCodes, if set, restricts the capture to responses with one of these status codes.
+	// A value between 1 and 5 represents a whole status class (4 for 4xx, 5 for 5xx).
+	StatusCodes []int
+	// RedactFields are the JSON / form fields whose values get replaced with "[REDACTED]".
+	// A value starting with "/" is a JSON pointer (RFC 6901) like "/card/number", where "*" matches
+	// any key / array index, like "/items/*/secret". Other values are field names matched,
+	// case insensitive, at any depth, like "password".
+	// A JSON / form body which cannot be parsed (it is malformed, or was truncated) is not logged
+	// if redact fields are configured, as it cannot be guaranteed it is free of sensitive data.
+	// For the same reason, bodies of other content types (like multipart/form-data) are not logged either,
+	// an "[omitted: <content type>]" placeholder being logged instead.
+	RedactFields []string
+}
+
+// matches returns whether bodies of given request / response status code should be attached to the entry.
+func (c *AccessLogBodyCapture) matches(r *http.Request, statusCode int) bool {
+	if len(c.Routes) > 0 && !slices.ContainsFunc(c.Routes, func(route string) bool {
+		return route == r.Pattern || route == r.URL.Path
+	}) {
+		return false
+	}
+	if len(c.StatusCodes) > 0 && !slices.ContainsFunc(c.StatusCodes, func(code int) bool {
+		return code == statusCode || code == statusCode/100
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// format returns the body as to be logged, redacted and truncated.
+func (c *AccessLogBodyCapture) format(body *captureBuffer, contentType string) string {
+	data := body.buf
+	if len(data) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if len(c.RedactFields) > 0 {
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			redacted, err := redactJSON(data, c.RedactFields)
+			if err != nil {
+				return "[not logged: body could not be redacted]"
+			}
+			data = redacted
+		case mediaType == "application/x-www-form-urlencoded":
+			redacted, err := redactForm(data, c.RedactFields)
+			if err != nil {
+				return "[not logged: body could not be redacted]"
+			}
+			data = redacted
+		case mediaType == "":
+			return "[omitted: unknown content type]"
+		default:
+			return "[omitted: " + mediaType + "]"
+		}
+	}
+	if !utf8.Valid(data) {
+		return "[binary body of " + strconv.Itoa(body.total) + " bytes]"
+	}
+	if body.truncated() {
+		return string(data) + accessLogTruncatedSuffix
+	}
+
+	return string(data)
+}
+
+// captureBuffer is an [io.Writer] which keeps the first max bytes written to it.
+type captureBuffer struct {
+	buf   []byte
+	max   int
+	total int
+}
+
+// Write records data, up to the max size.
+func (c *captureBuffer) Write(data []byte) (int, error) {
+	c.total += len(data)
+	if room := c.max - len(c.buf); room > 0 {
+		c.buf = append(c.buf, data[:min(room, len(data))]...)
+	}
+
+	return len(data), nil
+}
+
+func (c *captureBuffer) truncated() bool {
+	return c.total > len(c.buf)
+}
+
+// teeReadCloser is an [io.ReadCloser] which copies what is read to a writer.
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func newTeeReadCloser(rc io.ReadCloser, w io.Writer) teeReadCloser {
+	return teeReadCloser{Reader: io.TeeReader(rc, w), Closer: rc}
+}
+
+// redactJSON replaces the values of given fields inside a JSON document.
+func redactJSON(data []byte, fields []string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // preserve numbers as they are.
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, io.ErrUnexpectedEOF // not a single JSON value.
+	}
+
+	return json.Marshal(redactJSONValue(doc, nil, fields))
+}
+
+func redactJSONValue(value any, path []string, fields []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := append(path, key)
+			if isRedactedField(key, childPath, fields) {
+				v[key] = accessLogRedacted
+			} else {
+				v[key] = redactJSONValue(child, childPath, fields)
+			}
+		}
+	case []any:
+		for idx, child := range v {
+			v[idx] = redactJSONValue(child, append(path, strconv.Itoa(idx)), fields)
+		}
+	}
+
+	return value
+}
+
+// isRedactedField returns whether the field with given name, found at given path, should be redacted.
+func isRedactedField(name string, path []string, fields []string) bool {
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "/") {
+			if strings.EqualFold(field, name) {
+				return true
+			}
+
+			continue
+		}
+		if jsonPointerMatches(field, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jsonPointerMatches returns whether given JSON pointer (with "*" wildcards) designates given path.
+func jsonPointerMatches(pointer string, path []string) bool {
+	tokens := strings.Split(pointer[1:], "/")
+	if len(tokens) != len(path) {
+		return false
+	}
+	for idx, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if token != "*" && token != path[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// redactForm replaces the values of given fields inside an url encoded form.
+func redactForm(data []byte, fields []string) ([]byte, error) {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, err
+	}
+	for key := range values {
+		if isRedactedField(key, []string{key}, fields) {
+			for idx := range values[key] {
+				values[key][idx] = accessLogRedacted
+			}
+		}
+	}
+
+	return []byte(values.Encode()), nil
+}
@@ -0,0 +1,361 @@
+package middleware_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpTransport "github.com/actforgood/xtransport/http"
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestAccessLogBodyCapture(t *testing.T) {
+	t.Parallel()
+
+	t.Run("JSON bodies are captured and redacted", testAccessLogBodyCaptureJSON)
+	t.Run("form body is redacted", testAccessLogBodyCaptureForm)
+	t.Run("bodies are truncated", testAccessLogBodyCaptureTruncated)
+	t.Run("bodies which cannot be redacted are not logged", testAccessLogBodyCaptureNotRedactable)
+	t.Run("request body limit is respected", testAccessLogBodyCaptureRequestLimit)
+	t.Run("routes and status codes filters", testAccessLogBodyCaptureFilters)
+	t.Run("headers are redacted", testAccessLogRedactHeaders)
+}
+
+// echoHandler writes back, as JSON, the request body.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+})
+
+func testAccessLogBodyCaptureJSON(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			echoHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{
+				BodyCapture: &middleware.AccessLogBodyCapture{
+					RedactFields: []string{"Password", "/cards/*/number", "/amount"},
+				},
+			},
+		)
+		reqBody = `{"user":"john","password":"s3cr3t","cards":[{"number":"4111","type":"visa"}],` +
+			`"amount":10.50,"nested":{"amount":1,"password":"x"}}`
+		req = httptest.NewRequest(http.MethodPost, "http://example.com/orders", strings.NewReader(reqBody))
+	)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	expectedBody := `{"amount":"[REDACTED]","cards":[{"number":"[REDACTED]","type":"visa"}],` +
+		`"nested":{"amount":1,"password":"[REDACTED]"},"password":"[REDACTED]","user":"john"}`
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, expectedBody, loggerMock.ValueAt(1, "reqBody"))
+		assert.Equal(t, expectedBody, loggerMock.ValueAt(1, "respBody"))
+	}
+}
+
+func testAccessLogBodyCaptureForm(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			w.WriteHeader(http.StatusNoContent)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{
+				BodyCapture: &middleware.AccessLogBodyCapture{RedactFields: []string{"password"}},
+			},
+		)
+		req = httptest.NewRequest(
+			http.MethodPost,
+			"http://example.com/login",
+			strings.NewReader("username=john&password=s3cr3t"),
+		)
+	)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, "password=%5BREDACTED%5D&username=john", loggerMock.ValueAt(1, "reqBody"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "respBody"))
+	}
+}
+
+func testAccessLogBodyCaptureTruncated(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.Copy(w, r.Body)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{
+				BodyCapture: &middleware.AccessLogBodyCapture{MaxBytes: 5},
+			},
+		)
+		req = httptest.NewRequest(http.MethodPost, "http://example.com/echo", strings.NewReader(`{"password":"s3cr3t"}`))
+	)
+	req.Header.Set("Content-Type", "application/json")
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, `{"pas...[truncated]`, loggerMock.ValueAt(1, "reqBody"))
+		assert.Equal(t, `{"pas...[truncated]`, loggerMock.ValueAt(1, "respBody"))
+	}
+}
+
+func testAccessLogBodyCaptureNotRedactable(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name             string
+		reqContentType   string
+		reqBody          string
+		expectedReqBody  string
+		expectedRespBody string
+	}{
+		{
+			name:             "truncated JSON",
+			reqContentType:   "application/json",
+			reqBody:          `{"password":"s3cr3t","user":"john"}`,
+			expectedReqBody:  "[not logged: body could not be redacted]",
+			expectedRespBody: "[omitted: text/plain]",
+		},
+		{
+			name:           "multipart form",
+			reqContentType: "multipart/form-data; boundary=xyz",
+			reqBody: "--xyz\r\nContent-Disposition: form-data; name=\"password\"\r\n\r\n" +
+				"s3cr3t\r\n--xyz--\r\n",
+			expectedReqBody:  "[omitted: multipart/form-data]",
+			expectedRespBody: "[omitted: text/plain]",
+		},
+		{
+			name:             "missing content type",
+			reqBody:          "password=s3cr3t",
+			expectedReqBody:  "[omitted: unknown content type]",
+			expectedRespBody: "[omitted: text/plain]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					_, _ = io.Copy(w, r.Body)
+				})
+				loggerMock = mock.NewSlogHandler()
+				subject    = middleware.AccessLogWithConfig(
+					nextHandler,
+					slog.New(loggerMock),
+					middleware.AccessLogConfig{
+						BodyCapture: &middleware.AccessLogBodyCapture{
+							MaxBytes:     20,
+							RedactFields: []string{"password"},
+						},
+					},
+				)
+				req = httptest.NewRequest(http.MethodPost, "http://example.com/echo", strings.NewReader(test.reqBody))
+			)
+			if test.reqContentType != "" {
+				req.Header.Set("Content-Type", test.reqContentType)
+			}
+
+			// act
+			subject.ServeHTTP(httptest.NewRecorder(), req)
+
+			// assert
+			if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+				assert.Equal(t, test.expectedReqBody, loggerMock.ValueAt(1, "reqBody"))
+				assert.Equal(t, test.expectedRespBody, loggerMock.ValueAt(1, "respBody"))
+			}
+		})
+	}
+}
+
+func testAccessLogBodyCaptureRequestLimit(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.ReadAll(httpTransport.GetRequestBody(w, r, 4))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{BodyCapture: &middleware.AccessLogBodyCapture{}},
+		)
+		req = httptest.NewRequest(http.MethodPost, "http://example.com/upload", strings.NewReader("0123456789"))
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		body, _ := loggerMock.ValueAt(1, "reqBody").(string)
+		assert.True(t, len(body) <= 5) // at most limit + 1 bytes are read by max bytes reader.
+		assert.True(t, strings.HasPrefix("0123456789", body))
+	}
+}
+
+func testAccessLogBodyCaptureFilters(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name          string
+		path          string
+		statusCode    int
+		expectCapture bool
+	}{
+		{
+			name:          "matching pattern and status class",
+			path:          "/users/123",
+			statusCode:    http.StatusBadRequest,
+			expectCapture: true,
+		},
+		{
+			name:          "matching path and status code",
+			path:          "/health",
+			statusCode:    http.StatusServiceUnavailable,
+			expectCapture: true,
+		},
+		{
+			name:          "not matching status code",
+			path:          "/users/123",
+			statusCode:    http.StatusOK,
+			expectCapture: false,
+		},
+		{
+			name:          "not matching route",
+			path:          "/orders/123",
+			statusCode:    http.StatusBadRequest,
+			expectCapture: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				mux        = http.NewServeMux()
+				loggerMock = mock.NewSlogHandler()
+				subject    = middleware.AccessLogWithConfig(
+					mux,
+					slog.New(loggerMock),
+					middleware.AccessLogConfig{
+						BodyCapture: &middleware.AccessLogBodyCapture{
+							Routes:      []string{"POST /users/{id}", "/health"},
+							StatusCodes: []int{4, http.StatusServiceUnavailable},
+						},
+					},
+				)
+				req = httptest.NewRequest(http.MethodPost, "http://example.com"+test.path, strings.NewReader("req"))
+			)
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte("resp"))
+			})
+			mux.HandleFunc("POST /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte("resp"))
+			})
+
+			// act
+			subject.ServeHTTP(httptest.NewRecorder(), req)
+
+			// assert
+			if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+				if test.expectCapture {
+					assert.Equal(t, "req", loggerMock.ValueAt(1, "reqBody"))
+					assert.Equal(t, "resp", loggerMock.ValueAt(1, "respBody"))
+				} else {
+					assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "reqBody"))
+					assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "respBody"))
+				}
+			}
+		})
+	}
+}
+
+func testAccessLogRedactHeaders(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Set-Cookie", "session=abc")
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{
+				RequestHeaders:  []string{"Authorization", "X-Request-Source"},
+				ResponseHeaders: []string{"Set-Cookie"},
+				RedactHeaders:   []string{"authorization", "Set-Cookie"},
+			},
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	)
+	req.Header.Set("Authorization", "Bearer xyz")
+	req.Header.Set("X-Request-Source", "mobile")
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, "[REDACTED]", loggerMock.ValueAt(1, "reqHeader.Authorization"))
+		assert.Equal(t, "mobile", loggerMock.ValueAt(1, "reqHeader.X-Request-Source"))
+		assert.Equal(t, "[REDACTED]", loggerMock.ValueAt(1, "respHeader.Set-Cookie"))
+	}
+}
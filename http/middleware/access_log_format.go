@@ -0,0 +1,451 @@
+package middleware
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessLogHeader is a header logged by access log.
+type AccessLogHeader struct {
+	// Name is the canonical header name.
+	Name string
+	// Value is the header value.
+	Value string
+}
+
+// AccessLogEntry holds information about a request, to be logged by access log.
+type AccessLogEntry struct {
+	// Fields are the fields to be emitted.
+	// Some formats, like Common Log Format, have a fixed field set and ignore it.
+	Fields AccessLogField
+	// Time is the moment the request was received.
+	Time time.Time
+	// Level is the level corresponding to response's status class.
+	Level slog.Level
+	// Method is the request method.
+	Method string
+	// Path is the request path.
+	Path string
+	// Query is the request raw query.
+	Query string
+	// Pattern is the matched [http.ServeMux] pattern, if any.
+	Pattern string
+	// Protocol is the request protocol, like "HTTP/1.1".
+	Protocol string
+	// TLSVersion is the TLS version, like "TLS 1.3", if the connection is secured.
+	TLSVersion string
+	// Took is the request processing duration.
+	Took time.Duration
+	// Slow indicates whether the request took longer than the configured threshold.
+	Slow bool
+	// UserAgent is the request User-Agent header.
+	UserAgent string
+	// Referer is the request Referer header.
+	Referer string
+	// IP is the client ip.
+	IP string
+	// StatusCode is the response status code.
+	StatusCode int
+	// CorrelationID is the request correlation id, if any.
+	CorrelationID string
+	// AuthUsername is the authenticated principal's subject, if any.
+	AuthUsername string
+	// ReqContentLength is the request Content-Length header, or -1 if missing.
+	ReqContentLength int64
+	// RespContentLength is the response Content-Length header, or -1 if missing.
+	RespContentLength int64
+	// BytesIn is the no. of request body bytes read.
+	BytesIn int64
+	// BytesOut is the no. of response body bytes written.
+	BytesOut int64
+	// RequestHeaders are the allow-listed request headers.
+	RequestHeaders []AccessLogHeader
+	// ResponseHeaders are the allow-listed response headers.
+	ResponseHeaders []AccessLogHeader
+	// RequestBody is the captured, redacted, request body, if body capture is enabled.
+	RequestBody string
+	// ResponseBody is the captured, redacted, response body, if body capture is enabled.
+	ResponseBody string
+}
+
+// Has returns whether given field should be emitted.
+func (entry *AccessLogEntry) Has(field AccessLogField) bool {
+	return entry.Fields&field != 0
+}
+
+// slogParams returns the entry as slog key-value pairs, keys being renamed with given names.
+func (entry *AccessLogEntry) slogParams(names map[string]string) []any {
+	name := func(key string) string {
+		if newName, found := names[key]; found {
+			return newName
+		}
+
+		return key
+	}
+
+	logParams := make([]any, 0, 20*2)
+	logParams = append(logParams, name("lvl"), "ACCESS")
+	if entry.Has(AccessLogMethod) {
+		logParams = append(logParams, name("method"), entry.Method)
+	}
+	if entry.Has(AccessLogPath) {
+		logParams = append(logParams, name("path"), entry.Path)
+	}
+	if entry.Has(AccessLogPattern) && entry.Pattern != "" {
+		logParams = append(logParams, name("pattern"), entry.Pattern)
+	}
+	if entry.Has(AccessLogTook) {
+		logParams = append(logParams, name("took"), entry.Took.String())
+	}
+	if entry.Slow {
+		logParams = append(logParams, name("slow"), true)
+	}
+	if entry.Has(AccessLogUserAgent) {
+		logParams = append(logParams, name("userAgent"), entry.UserAgent)
+	}
+	if entry.Has(AccessLogIP) {
+		logParams = append(logParams, name("ip"), entry.IP)
+	}
+	if entry.Has(AccessLogStatusCode) {
+		logParams = append(logParams, name("statusCode"), entry.StatusCode)
+	}
+	if entry.Has(AccessLogQuery) && entry.Query != "" {
+		logParams = append(logParams, name("query"), entry.Query)
+	}
+	if entry.Has(AccessLogProtocol) {
+		logParams = append(logParams, name("protocol"), entry.Protocol)
+	}
+	if entry.Has(AccessLogTLSVersion) && entry.TLSVersion != "" {
+		logParams = append(logParams, name("tlsVersion"), entry.TLSVersion)
+	}
+	if entry.Has(AccessLogCorrelationID) && entry.CorrelationID != "" {
+		logParams = append(logParams, name("correlationId"), entry.CorrelationID)
+	}
+	if entry.Has(AccessLogAuthUsername) && entry.AuthUsername != "" {
+		logParams = append(logParams, name("authUsername"), entry.AuthUsername)
+	}
+	if entry.Has(AccessLogContentLength) {
+		if entry.ReqContentLength >= 0 {
+			logParams = append(logParams, name("reqContentLength"), entry.ReqContentLength)
+		}
+		if entry.RespContentLength >= 0 {
+			logParams = append(logParams, name("respContentLength"), entry.RespContentLength)
+		} else {
+			logParams = append(logParams, name("respBodyLength"), entry.BytesOut)
+		}
+	}
+	if entry.Has(AccessLogBytesIn) {
+		logParams = append(logParams, name("bytesIn"), entry.BytesIn)
+	}
+	if entry.Has(AccessLogBytesOut) {
+		logParams = append(logParams, name("bytesOut"), entry.BytesOut)
+	}
+	for _, header := range entry.RequestHeaders {
+		logParams = append(logParams, name("reqHeader")+"."+header.Name, header.Value)
+	}
+	for _, header := range entry.ResponseHeaders {
+		logParams = append(logParams, name("respHeader")+"."+header.Name, header.Value)
+	}
+	if entry.RequestBody != "" {
+		logParams = append(logParams, name("reqBody"), entry.RequestBody)
+	}
+	if entry.ResponseBody != "" {
+		logParams = append(logParams, name("respBody"), entry.ResponseBody)
+	}
+
+	return logParams
+}
+
+// AccessLogFormatter is the contract for an access log entries formatter.
+type AccessLogFormatter interface {
+	// Format appends the formatted entry, followed by a new line, to buf,
+	// returning the extended buffer.
+	Format(buf []byte, entry *AccessLogEntry) []byte
+}
+
+var accessLogBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 1024)
+
+		return &buf
+	},
+}
+
+// CommonLogFormatter is an [AccessLogFormatter] producing NCSA Common Log Format lines:
+//
+//	127.0.0.1 - john [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+//
+// or Combined Log Format lines, which additionally contain the referer and the user agent:
+//
+//	127.0.0.1 - john [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "http://x.org/" "Mozilla/5.0"
+//
+// Entry's Fields are ignored, as the formats have a fixed field set.
+type CommonLogFormatter struct {
+	// Combined indicates whether Combined Log Format is used.
+	Combined bool
+}
+
+// Format ...see [AccessLogFormatter.Format].
+func (f CommonLogFormatter) Format(buf []byte, entry *AccessLogEntry) []byte {
+	buf = appendCLFValue(buf, entry.IP)
+	buf = append(buf, " - "...)
+	buf = appendCLFValue(buf, entry.AuthUsername)
+	buf = append(buf, " ["...)
+	buf = entry.Time.AppendFormat(buf, "02/Jan/2006:15:04:05 -0700")
+	buf = append(buf, `] "`...)
+	requestURI := entry.Path
+	if entry.Query != "" {
+		requestURI += "?" + entry.Query
+	}
+	buf = appendCLFQuoted(buf, entry.Method+" "+requestURI+" "+entry.Protocol)
+	buf = append(buf, `" `...)
+	buf = strconv.AppendInt(buf, int64(entry.StatusCode), 10)
+	buf = append(buf, ' ')
+	if entry.BytesOut > 0 {
+		buf = strconv.AppendInt(buf, entry.BytesOut, 10)
+	} else {
+		buf = append(buf, '-')
+	}
+	if f.Combined {
+		buf = append(buf, ` "`...)
+		buf = appendCLFQuoted(buf, cmp.Or(entry.Referer, "-"))
+		buf = append(buf, `" "`...)
+		buf = appendCLFQuoted(buf, cmp.Or(entry.UserAgent, "-"))
+		buf = append(buf, '"')
+	}
+
+	return append(buf, '\n')
+}
+
+// appendCLFValue appends an unquoted field value, escaping spaces as "%20", and control characters.
+func appendCLFValue(buf []byte, value string) []byte {
+	if value == "" {
+		return append(buf, '-')
+	}
+	for i := range len(value) {
+		switch c := value[i]; {
+		case c == ' ':
+			buf = append(buf, "%20"...)
+		case isCLFControl(c):
+			buf = appendCLFHexEscaped(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	return buf
+}
+
+// appendCLFQuoted appends a quoted field value, escaping quotes, backslashes, and control characters.
+func appendCLFQuoted(buf []byte, value string) []byte {
+	for i := range len(value) {
+		switch c := value[i]; {
+		case c == '"', c == '\\':
+			buf = append(buf, '\\', c)
+		case isCLFControl(c):
+			buf = appendCLFHexEscaped(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	return buf
+}
+
+// isCLFControl returns true for control characters, which could be used for log injection.
+func isCLFControl(c byte) bool {
+	return c < 0x20 || c == 0x7f
+}
+
+// appendCLFHexEscaped appends given byte escaped like "\xhh", as Apache does.
+func appendCLFHexEscaped(buf []byte, c byte) []byte {
+	const hexDigits = "0123456789abcdef"
+
+	return append(buf, '\\', 'x', hexDigits[c>>4], hexDigits[c&0x0f])
+}
+
+// ecsLogLevel returns the ECS name of given level, [AccessLevel] being mapped to "info".
+func ecsLogLevel(level slog.Level) string {
+	switch {
+	case level == AccessLevel:
+		return "info"
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// ECSJSONFormatter is an [AccessLogFormatter] producing Elastic Common Schema compatible JSON lines,
+// like:
+//
+//	{"@timestamp":"2024-05-10T12:00:00Z","log.level":"info","message":"access log","http.request.method":"GET",...}
+//
+// Fields are emitted with dotted ECS names ("url.path", "http.response.status_code", etc.).
+type ECSJSONFormatter struct {
+	// FieldNames allows renaming the ECS fields, like {"http.request.id": "trace.id"}.
+	FieldNames map[string]string
+}
+
+// Format ...see [AccessLogFormatter.Format].
+func (f ECSJSONFormatter) Format(buf []byte, entry *AccessLogEntry) []byte {
+	enc := jsonLineEncoder{buf: append(buf, '{'), names: f.FieldNames}
+	enc.addString("@timestamp", entry.Time.Format(time.RFC3339Nano))
+	enc.addString("log.level", ecsLogLevel(entry.Level))
+	enc.addString("message", "access log")
+	if entry.Has(AccessLogMethod) {
+		enc.addString("http.request.method", entry.Method)
+	}
+	if entry.Has(AccessLogPath) {
+		enc.addString("url.path", entry.Path)
+	}
+	if entry.Has(AccessLogQuery) && entry.Query != "" {
+		enc.addString("url.query", entry.Query)
+	}
+	if entry.Has(AccessLogPattern) && entry.Pattern != "" {
+		enc.addString("http.route", entry.Pattern)
+	}
+	if entry.Has(AccessLogTook) {
+		enc.addInt("event.duration", entry.Took.Nanoseconds())
+	}
+	if entry.Slow {
+		enc.addRaw("labels.slow", "true")
+	}
+	if entry.Has(AccessLogUserAgent) && entry.UserAgent != "" {
+		enc.addString("user_agent.original", entry.UserAgent)
+	}
+	if entry.Has(AccessLogIP) && entry.IP != "" {
+		enc.addString("client.ip", entry.IP)
+	}
+	if entry.Has(AccessLogStatusCode) {
+		enc.addInt("http.response.status_code", int64(entry.StatusCode))
+	}
+	if entry.Has(AccessLogProtocol) && entry.Protocol != "" {
+		enc.addString("http.version", strings.TrimPrefix(entry.Protocol, "HTTP/"))
+	}
+	if entry.Has(AccessLogTLSVersion) && entry.TLSVersion != "" {
+		enc.addString("tls.version_protocol", "tls")
+		enc.addString("tls.version", strings.TrimPrefix(entry.TLSVersion, "TLS "))
+	}
+	if entry.Has(AccessLogCorrelationID) && entry.CorrelationID != "" {
+		enc.addString("http.request.id", entry.CorrelationID)
+	}
+	if entry.Has(AccessLogAuthUsername) && entry.AuthUsername != "" {
+		enc.addString("user.name", entry.AuthUsername)
+	}
+	if entry.Has(AccessLogBytesIn) {
+		enc.addInt("http.request.body.bytes", entry.BytesIn)
+	} else if entry.Has(AccessLogContentLength) && entry.ReqContentLength >= 0 {
+		enc.addInt("http.request.body.bytes", entry.ReqContentLength)
+	}
+	if entry.Has(AccessLogBytesOut) || entry.Has(AccessLogContentLength) {
+		enc.addInt("http.response.body.bytes", entry.BytesOut)
+	}
+	if entry.Referer != "" {
+		enc.addString("http.request.referrer", entry.Referer)
+	}
+	for _, header := range entry.RequestHeaders {
+		enc.addString("http.request.headers."+strings.ToLower(header.Name), header.Value)
+	}
+	for _, header := range entry.ResponseHeaders {
+		enc.addString("http.response.headers."+strings.ToLower(header.Name), header.Value)
+	}
+	if entry.RequestBody != "" {
+		enc.addString("http.request.body.content", entry.RequestBody)
+	}
+	if entry.ResponseBody != "" {
+		enc.addString("http.response.body.content", entry.ResponseBody)
+	}
+
+	return append(enc.buf, '}', '\n')
+}
+
+// jsonLineEncoder appends JSON object members to a buffer.
+type jsonLineEncoder struct {
+	buf     []byte
+	names   map[string]string
+	hasKeys bool
+}
+
+func (enc *jsonLineEncoder) addKey(key string) {
+	if newName, found := enc.names[key]; found {
+		key = newName
+	}
+	if enc.hasKeys {
+		enc.buf = append(enc.buf, ',')
+	}
+	enc.hasKeys = true
+	enc.buf = appendJSONString(enc.buf, key)
+	enc.buf = append(enc.buf, ':')
+}
+
+func (enc *jsonLineEncoder) addString(key, value string) {
+	enc.addKey(key)
+	enc.buf = appendJSONString(enc.buf, value)
+}
+
+func (enc *jsonLineEncoder) addInt(key string, value int64) {
+	enc.addKey(key)
+	enc.buf = strconv.AppendInt(enc.buf, value, 10)
+}
+
+func (enc *jsonLineEncoder) addRaw(key, value string) {
+	enc.addKey(key)
+	enc.buf = append(enc.buf, value...)
+}
+
+func appendJSONString(buf []byte, value string) []byte {
+	encoded, _ := json.Marshal(value) // cannot fail for strings.
+
+	return append(buf, encoded...)
+}
+
+// SlogFormatter is an [AccessLogFormatter] producing lines in the same style
+// (same keys) as the access log emits through a logger, using [slog.TextHandler],
+// or [slog.JSONHandler].
+type SlogFormatter struct {
+	// JSON indicates whether JSON output is produced, instead of text (key=value).
+	JSON bool
+	// FieldNames allows renaming the fields, see [AccessLogConfig].FieldNames.
+	FieldNames map[string]string
+}
+
+// Format ...see [AccessLogFormatter.Format].
+func (f SlogFormatter) Format(buf []byte, entry *AccessLogEntry) []byte {
+	var (
+		out  = bytes.NewBuffer(buf)
+		opts = &slog.HandlerOptions{
+			Level: slog.Level(math.MinInt),
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.LevelKey {
+					return slog.Attr{} // level is already present as "lvl" field.
+				}
+
+				return a
+			},
+		}
+		handler slog.Handler
+	)
+	if f.JSON {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
+	record := slog.NewRecord(entry.Time, entry.Level, "access log", 0)
+	record.Add(entry.slogParams(f.FieldNames)...)
+	_ = handler.Handle(context.Background(), record)
+
+	return out.Bytes()
+}
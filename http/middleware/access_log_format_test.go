@@ -0,0 +1,271 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport"
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestAccessLogFormatters(t *testing.T) {
+	t.Parallel()
+
+	t.Run("common log format", testAccessLogFormatterCommon)
+	t.Run("combined log format", testAccessLogFormatterCombined)
+	t.Run("common log format escapes control characters", testAccessLogFormatterCommonEscaping)
+	t.Run("ECS JSON", testAccessLogFormatterECSJSON)
+	t.Run("ECS JSON log levels", testAccessLogFormatterECSJSONLevels)
+	t.Run("slog text", testAccessLogFormatterSlogText)
+	t.Run("slog JSON with custom field names", testAccessLogFormatterSlogJSON)
+	t.Run("custom field names through logger", testAccessLogFieldNames)
+}
+
+// serveFormattedAccessLog serves a request through access log with given formatter, returning the output.
+func serveFormattedAccessLog(formatter middleware.AccessLogFormatter) string {
+	var (
+		output      bytes.Buffer
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":123}`))
+		})
+		subject = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(mock.NewSlogHandler()),
+			middleware.AccessLogConfig{
+				Formatter:      formatter,
+				Output:         &output,
+				Fields:         middleware.DefaultAccessLogFields | middleware.AccessLogProtocol,
+				RequestHeaders: []string{"X-Request-Source"},
+			},
+		)
+		req = httptest.NewRequest(http.MethodPost, "http://example.com/items?dryRun=1", nil)
+	)
+	req.Header.Set("User-Agent", `Test "Agent"`)
+	req.Header.Set("Referer", "http://example.com/form")
+	req.Header.Set("X-Request-Source", "mobile")
+	req.SetBasicAuth("john", "s3cr3t")
+	req.URL.User = nil
+	req = req.WithContext(middleware.ContextWithPrincipal(
+		xtransport.ContextWithCorrelationID(req.Context(), "abcd-1234"),
+		middleware.Principal{Subject: "john", Method: "basic"},
+	))
+
+	subject.ServeHTTP(httptest.NewRecorder(), req)
+
+	return output.String()
+}
+
+func testAccessLogFormatterCommon(t *testing.T) {
+	t.Parallel()
+
+	// act
+	result := serveFormattedAccessLog(middleware.CommonLogFormatter{})
+
+	// assert
+	assert.True(t, regexp.MustCompile(
+		`^192\.0\.2\.1 - john \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /items\?dryRun=1 HTTP/1\.1" 201 10\n$`,
+	).MatchString(result))
+}
+
+func testAccessLogFormatterCombined(t *testing.T) {
+	t.Parallel()
+
+	// act
+	result := serveFormattedAccessLog(middleware.CommonLogFormatter{Combined: true})
+
+	// assert
+	assert.True(t, strings.HasSuffix(
+		result,
+		`"POST /items?dryRun=1 HTTP/1.1" 201 10 "http://example.com/form" "Test \"Agent\""`+"\n",
+	))
+}
+
+func testAccessLogFormatterCommonEscaping(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		output  bytes.Buffer
+		subject = middleware.AccessLogWithConfig(
+			http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+			slog.New(mock.NewSlogHandler()),
+			middleware.AccessLogConfig{
+				Formatter: middleware.CommonLogFormatter{Combined: true},
+				Output:    &output,
+			},
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/items%0D%0A127.0.0.1", nil)
+	)
+	req.Header.Set("User-Agent", "Agent\x7f\t\\")
+	req.SetBasicAuth("jo hn\r", "s3cr3t")
+	req.URL.User = nil
+	req = req.WithContext(middleware.ContextWithPrincipal(
+		req.Context(),
+		middleware.Principal{Subject: "jo hn\r", Method: "basic"},
+	))
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	result := output.String()
+	assert.Equal(t, 1, strings.Count(result, "\n"))
+	assert.True(t, strings.Contains(result, ` - jo%20hn\x0d [`))
+	assert.True(t, strings.Contains(result, `"GET /items\x0d\x0a127.0.0.1 HTTP/1.1" 200 -`))
+	assert.True(t, strings.HasSuffix(result, `"Agent\x7f\x09\\"`+"\n"))
+}
+
+func testAccessLogFormatterECSJSON(t *testing.T) {
+	t.Parallel()
+
+	// act
+	result := serveFormattedAccessLog(middleware.ECSJSONFormatter{
+		FieldNames: map[string]string{"http.request.id": "trace.id"},
+	})
+
+	// assert
+	assert.True(t, strings.HasSuffix(result, "}\n"))
+	var doc map[string]any
+	assert.RequireNil(t, json.Unmarshal([]byte(result), &doc))
+	assert.Equal(t, "POST", doc["http.request.method"])
+	assert.Equal(t, "/items", doc["url.path"])
+	assert.Equal(t, "dryRun=1", doc["url.query"])
+	assert.Equal(t, float64(http.StatusCreated), doc["http.response.status_code"])
+	assert.Equal(t, float64(10), doc["http.response.body.bytes"])
+	assert.Equal(t, "192.0.2.1", doc["client.ip"])
+	assert.Equal(t, `Test "Agent"`, doc["user_agent.original"])
+	assert.Equal(t, "john", doc["user.name"])
+	assert.Equal(t, "abcd-1234", doc["trace.id"])
+	assert.Equal(t, nil, doc["http.request.id"])
+	assert.Equal(t, "1.1", doc["http.version"])
+	assert.Equal(t, "mobile", doc["http.request.headers.x-request-source"])
+	assert.Equal(t, "access log", doc["message"])
+	assert.Equal(t, "info", doc["log.level"])
+	_, hasTimestamp := doc["@timestamp"]
+	assert.True(t, hasTimestamp)
+	_, hasDuration := doc["event.duration"]
+	assert.True(t, hasDuration)
+}
+
+func testAccessLogFormatterECSJSONLevels(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name          string
+		level         slog.Level
+		expectedLevel string
+	}{
+		{name: "access level", level: middleware.AccessLevel, expectedLevel: "info"},
+		{name: "debug", level: slog.LevelDebug, expectedLevel: "debug"},
+		{name: "info", level: slog.LevelInfo, expectedLevel: "info"},
+		{name: "warn", level: slog.LevelWarn, expectedLevel: "warn"},
+		{name: "error", level: slog.LevelError, expectedLevel: "error"},
+		{name: "above error", level: slog.LevelError + 2, expectedLevel: "error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				output  bytes.Buffer
+				subject = middleware.AccessLogWithConfig(
+					http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+					slog.New(mock.NewSlogHandler()),
+					middleware.AccessLogConfig{
+						Formatter:    middleware.ECSJSONFormatter{},
+						Output:       &output,
+						StatusLevels: map[int]slog.Level{2: test.level},
+					},
+				)
+			)
+
+			// act
+			subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+
+			// assert
+			var doc map[string]any
+			assert.RequireNil(t, json.Unmarshal(output.Bytes(), &doc))
+			assert.Equal(t, test.expectedLevel, doc["log.level"])
+		})
+	}
+}
+
+func testAccessLogFormatterSlogText(t *testing.T) {
+	t.Parallel()
+
+	// act
+	result := serveFormattedAccessLog(middleware.SlogFormatter{})
+
+	// assert
+	assert.True(t, strings.HasSuffix(result, "\n"))
+	for _, expected := range [...]string{
+		`msg="access log"`,
+		"lvl=ACCESS",
+		"method=POST",
+		"path=/items",
+		"statusCode=201",
+		`query="dryRun=1"`,
+		"authUsername=john",
+		"correlationId=abcd-1234",
+		"respBodyLength=10",
+		"reqHeader.X-Request-Source=mobile",
+	} {
+		assert.True(t, strings.Contains(result, expected))
+	}
+}
+
+func testAccessLogFormatterSlogJSON(t *testing.T) {
+	t.Parallel()
+
+	// act
+	result := serveFormattedAccessLog(middleware.SlogFormatter{
+		JSON:       true,
+		FieldNames: map[string]string{"statusCode": "status", "reqHeader": "header"},
+	})
+
+	// assert
+	var doc map[string]any
+	assert.RequireNil(t, json.Unmarshal([]byte(result), &doc))
+	assert.Equal(t, float64(http.StatusCreated), doc["status"])
+	assert.Equal(t, nil, doc["statusCode"])
+	assert.Equal(t, "mobile", doc["header.X-Request-Source"])
+	assert.Equal(t, "POST", doc["method"])
+}
+
+func testAccessLogFieldNames(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{FieldNames: map[string]string{"statusCode": "http.status", "path": "url"}},
+		)
+	)
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/jobs", nil))
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, int64(http.StatusAccepted), loggerMock.ValueAt(1, "http.status"))
+		assert.Equal(t, "/jobs", loggerMock.ValueAt(1, "url"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "statusCode"))
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/actforgood/xtransport"
+	httpTransport "github.com/actforgood/xtransport/http"
 	"github.com/actforgood/xtransport/http/middleware"
 	"github.com/actforgood/xtransport/testing/assert"
 	"github.com/actforgood/xtransport/testing/mock"
@@ -25,6 +28,17 @@ func TestAccessLog(t *testing.T) {
 	t.Run("check path value is obfuscated", testAccessLogObfuscatePathValue)
 }
 
+func TestAccessLogWithConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successful requests are sampled", testAccessLogWithConfigSampling)
+	t.Run("slow requests are always logged", testAccessLogWithConfigSlow)
+	t.Run("level per status class", testAccessLogWithConfigStatusLevels)
+	t.Run("custom fields", testAccessLogWithConfigFields)
+	t.Run("client ip resolver", testAccessLogWithConfigClientIPResolver)
+	t.Run("route pattern is passed back through middlewares", testAccessLogWithConfigRoutePattern)
+}
+
 func testAccessLogBasic(t *testing.T) {
 	t.Parallel()
 
@@ -206,3 +220,210 @@ func testAccessLogObfuscatePathValue(t *testing.T) {
 		}
 	}
 }
+
+func testAccessLogWithConfigSampling(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{SuccessSampleRate: 1e-9},
+		)
+	)
+
+	// act
+	for _, path := range [...]string{"/ok", "/ok", "/ok", "/missing", "/fail", "/ok"} {
+		subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil))
+	}
+
+	// assert
+	if assert.Equal(t, 2, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, int64(http.StatusNotFound), loggerMock.ValueAt(1, "statusCode"))
+		assert.Equal(t, int64(http.StatusInternalServerError), loggerMock.ValueAt(2, "statusCode"))
+	}
+}
+
+func testAccessLogWithConfigSlow(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(20 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{SuccessSampleRate: 1e-9, SlowThreshold: 10 * time.Millisecond},
+		)
+	)
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/fast", nil))
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/slow", nil))
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, "/slow", loggerMock.ValueAt(1, "path"))
+		assert.Equal(t, true, loggerMock.ValueAt(1, "slow"))
+	}
+}
+
+func testAccessLogWithConfigStatusLevels(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			statusCode, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+			w.WriteHeader(statusCode)
+		})
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLogWithConfig(
+			nextHandler,
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{StatusLevels: map[int]slog.Level{
+				2: slog.LevelInfo,
+				4: slog.LevelWarn,
+				5: slog.LevelError,
+			}},
+		)
+	)
+
+	// act
+	for _, path := range [...]string{"/200", "/204", "/302", "/400", "/503"} {
+		subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil))
+	}
+
+	// assert
+	assert.Equal(t, 2, loggerMock.LogCallsCount(slog.LevelInfo))
+	assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel))
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelWarn))
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func testAccessLogWithConfigFields(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			w.Header().Set("X-Cache", "HIT")
+			w.Header().Set("X-Secret", "s3cr3t")
+			_, _ = w.Write([]byte("created"))
+		})
+		loggerMock = mock.NewSlogHandler()
+		mux        = http.NewServeMux()
+		req        = httptest.NewRequest(http.MethodPost, "https://example.com/items/123", strings.NewReader("payload"))
+		w          = httptest.NewRecorder()
+	)
+	mux.Handle("POST /items/{id}", middleware.AccessLogWithConfig(
+		nextHandler,
+		slog.New(loggerMock),
+		middleware.AccessLogConfig{
+			Fields: middleware.AccessLogMethod | middleware.AccessLogStatusCode | middleware.AccessLogPattern |
+				middleware.AccessLogProtocol | middleware.AccessLogTLSVersion |
+				middleware.AccessLogBytesIn | middleware.AccessLogBytesOut,
+			RequestHeaders:  []string{"x-request-source", "Authorization"},
+			ResponseHeaders: []string{"X-Cache"},
+		},
+	))
+	req.Header.Set("X-Request-Source", "mobile")
+	req.Header.Set("User-Agent", "TestAccessLog/1.5")
+
+	// act
+	mux.ServeHTTP(w, req)
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, http.MethodPost, loggerMock.ValueAt(1, "method"))
+		assert.Equal(t, int64(http.StatusOK), loggerMock.ValueAt(1, "statusCode"))
+		assert.Equal(t, "POST /items/{id}", loggerMock.ValueAt(1, "pattern"))
+		assert.Equal(t, "HTTP/1.1", loggerMock.ValueAt(1, "protocol"))
+		assert.Equal(t, "TLS 1.2", loggerMock.ValueAt(1, "tlsVersion"))
+		assert.Equal(t, int64(len("payload")), loggerMock.ValueAt(1, "bytesIn"))
+		assert.Equal(t, int64(len("created")), loggerMock.ValueAt(1, "bytesOut"))
+		assert.Equal(t, "mobile", loggerMock.ValueAt(1, "reqHeader.X-Request-Source"))
+		assert.Equal(t, "HIT", loggerMock.ValueAt(1, "respHeader.X-Cache"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "respHeader.X-Secret"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "reqHeader.Authorization"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "path"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "userAgent"))
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "took"))
+	}
+}
+
+func testAccessLogWithConfigClientIPResolver(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		loggerMock     = mock.NewSlogHandler()
+		logger         = slog.New(loggerMock)
+		trustedProxies = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+		defaultSubject = middleware.AccessLogWithConfig(nextHandler, logger, middleware.AccessLogConfig{})
+		proxiedSubject = middleware.AccessLogWithConfig(nextHandler, logger, middleware.AccessLogConfig{
+			ClientIPResolver: httpTransport.NewClientIPResolver(trustedProxies...),
+		})
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	)
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 8.8.8.8")
+
+	// act
+	defaultSubject.ServeHTTP(httptest.NewRecorder(), req)
+	proxiedSubject.ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	if assert.Equal(t, 2, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, "192.0.2.1", loggerMock.ValueAt(1, "ip"))
+		assert.Equal(t, "8.8.8.8", loggerMock.ValueAt(2, "ip"))
+	}
+}
+
+func testAccessLogWithConfigRoutePattern(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		loggerMock = mock.NewSlogHandler()
+		mux        = http.NewServeMux()
+		subject    = middleware.AccessLogWithConfig(
+			middleware.CorrelationID(middleware.RoutePattern(mux), func() string { return "abcd-1234" }),
+			slog.New(loggerMock),
+			middleware.AccessLogConfig{Fields: middleware.AccessLogPattern},
+		)
+	)
+	mux.HandleFunc("GET /items/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/items/123", nil))
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, "GET /items/{id}", loggerMock.ValueAt(1, "pattern"))
+	}
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bufio"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/actforgood/xerr"
+)
+
+const defaultAccessLogBufferSize = 64 * 1024
+
+// AccessLogFileWriter is a buffered file writer, suited for access logs.
+// Data is flushed when the buffer is full, and periodically, if a flush interval is configured.
+// It is rotation friendly: after the file was moved by a tool like logrotate, call [AccessLogFileWriter.Reopen],
+// or let [AccessLogFileWriter.ReopenOnSignal] do it upon receiving SIGHUP.
+// It is concurrent safe to use.
+type AccessLogFileWriter struct {
+	path       string
+	bufferSize int
+	file       *os.File
+	buf        *bufio.Writer
+	mu         sync.Mutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+	signals    chan os.Signal
+	signalOnce sync.Once
+}
+
+// NewAccessLogFileWriter instantiates a new buffered file writer, appending to the file at given path.
+// The file is created, if it does not exist.
+// A zero buffer size defaults to 64Kb. A zero flush interval disables periodic flushing.
+// [AccessLogFileWriter.Close] should be called to release resources.
+func NewAccessLogFileWriter(path string, bufferSize int, flushInterval time.Duration) (*AccessLogFileWriter, error) {
+	if bufferSize <= 0 {
+		bufferSize = defaultAccessLogBufferSize
+	}
+	w := &AccessLogFileWriter{
+		path:       path,
+		bufferSize: bufferSize,
+		stop:       make(chan struct{}),
+		signals:    make(chan os.Signal, 1),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if flushInterval > 0 {
+		w.wg.Go(func() {
+			ticker := time.NewTicker(flushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					_ = w.Flush()
+				case <-w.stop:
+					return
+				}
+			}
+		})
+	}
+
+	return w, nil
+}
+
+func (w *AccessLogFileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return xerr.Wrapf(err, "could not open access log file %s", w.path)
+	}
+	w.file = file
+	w.buf = bufio.NewWriterSize(file, w.bufferSize)
+
+	return nil
+}
+
+// Write writes data to the buffer.
+func (w *AccessLogFileWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(data)
+}
+
+// Flush writes any buffered data to the file.
+func (w *AccessLogFileWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Flush()
+}
+
+// Reopen flushes buffered data, closes the file and opens it again,
+// (re)creating it at the configured path.
+func (w *AccessLogFileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	flushErr := w.buf.Flush()
+	closeErr := w.file.Close()
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	var mErr xerr.MultiError
+
+	return mErr.Add(flushErr, closeErr).ErrOrNil()
+}
+
+// ReopenOnSignal reopens the file each time one of given signals is received.
+// If no signal is provided, SIGHUP is used.
+// It should be called at most once, subsequent calls are no-ops.
+func (w *AccessLogFileWriter) ReopenOnSignal(signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	w.signalOnce.Do(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		select {
+		case <-w.stop: // already closed
+			return
+		default:
+		}
+		signal.Notify(w.signals, signals...)
+		w.wg.Go(func() {
+			for {
+				select {
+				case <-w.signals:
+					_ = w.Reopen()
+				case <-w.stop:
+					return
+				}
+			}
+		})
+	})
+}
+
+// Close stops background routines, flushes buffered data and closes the file.
+func (w *AccessLogFileWriter) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		close(w.stop)
+		w.mu.Unlock()
+		signal.Stop(w.signals)
+		w.wg.Wait()
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		var mErr xerr.MultiError
+		err = mErr.Add(w.buf.Flush(), w.file.Close()).ErrOrNil()
+	})
+
+	return err
+}
@@ -0,0 +1,147 @@
+package middleware_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestAccessLogFileWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("data is buffered", testAccessLogFileWriterBuffered)
+	t.Run("data is flushed periodically", testAccessLogFileWriterPeriodicFlush)
+	t.Run("file is reopened", testAccessLogFileWriterReopen)
+	t.Run("file is reopened on signal", testAccessLogFileWriterReopenOnSignal)
+	t.Run("reopen on signal and close are concurrent safe", testAccessLogFileWriterConcurrentSignalClose)
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	assert.RequireNil(t, err)
+
+	return string(content)
+}
+
+func testAccessLogFileWriterBuffered(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	subject, err := middleware.NewAccessLogFileWriter(logPath, 0, 0)
+	assert.RequireNil(t, err)
+
+	// act
+	_, err = subject.Write([]byte("line 1\n"))
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, "", readFile(t, logPath))
+	assert.Nil(t, subject.Close())
+	assert.Equal(t, "line 1\n", readFile(t, logPath))
+	assert.Nil(t, subject.Close()) // subsequent call is a no-op
+}
+
+func testAccessLogFileWriterPeriodicFlush(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	subject, err := middleware.NewAccessLogFileWriter(logPath, 1024, 10*time.Millisecond)
+	assert.RequireNil(t, err)
+	defer subject.Close()
+
+	// act
+	_, _ = subject.Write([]byte("line 1\n"))
+	time.Sleep(50 * time.Millisecond)
+
+	// assert
+	assert.Equal(t, "line 1\n", readFile(t, logPath))
+}
+
+func testAccessLogFileWriterReopen(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		logDir      = t.TempDir()
+		logPath     = filepath.Join(logDir, "access.log")
+		rotatedPath = filepath.Join(logDir, "access.log.1")
+	)
+	subject, err := middleware.NewAccessLogFileWriter(logPath, 0, 0)
+	assert.RequireNil(t, err)
+	defer subject.Close()
+	_, _ = subject.Write([]byte("line 1\n"))
+	assert.RequireNil(t, os.Rename(logPath, rotatedPath))
+
+	// act
+	err = subject.Reopen()
+	_, _ = subject.Write([]byte("line 2\n"))
+	_ = subject.Flush()
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, "line 1\n", readFile(t, rotatedPath))
+	assert.Equal(t, "line 2\n", readFile(t, logPath))
+}
+
+func testAccessLogFileWriterConcurrentSignalClose(t *testing.T) {
+	t.Parallel()
+
+	for range 10 {
+		// arrange
+		subject, err := middleware.NewAccessLogFileWriter(filepath.Join(t.TempDir(), "access.log"), 0, 0)
+		assert.RequireNil(t, err)
+		var wg sync.WaitGroup
+
+		// act
+		wg.Go(func() { subject.ReopenOnSignal(syscall.SIGUSR2) })
+		wg.Go(func() { assert.Nil(t, subject.Close()) })
+		wg.Wait()
+
+		// assert
+		subject.ReopenOnSignal(syscall.SIGUSR2) // no-op, after close
+		assert.Nil(t, subject.Close())
+	}
+}
+
+func testAccessLogFileWriterReopenOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent on windows")
+	}
+	t.Parallel()
+
+	// arrange
+	var (
+		logDir      = t.TempDir()
+		logPath     = filepath.Join(logDir, "access.log")
+		rotatedPath = filepath.Join(logDir, "access.log.1")
+	)
+	subject, err := middleware.NewAccessLogFileWriter(logPath, 0, 0)
+	assert.RequireNil(t, err)
+	defer subject.Close()
+	subject.ReopenOnSignal(syscall.SIGUSR1)
+	_, _ = subject.Write([]byte("line 1\n"))
+	assert.RequireNil(t, os.Rename(logPath, rotatedPath))
+	process, err := os.FindProcess(os.Getpid())
+	assert.RequireNil(t, err)
+
+	// act
+	assert.RequireNil(t, process.Signal(syscall.SIGUSR1))
+	time.Sleep(50 * time.Millisecond)
+	_, _ = subject.Write([]byte("line 2\n"))
+	_ = subject.Flush()
+
+	// assert
+	assert.Equal(t, "line 1\n", readFile(t, rotatedPath))
+	assert.Equal(t, "line 2\n", readFile(t, logPath))
+}
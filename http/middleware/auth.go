@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+var (
+	// ErrNoCredentials is returned by an [AuthStrategy] if the request
+	// does not carry credentials specific to that strategy.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned by an [AuthStrategy] if the request
+	// carries invalid credentials.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated entity.
+type Principal struct {
+	// Subject identifies the principal (user name, user id, client id, etc.).
+	Subject string
+	// Method is the authentication method ("jwt", "apikey", "basic", etc.).
+	Method string
+	// Claims holds additional information about the principal, like JWT claims.
+	Claims map[string]any
+}
+
+// principalCtxKey is the context key where principal is stored.
+type principalCtxKey struct{}
+
+// principalHolderCtxKey is the context key where a principal holder is stored.
+// A holder allows outer middlewares (like [AccessLog]) to get the principal
+// set by inner middlewares on a derived context.
+type principalHolderCtxKey struct{}
+
+// ContextWithPrincipal returns a new context enriched with principal information.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	if holder, found := ctx.Value(principalHolderCtxKey{}).(*Principal); found {
+		*holder = principal
+	}
+
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in the context.
+// The second returned value indicates whether a principal was found.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if principal, found := ctx.Value(principalCtxKey{}).(Principal); found {
+		return principal, true
+	}
+	if holder, found := ctx.Value(principalHolderCtxKey{}).(*Principal); found && holder.Subject != "" {
+		return *holder, true
+	}
+
+	return Principal{}, false
+}
+
+// contextWithPrincipalHolder returns a new context enriched with an (empty) principal holder.
+func contextWithPrincipalHolder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, principalHolderCtxKey{}, new(Principal))
+}
+
+// AuthStrategy is the contract for an authentication strategy.
+type AuthStrategy interface {
+	// Authenticate authenticates the request, returning the principal.
+	// It should return [ErrNoCredentials] if the request does not carry strategy specific credentials,
+	// and an error wrapping [ErrInvalidCredentials] if credentials are not valid.
+	Authenticate(r *http.Request) (Principal, error)
+	// Challenge returns the WWW-Authenticate header value for this strategy, if any.
+	Challenge() string
+}
+
+// Auth is a decorator/middleware that authenticates requests
+// through given strategies, tried in order. The first strategy for which the request
+// carries credentials decides the outcome.
+// On success, the principal is stored in the request context, see [PrincipalFromContext].
+// On failure, a 401 Unauthorized response is sent, with strategies' challenges.
+// Other errors than [ErrNoCredentials] / [ErrInvalidCredentials] are logged
+// and a 500 Internal Server Error response is sent.
+func Auth(next http.Handler, logger *slog.Logger, strategies ...AuthStrategy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, strategy := range strategies {
+			principal, err := strategy.Authenticate(r)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err == nil {
+				next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+
+				return
+			}
+			if !errors.Is(err, ErrInvalidCredentials) {
+				logger.Error(
+					"could not authenticate request",
+					"err", err,
+					"path", r.URL.Path,
+					"method", r.Method,
+				)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+
+			break
+		}
+
+		for _, strategy := range strategies {
+			if challenge := strategy.Challenge(); challenge != "" {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
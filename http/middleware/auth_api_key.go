@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+)
+
+// APIKeyProvider is the contract for verifying API keys.
+type APIKeyProvider interface {
+	// PrincipalForAPIKey returns the principal owning given API key.
+	// It should return an error wrapping [ErrInvalidCredentials] if the key is not valid.
+	PrincipalForAPIKey(ctx context.Context, apiKey string) (Principal, error)
+}
+
+// StaticAPIKeys is an [APIKeyProvider] holding a static set of API keys.
+// Keys are stored and looked up by their SHA-256 digest.
+type StaticAPIKeys struct {
+	subjects map[[sha256.Size]byte]string
+}
+
+// NewStaticAPIKeys instantiates a new static API keys provider.
+// The keys parameter maps API keys to subjects.
+func NewStaticAPIKeys(keys map[string]string) StaticAPIKeys {
+	subjects := make(map[[sha256.Size]byte]string, len(keys))
+	for key, subject := range keys {
+		subjects[sha256.Sum256([]byte(key))] = subject
+	}
+
+	return StaticAPIKeys{subjects: subjects}
+}
+
+// PrincipalForAPIKey ...see [APIKeyProvider.PrincipalForAPIKey].
+func (keys StaticAPIKeys) PrincipalForAPIKey(_ context.Context, apiKey string) (Principal, error) {
+	subject, found := keys.subjects[sha256.Sum256([]byte(apiKey))]
+	if !found {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	return Principal{Subject: subject, Method: "apikey"}, nil
+}
+
+type apiKeyAuthStrategy struct {
+	headerName string
+	keys       APIKeyProvider
+}
+
+// NewAPIKeyAuthStrategy instantiates a new API key authentication strategy,
+// which reads the key from given header, like "X-Api-Key".
+func NewAPIKeyAuthStrategy(headerName string, keys APIKeyProvider) AuthStrategy {
+	return apiKeyAuthStrategy{headerName: headerName, keys: keys}
+}
+
+// Authenticate ...see [AuthStrategy.Authenticate].
+func (strategy apiKeyAuthStrategy) Authenticate(r *http.Request) (Principal, error) {
+	apiKey := r.Header.Get(strategy.headerName)
+	if apiKey == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	return strategy.keys.PrincipalForAPIKey(r.Context(), apiKey)
+}
+
+// Challenge ...see [AuthStrategy.Challenge].
+func (apiKeyAuthStrategy) Challenge() string {
+	return ""
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+// BasicCredentialsProvider is the contract for verifying Basic authentication credentials.
+type BasicCredentialsProvider interface {
+	// Verify verifies the username and password, returning the principal.
+	// It should return an error wrapping [ErrInvalidCredentials] if credentials are not valid.
+	Verify(ctx context.Context, username, password string) (Principal, error)
+}
+
+// StaticBasicCredentials is a [BasicCredentialsProvider] holding
+// a static set of username-password pairs.
+// Comparisons are done in constant time.
+type StaticBasicCredentials struct {
+	passwords map[string][sha256.Size]byte
+}
+
+// NewStaticBasicCredentials instantiates a new static Basic credentials provider.
+// The credentials parameter maps usernames to passwords.
+func NewStaticBasicCredentials(credentials map[string]string) StaticBasicCredentials {
+	passwords := make(map[string][sha256.Size]byte, len(credentials))
+	for username, password := range credentials {
+		passwords[username] = sha256.Sum256([]byte(password))
+	}
+
+	return StaticBasicCredentials{passwords: passwords}
+}
+
+// Verify ...see [BasicCredentialsProvider.Verify].
+func (creds StaticBasicCredentials) Verify(_ context.Context, username, password string) (Principal, error) {
+	expected, found := creds.passwords[username]
+	actual := sha256.Sum256([]byte(password))
+	// compare even if user was not found, to not leak users' existence through timing.
+	if subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 || !found {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	return Principal{Subject: username, Method: "basic"}, nil
+}
+
+type basicAuthStrategy struct {
+	realm       string
+	credentials BasicCredentialsProvider
+}
+
+// NewBasicAuthStrategy instantiates a new Basic authentication strategy.
+func NewBasicAuthStrategy(realm string, credentials BasicCredentialsProvider) AuthStrategy {
+	return basicAuthStrategy{realm: realm, credentials: credentials}
+}
+
+// Authenticate ...see [AuthStrategy.Authenticate].
+func (strategy basicAuthStrategy) Authenticate(r *http.Request) (Principal, error) {
+	if r.Header.Get("Authorization") == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	return strategy.credentials.Verify(r.Context(), username, password)
+}
+
+// Challenge ...see [AuthStrategy.Challenge].
+func (strategy basicAuthStrategy) Challenge() string {
+	return "Basic realm=" + strconv.Quote(strategy.realm) + `, charset="UTF-8"`
+}
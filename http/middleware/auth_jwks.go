@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/actforgood/xerr"
+)
+
+// JWKSFileKeyProvider is a [JWTKeyProvider] which loads keys from a local JSON Web Key Set file.
+// The file is reloaded if it was modified, checked at most once per refresh interval,
+// or when an unknown key id is requested, thus supporting keys rotation.
+// It is concurrent safe to use.
+type JWKSFileKeyProvider struct {
+	path            string
+	refreshInterval time.Duration
+	keys            map[string]any // kid => key
+	modTime         time.Time
+	lastCheck       time.Time
+	mu              sync.RWMutex
+}
+
+// NewJWKSFileKeyProvider instantiates a new JWKS file key provider.
+// An error is returned if the file cannot be loaded.
+func NewJWKSFileKeyProvider(path string, refreshInterval time.Duration) (*JWKSFileKeyProvider, error) {
+	provider := &JWKSFileKeyProvider{
+		path:            path,
+		refreshInterval: refreshInterval,
+	}
+	if err := provider.reload(true); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// Key ...see [JWTKeyProvider.Key].
+// If the token has no key id and the set contains a single key, that key is returned.
+func (provider *JWKSFileKeyProvider) Key(_ context.Context, kid, _ string) (any, error) {
+	provider.mu.RLock()
+	shouldCheck := time.Since(provider.lastCheck) >= provider.refreshInterval
+	key, found := provider.lookup(kid)
+	provider.mu.RUnlock()
+
+	if found && !shouldCheck {
+		return key, nil
+	}
+	if shouldCheck || !found {
+		if err := provider.reload(false); err != nil {
+			return nil, err
+		}
+		provider.mu.RLock()
+		key, found = provider.lookup(kid)
+		provider.mu.RUnlock()
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidCredentials, kid)
+	}
+
+	return key, nil
+}
+
+func (provider *JWKSFileKeyProvider) lookup(kid string) (any, bool) {
+	if kid == "" && len(provider.keys) == 1 {
+		for _, key := range provider.keys {
+			return key, true
+		}
+	}
+	key, found := provider.keys[kid]
+
+	return key, found
+}
+
+// reload reloads the keys, if file was modified (or if forced).
+// The file is checked at most once per refresh interval, unless forced.
+func (provider *JWKSFileKeyProvider) reload(force bool) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if !force && time.Since(provider.lastCheck) < provider.refreshInterval {
+		return nil // another goroutine just checked.
+	}
+	provider.lastCheck = time.Now()
+
+	info, err := os.Stat(provider.path)
+	if err != nil {
+		return xerr.Wrapf(err, "could not stat JWKS file %s", provider.path)
+	}
+	if !force && info.ModTime().Equal(provider.modTime) {
+		return nil
+	}
+	content, err := os.ReadFile(provider.path)
+	if err != nil {
+		return xerr.Wrapf(err, "could not read JWKS file %s", provider.path)
+	}
+	keys, err := ParseJWKS(content)
+	if err != nil {
+		return err
+	}
+	provider.keys = keys
+	provider.modTime = info.ModTime()
+
+	return nil
+}
+
+// jwk is a JSON Web Key, see RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses a JSON Web Key Set, returning the keys indexed by key id.
+// Supported key types are "RSA", "EC" (P-256, P-384, P-521), "OKP" (Ed25519), "oct".
+// Keys with other use than signature are skipped.
+func ParseJWKS(content []byte) (map[string]any, error) {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, xerr.Wrap(err, "could not parse JWKS")
+	}
+
+	keys := make(map[string]any, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, xerr.Wrapf(err, "invalid JWK %q", k.Kid)
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return nil, xerr.New("invalid RSA key")
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, xerr.New("unsupported EC curve " + k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, xerr.New("invalid EC key")
+		}
+		// uncompressed point format, validated by ecdsa.ParseUncompressedPublicKey.
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, xerr.New("invalid EC key")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		key, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return nil, xerr.Wrap(err, "invalid EC key")
+		}
+
+		return key, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, xerr.New("invalid OKP key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, xerr.New("invalid oct key")
+		}
+
+		return secret, nil
+	default:
+		return nil, xerr.New("unsupported key type " + k.Kty)
+	}
+}
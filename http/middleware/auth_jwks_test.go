@@ -0,0 +1,173 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestJWKSFileKeyProvider(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keys are loaded", testJWKSFileKeyProviderLoad)
+	t.Run("keys are rotated", testJWKSFileKeyProviderRotation)
+	t.Run("missing file", testJWKSFileKeyProviderMissingFile)
+}
+
+func testJWKSFileKeyProviderLoad(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		jwksPath  = filepath.Join(t.TempDir(), "jwks.json")
+	)
+	writeJWKS(t, jwksPath, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+	subject, err := middleware.NewJWKSFileKeyProvider(jwksPath, time.Minute)
+	assert.RequireNil(t, err)
+
+	// act
+	rsaResult, errRSA := subject.Key(context.Background(), "rsa-1", "RS256")
+	ecResult, errEC := subject.Key(context.Background(), "ec-1", "ES256")
+	_, errUnknown := subject.Key(context.Background(), "unknown", "RS256")
+
+	// assert
+	assert.Nil(t, errRSA)
+	assert.Nil(t, errEC)
+	assert.True(t, rsaKey.PublicKey.Equal(rsaResult))
+	assert.True(t, ecKey.PublicKey.Equal(ecResult))
+	assert.True(t, errors.Is(errUnknown, middleware.ErrInvalidCredentials))
+}
+
+func testJWKSFileKeyProviderRotation(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		oldKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		newKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		jwksPath  = filepath.Join(t.TempDir(), "jwks.json")
+	)
+	writeJWKS(t, jwksPath, ecJWK("key-1", &oldKey.PublicKey))
+	subject, err := middleware.NewJWKSFileKeyProvider(jwksPath, 0)
+	assert.RequireNil(t, err)
+	token := signJWT(t, "ES256", "key-2", newKey, map[string]any{"sub": "john"})
+	_, errBeforeRotation := middleware.NewJWTAuthStrategy(
+		middleware.JWTConfig{Keys: subject},
+	).Authenticate(bearerRequest(token))
+
+	// act
+	writeJWKS(t, jwksPath, ecJWK("key-1", &oldKey.PublicKey), ecJWK("key-2", &newKey.PublicKey))
+	futureTime := time.Now().Add(time.Minute)
+	assert.RequireNil(t, os.Chtimes(jwksPath, futureTime, futureTime))
+	principal, errAfterRotation := middleware.NewJWTAuthStrategy(
+		middleware.JWTConfig{Keys: subject},
+	).Authenticate(bearerRequest(token))
+
+	// assert
+	assert.True(t, errors.Is(errBeforeRotation, middleware.ErrInvalidCredentials))
+	assert.Nil(t, errAfterRotation)
+	assert.Equal(t, "john", principal.Subject)
+}
+
+func testJWKSFileKeyProviderMissingFile(t *testing.T) {
+	t.Parallel()
+
+	// act
+	subject, err := middleware.NewJWKSFileKeyProvider(filepath.Join(t.TempDir(), "missing.json"), time.Minute)
+
+	// assert
+	assert.NotNil(t, err)
+	assert.Nil(t, subject)
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name        string
+		content     string
+		expectedLen int
+		expectErr   bool
+	}{
+		{
+			name:        "oct and encryption keys",
+			content:     `{"keys":[{"kty":"oct","kid":"h1","k":"c2VjcmV0"},{"kty":"RSA","kid":"e1","use":"enc"}]}`,
+			expectedLen: 1,
+		},
+		{
+			name:      "unsupported key type",
+			content:   `{"keys":[{"kty":"XYZ","kid":"x1"}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid json",
+			content:   `{"keys":`,
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			keys, err := middleware.ParseJWKS([]byte(test.content))
+
+			// assert
+			assert.Equal(t, test.expectErr, err != nil)
+			assert.Equal(t, test.expectedLen, len(keys))
+		})
+	}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...string) {
+	t.Helper()
+
+	content := `{"keys":[`
+	for i, key := range keys {
+		if i > 0 {
+			content += ","
+		}
+		content += key
+	}
+	content += "]}"
+	assert.RequireNil(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) string {
+	return `{"kty":"RSA","kid":"` + kid + `","use":"sig","n":"` +
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()) + `","e":"` +
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()) + `"}`
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) string {
+	point, _ := key.Bytes() // uncompressed: 0x04 || X || Y
+	size := (len(point) - 1) / 2
+
+	return `{"kty":"EC","kid":"` + kid + `","crv":"P-256","x":"` +
+		base64.RawURLEncoding.EncodeToString(point[1:1+size]) + `","y":"` +
+		base64.RawURLEncoding.EncodeToString(point[1+size:]) + `"}`
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return req
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	_ "crypto/sha256" // register hash implementation
+	_ "crypto/sha512" // register hash implementation
+)
+
+// JWTKeyProvider is the contract for providing JWT verification keys.
+type JWTKeyProvider interface {
+	// Key returns the verification key for given key id and algorithm.
+	// Supported key types are []byte (HMAC), *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey.
+	Key(ctx context.Context, kid, alg string) (any, error)
+}
+
+// StaticJWTKey is a [JWTKeyProvider] which always returns the same key, regardless of key id.
+type StaticJWTKey struct {
+	key any
+}
+
+// NewStaticJWTKey instantiates a new static key provider.
+// Supported key types are []byte (HMAC), *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey.
+func NewStaticJWTKey(key any) StaticJWTKey {
+	return StaticJWTKey{key: key}
+}
+
+// Key ...see [JWTKeyProvider.Key].
+func (sk StaticJWTKey) Key(context.Context, string, string) (any, error) {
+	return sk.key, nil
+}
+
+// JWTConfig holds the configuration for JWT authentication strategy.
+type JWTConfig struct {
+	// Keys provides the verification keys.
+	Keys JWTKeyProvider
+	// Algorithms is the list of accepted algorithms.
+	// Defaults to all supported algorithms: HS256, HS384, HS512, RS256, RS384, RS512,
+	// PS256, PS384, PS512, ES256, ES384, ES512, EdDSA.
+	Algorithms []string
+	// Issuer, if set, must match the "iss" claim.
+	Issuer string
+	// Audience, if set, must be found in the "aud" claim.
+	Audience string
+	// Leeway is the tolerated clock skew when validating "exp", "nbf" claims.
+	Leeway time.Duration
+	// SubjectClaim is the claim used as principal's subject. Defaults to "sub".
+	SubjectClaim string
+}
+
+var jwtAlgorithms = map[string]struct {
+	hash crypto.Hash
+	kind string
+}{
+	"HS256": {crypto.SHA256, "hmac"},
+	"HS384": {crypto.SHA384, "hmac"},
+	"HS512": {crypto.SHA512, "hmac"},
+	"RS256": {crypto.SHA256, "rsa"},
+	"RS384": {crypto.SHA384, "rsa"},
+	"RS512": {crypto.SHA512, "rsa"},
+	"PS256": {crypto.SHA256, "rsa-pss"},
+	"PS384": {crypto.SHA384, "rsa-pss"},
+	"PS512": {crypto.SHA512, "rsa-pss"},
+	"ES256": {crypto.SHA256, "ecdsa"},
+	"ES384": {crypto.SHA384, "ecdsa"},
+	"ES512": {crypto.SHA512, "ecdsa"},
+	"EdDSA": {0, "eddsa"},
+}
+
+type jwtAuthStrategy struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTAuthStrategy instantiates a new JWT bearer token authentication strategy.
+func NewJWTAuthStrategy(config JWTConfig) AuthStrategy {
+	if len(config.Algorithms) == 0 {
+		for alg := range jwtAlgorithms {
+			config.Algorithms = append(config.Algorithms, alg)
+		}
+	}
+	if config.SubjectClaim == "" {
+		config.SubjectClaim = "sub"
+	}
+
+	return jwtAuthStrategy{config: config, now: time.Now}
+}
+
+// Authenticate ...see [AuthStrategy.Authenticate].
+func (strategy jwtAuthStrategy) Authenticate(r *http.Request) (Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims, err := strategy.verify(r.Context(), strings.TrimSpace(token))
+	if err != nil {
+		return Principal{}, err
+	}
+	subject, _ := claims[strategy.config.SubjectClaim].(string)
+
+	return Principal{Subject: subject, Method: "jwt", Claims: claims}, nil
+}
+
+// Challenge ...see [AuthStrategy.Challenge].
+func (jwtAuthStrategy) Challenge() string {
+	return "Bearer"
+}
+
+// verify verifies the token's signature and standard claims, returning token's claims.
+func (strategy jwtAuthStrategy) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed token header", ErrInvalidCredentials)
+	}
+	if !slices.Contains(strategy.config.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("%w: algorithm %q is not accepted", ErrInvalidCredentials, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token signature", ErrInvalidCredentials)
+	}
+	key, err := strategy.config.Keys.Key(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrInvalidCredentials)
+	}
+	if err := strategy.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (strategy jwtAuthStrategy) validateClaims(claims map[string]any) error {
+	now := strategy.now()
+	if exp, found := claims["exp"].(float64); found {
+		if !now.Before(time.Unix(int64(exp), 0).Add(strategy.config.Leeway)) {
+			return fmt.Errorf("%w: token is expired", ErrInvalidCredentials)
+		}
+	} else if _, found := claims["exp"]; found {
+		return fmt.Errorf("%w: invalid exp claim", ErrInvalidCredentials)
+	}
+	if nbf, found := claims["nbf"].(float64); found {
+		if now.Add(strategy.config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+			return fmt.Errorf("%w: token is not valid yet", ErrInvalidCredentials)
+		}
+	} else if _, found := claims["nbf"]; found {
+		return fmt.Errorf("%w: invalid nbf claim", ErrInvalidCredentials)
+	}
+	if strategy.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != strategy.config.Issuer {
+			return fmt.Errorf("%w: invalid issuer", ErrInvalidCredentials)
+		}
+	}
+	if strategy.config.Audience != "" {
+		var audienceFound bool
+		switch aud := claims["aud"].(type) {
+		case string:
+			audienceFound = aud == strategy.config.Audience
+		case []any:
+			audienceFound = slices.Contains(aud, any(strategy.config.Audience))
+		}
+		if !audienceFound {
+			return fmt.Errorf("%w: invalid audience", ErrInvalidCredentials)
+		}
+	}
+
+	return nil
+}
+
+func decodeJWTSegment(segment string, dest any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+// verifyJWTSignature verifies the signature, making sure the key type matches the algorithm.
+func verifyJWTSignature(alg string, key any, signingInput, signature []byte) error {
+	algorithm := jwtAlgorithms[alg]
+	var hashed []byte
+	if algorithm.hash != 0 && algorithm.kind != "hmac" {
+		hasher := algorithm.hash.New()
+		hasher.Write(signingInput)
+		hashed = hasher.Sum(nil)
+	}
+
+	var valid bool
+	switch k := key.(type) {
+	case []byte:
+		if algorithm.kind == "hmac" {
+			mac := hmac.New(algorithm.hash.New, k)
+			mac.Write(signingInput)
+			valid = hmac.Equal(signature, mac.Sum(nil))
+		}
+	case *rsa.PublicKey:
+		switch algorithm.kind {
+		case "rsa":
+			valid = rsa.VerifyPKCS1v15(k, algorithm.hash, hashed, signature) == nil
+		case "rsa-pss":
+			valid = rsa.VerifyPSS(k, algorithm.hash, hashed, signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		keySize := (k.Curve.Params().BitSize + 7) / 8
+		if algorithm.kind == "ecdsa" && len(signature) == 2*keySize {
+			r := new(big.Int).SetBytes(signature[:keySize])
+			s := new(big.Int).SetBytes(signature[keySize:])
+			valid = ecdsa.Verify(k, hashed, r, s)
+		}
+	case ed25519.PublicKey:
+		if algorithm.kind == "eddsa" {
+			valid = ed25519.Verify(k, signingInput, signature)
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%w: invalid token signature", ErrInvalidCredentials)
+	}
+
+	return nil
+}
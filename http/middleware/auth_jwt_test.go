@@ -0,0 +1,214 @@
+package middleware_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestJWTAuthStrategy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		hmacKey       = []byte("super-secret-hmac-key")
+		rsaKey, _     = rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _      = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		edPub, edPriv = mustGenerateEd25519Key(t)
+		now           = time.Now().Unix()
+		validClaims   = map[string]any{"sub": "john", "iss": "auth.example.com", "aud": []any{"api"}, "exp": now + 60}
+		config        = middleware.JWTConfig{Issuer: "auth.example.com", Audience: "api"}
+	)
+
+	tests := [...]struct {
+		name           string
+		token          string
+		verifyKey      any
+		algorithms     []string
+		expectedStatus int
+	}{
+		{
+			name:           "valid HS256",
+			token:          signJWT(t, "HS256", "", hmacKey, validClaims),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "valid RS256",
+			token:          signJWT(t, "RS256", "", rsaKey, validClaims),
+			verifyKey:      &rsaKey.PublicKey,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "valid ES256",
+			token:          signJWT(t, "ES256", "", ecKey, validClaims),
+			verifyKey:      &ecKey.PublicKey,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "valid EdDSA",
+			token:          signJWT(t, "EdDSA", "", edPriv, validClaims),
+			verifyKey:      edPub,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid signature",
+			token:          signJWT(t, "HS256", "", []byte("another-key"), validClaims),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "algorithm not accepted",
+			token:          signJWT(t, "HS256", "", hmacKey, validClaims),
+			verifyKey:      hmacKey,
+			algorithms:     []string{"RS256"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "algorithm - key type mismatch",
+			token:          signJWT(t, "HS256", "", hmacKey, validClaims),
+			verifyKey:      &rsaKey.PublicKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "none algorithm",
+			token:          signJWT(t, "none", "", nil, validClaims),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired",
+			token: signJWT(t, "HS256", "", hmacKey, map[string]any{
+				"sub": "john", "iss": "auth.example.com", "aud": "api", "exp": now - 60,
+			}),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "not valid yet",
+			token: signJWT(t, "HS256", "", hmacKey, map[string]any{
+				"sub": "john", "iss": "auth.example.com", "aud": "api", "nbf": now + 60,
+			}),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid issuer",
+			token:          signJWT(t, "HS256", "", hmacKey, map[string]any{"sub": "john", "iss": "evil", "aud": "api"}),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "invalid audience",
+			token: signJWT(t, "HS256", "", hmacKey, map[string]any{
+				"sub": "john", "iss": "auth.example.com", "aud": "other",
+			}),
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "malformed",
+			token:          "abc.def",
+			verifyKey:      hmacKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				actualPrincipal middleware.Principal
+				nextHandler     = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					actualPrincipal, _ = middleware.PrincipalFromContext(r.Context())
+					w.WriteHeader(http.StatusOK)
+				})
+				testConfig = config
+				logger     = slog.New(mock.NewSlogHandler())
+				req        = httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+				w          = httptest.NewRecorder()
+			)
+			testConfig.Keys = middleware.NewStaticJWTKey(test.verifyKey)
+			testConfig.Algorithms = test.algorithms
+			req.Header.Set("Authorization", "Bearer "+test.token)
+
+			// act
+			middleware.Auth(nextHandler, logger, middleware.NewJWTAuthStrategy(testConfig)).ServeHTTP(w, req)
+
+			// assert
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusOK {
+				assert.Equal(t, "john", actualPrincipal.Subject)
+				assert.Equal(t, "jwt", actualPrincipal.Method)
+				assert.Equal(t, "auth.example.com", actualPrincipal.Claims["iss"])
+			}
+		})
+	}
+}
+
+func mustGenerateEd25519Key(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.RequireNil(t, err)
+
+	return pub, priv
+}
+
+// signJWT produces a signed JWT.
+func signJWT(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+	hashed := sha256.Sum256([]byte(signingInput))
+
+	var (
+		signature []byte
+		err       error
+	)
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashed[:])
+	case *ecdsa.PrivateKey:
+		r, s, errSign := ecdsa.Sign(rand.Reader, k, hashed[:])
+		err = errSign
+		if err == nil {
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signingInput))
+	}
+	assert.RequireNil(t, err)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,228 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("basic auth valid credentials", testAuthBasicValid)
+	t.Run("basic auth invalid credentials", testAuthBasicInvalid)
+	t.Run("api key valid", testAuthAPIKeyValid)
+	t.Run("no credentials", testAuthNoCredentials)
+	t.Run("strategy internal error", testAuthInternalError)
+	t.Run("principal is picked up by access log", testAuthWithAccessLog)
+}
+
+func testAuthBasicValid(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		actualPrincipal middleware.Principal
+		nextHandler     = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actualPrincipal, _ = middleware.PrincipalFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		})
+		credentials = middleware.NewStaticBasicCredentials(map[string]string{"john": "s3cr3t"})
+		logger      = slog.New(mock.NewSlogHandler())
+		subject     = middleware.Auth(nextHandler, logger, middleware.NewBasicAuthStrategy("api", credentials))
+		req         = httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+		w           = httptest.NewRecorder()
+	)
+	req.SetBasicAuth("john", "s3cr3t")
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, middleware.Principal{Subject: "john", Method: "basic"}, actualPrincipal)
+}
+
+func testAuthBasicInvalid(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandlerCallsCnt int
+		nextHandler         = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			nextHandlerCallsCnt++
+		})
+		credentials = middleware.NewStaticBasicCredentials(map[string]string{"john": "s3cr3t"})
+		logger      = slog.New(mock.NewSlogHandler())
+		subject     = middleware.Auth(
+			nextHandler,
+			logger,
+			middleware.NewBasicAuthStrategy("api", credentials),
+			middleware.NewAPIKeyAuthStrategy("X-Api-Key", middleware.NewStaticAPIKeys(nil)),
+		)
+	)
+
+	for _, creds := range [...][2]string{{"john", "wrong"}, {"jane", "s3cr3t"}} {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+		req.SetBasicAuth(creds[0], creds[1])
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		// assert
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, []string{`Basic realm="api", charset="UTF-8"`}, w.Header().Values("WWW-Authenticate"))
+	}
+	assert.Equal(t, 0, nextHandlerCallsCnt)
+}
+
+func testAuthAPIKeyValid(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		actualPrincipal middleware.Principal
+		nextHandler     = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actualPrincipal, _ = middleware.PrincipalFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		})
+		credentials = middleware.NewStaticBasicCredentials(map[string]string{"john": "s3cr3t"})
+		apiKeys     = middleware.NewStaticAPIKeys(map[string]string{"key-123": "billing-service"})
+		logger      = slog.New(mock.NewSlogHandler())
+		subject     = middleware.Auth(
+			nextHandler,
+			logger,
+			middleware.NewBasicAuthStrategy("api", credentials),
+			middleware.NewAPIKeyAuthStrategy("X-Api-Key", apiKeys),
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("X-Api-Key", "key-123")
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, middleware.Principal{Subject: "billing-service", Method: "apikey"}, actualPrincipal)
+}
+
+func testAuthNoCredentials(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})
+		logger      = slog.New(mock.NewSlogHandler())
+		subject     = middleware.Auth(
+			nextHandler,
+			logger,
+			middleware.NewJWTAuthStrategy(middleware.JWTConfig{Keys: middleware.NewStaticJWTKey([]byte("k"))}),
+			middleware.NewBasicAuthStrategy("api", middleware.NewStaticBasicCredentials(nil)),
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(
+		t,
+		[]string{"Bearer", `Basic realm="api", charset="UTF-8"`},
+		w.Header().Values("WWW-Authenticate"),
+	)
+}
+
+func testAuthInternalError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})
+		loggerMock  = mock.NewSlogHandler()
+		logger      = slog.New(loggerMock)
+		subject     = middleware.Auth(
+			nextHandler,
+			logger,
+			middleware.NewAPIKeyAuthStrategy("X-Api-Key", errAPIKeyProvider{}),
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("X-Api-Key", "key-123")
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func testAuthWithAccessLog(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		logger     = slog.New(loggerMock)
+		apiKeys    = middleware.NewStaticAPIKeys(map[string]string{"key-123": "billing-service"})
+		subject    = middleware.AccessLog(
+			middleware.Auth(nextHandler, logger, middleware.NewAPIKeyAuthStrategy("X-Api-Key", apiKeys)),
+			logger,
+			nil,
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/private", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("X-Api-Key", "key-123")
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, "billing-service", loggerMock.ValueAt(1, "authUsername"))
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		ctx       = context.Background()
+		principal = middleware.Principal{Subject: "john", Method: "jwt", Claims: map[string]any{"role": "admin"}}
+	)
+
+	// act
+	_, foundBefore := middleware.PrincipalFromContext(ctx)
+	actualPrincipal, foundAfter := middleware.PrincipalFromContext(middleware.ContextWithPrincipal(ctx, principal))
+
+	// assert
+	assert.Equal(t, false, foundBefore)
+	assert.True(t, foundAfter)
+	assert.Equal(t, principal, actualPrincipal)
+}
+
+type errAPIKeyProvider struct{}
+
+func (errAPIKeyProvider) PrincipalForAPIKey(context.Context, string) (middleware.Principal, error) {
+	return middleware.Principal{}, errors.New("intentionally triggered provider error")
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimiterConfig holds the configuration for a [ConcurrencyLimiter].
+type ConcurrencyLimiterConfig struct {
+	// Limit is the max no. of requests served concurrently.
+	// In adaptive mode, it is the initial limit.
+	Limit int
+	// MaxWait is the max time a request waits in queue for a free slot, before being shed.
+	// If not set, requests exceeding the limit are shed immediately.
+	MaxWait time.Duration
+	// MaxQueue is the max no. of requests waiting for a free slot.
+	// If not set, it defaults to Limit.
+	MaxQueue int
+	// Adaptive enables adjusting the limit based on observed latency,
+	// using an AIMD (additive increase, multiplicative decrease) algorithm:
+	// the limit grows by ~1 for each limit's worth of requests served within tolerated latency,
+	// and is decreased by BackoffRatio when latency exceeds Tolerance * baseline latency,
+	// at most once per round trip (only requests started after previous decrease can trigger another one).
+	// Shed requests do not decrease the limit, as shedding is the symptom of overload, not its measure.
+	Adaptive bool
+	// MinLimit is the min limit in adaptive mode. Defaults to 1.
+	MinLimit int
+	// MaxLimit is the max limit in adaptive mode. Defaults to 10 * Limit.
+	MaxLimit int
+	// Tolerance is the latency multiplier of the baseline (smoothed average of observed) latency,
+	// beyond which the limit is decreased. Defaults to 2.
+	Tolerance float64
+	// BackoffRatio is the multiplier applied to decrease the limit. Defaults to 0.9.
+	BackoffRatio float64
+}
+
+// ConcurrencyLimiter caps the no. of in-flight requests.
+// It is concurrent safe to use and can be shared by multiple [ConcurrencyLimit] middlewares.
+type ConcurrencyLimiter struct {
+	config   ConcurrencyLimiterConfig
+	limit    float64
+	inFlight int
+	waiters  []chan struct{}
+	mu       sync.Mutex
+
+	// adaptive mode state.
+	baseline    time.Duration // smoothed latency.
+	samples     int           // no. of latency samples, up to baselineWindow.
+	lastBackoff time.Time     // moment the limit was last decreased.
+}
+
+// baselineWindow is the no. of latency samples the baseline is averaged over.
+const baselineWindow = 100
+
+// NewConcurrencyLimiter instantiates a new concurrency limiter.
+func NewConcurrencyLimiter(config ConcurrencyLimiterConfig) *ConcurrencyLimiter {
+	if config.Limit <= 0 {
+		config.Limit = 1
+	}
+	if config.MaxQueue <= 0 {
+		config.MaxQueue = config.Limit
+	}
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = 10 * config.Limit
+	}
+	if config.Tolerance <= 1 {
+		config.Tolerance = 2
+	}
+	if config.BackoffRatio <= 0 || config.BackoffRatio >= 1 {
+		config.BackoffRatio = 0.9
+	}
+
+	return &ConcurrencyLimiter{
+		config: config,
+		limit:  float64(config.Limit),
+	}
+}
+
+// Acquire waits for a free slot, up to the configured max wait.
+// If a slot was acquired, the returned release function must be called once the request is served,
+// otherwise the request should be shed.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (release func(), acquired bool) {
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) {
+		l.inFlight++
+		l.mu.Unlock()
+
+		return l.releaseFunc(time.Now()), true
+	}
+	if l.config.MaxWait <= 0 || len(l.waiters) >= l.config.MaxQueue {
+		l.mu.Unlock()
+
+		return nil, false
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.config.MaxWait)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return l.releaseFunc(time.Now()), true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ready: // slot was handed over meanwhile
+		return l.releaseFunc(time.Now()), true
+	default:
+	}
+	if idx := slices.Index(l.waiters, ready); idx >= 0 {
+		l.waiters = slices.Delete(l.waiters, idx, idx+1)
+	}
+
+	return nil, false
+}
+
+// Limit returns current limit.
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the no. of requests currently being served.
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+func (l *ConcurrencyLimiter) releaseFunc(start time.Time) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			latency := time.Since(start)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.config.Adaptive {
+				l.adaptLocked(start, latency)
+			}
+			l.inFlight--
+			for len(l.waiters) > 0 && l.inFlight < int(l.limit) {
+				// hand over the slot to the first waiter.
+				l.inFlight++
+				close(l.waiters[0])
+				l.waiters = l.waiters[1:]
+			}
+		})
+	}
+}
+
+// adaptLocked applies AIMD algorithm based on observed latency of a request started at given moment.
+func (l *ConcurrencyLimiter) adaptLocked(start time.Time, latency time.Duration) {
+	exceeded := l.samples > 0 && float64(latency) > l.config.Tolerance*float64(l.baseline)
+
+	// exponentially weighted moving average, being a cumulative average until the window is filled,
+	// so that the baseline follows workload changes, without being snapped by outliers.
+	if l.samples < baselineWindow {
+		l.samples++
+	}
+	l.baseline += (latency - l.baseline) / time.Duration(l.samples)
+
+	if exceeded {
+		if start.After(l.lastBackoff) { // once per round trip.
+			l.limit = max(float64(l.config.MinLimit), l.limit*l.config.BackoffRatio)
+			l.lastBackoff = time.Now()
+		}
+
+		return
+	}
+	if float64(l.inFlight) >= l.limit/2 { // increase only if the limit is actually used.
+		l.limit = min(float64(l.config.MaxLimit), l.limit+1/l.limit)
+	}
+}
+
+// ExemptPaths returns a function which exempts from limiting the requests
+// with given paths, like health endpoints, see [httpTransport.Health].
+func ExemptPaths(paths ...string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return slices.Contains(paths, r.URL.Path)
+	}
+}
+
+// ConcurrencyLimit is a decorator/middleware that caps the no. of in-flight requests
+// through given limiter. Requests that cannot acquire a slot are shed with a 503 Service Unavailable response.
+// Requests for which exempt function returns true are not limited.
+//
+// It can be used globally, decorating the [http.ServeMux], and/or per route,
+// decorating the handlers registered on the mux, each with its own limiter.
+func ConcurrencyLimit(
+	next http.Handler,
+	limiter *ConcurrencyLimiter,
+	exempt func(*http.Request) bool,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exempt != nil && exempt(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		release, acquired := limiter.Acquire(r.Context())
+		if !acquired {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,321 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport"
+	httpTransport "github.com/actforgood/xtransport/http"
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("excess requests are shed", testConcurrencyLimitSheds)
+	t.Run("exempt requests are not limited", testConcurrencyLimitExempt)
+}
+
+func testConcurrencyLimitSheds(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		started     = make(chan struct{})
+		unblock     = make(chan struct{})
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			started <- struct{}{}
+			<-unblock
+			w.WriteHeader(http.StatusOK)
+		})
+		limiter = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{Limit: 1})
+		subject = middleware.ConcurrencyLimit(nextHandler, limiter, nil)
+		w1      = httptest.NewRecorder()
+		w2      = httptest.NewRecorder()
+		wg      sync.WaitGroup
+	)
+	wg.Go(func() {
+		subject.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "http://example.com/a", nil))
+	})
+	<-started
+
+	// act
+	subject.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "http://example.com/b", nil))
+	close(unblock)
+	wg.Wait()
+
+	// assert
+	assert.Equal(t, http.StatusOK, w1.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, w2.Code)
+	assert.Equal(t, "1", w2.Header().Get("Retry-After"))
+	assert.Equal(t, 0, limiter.InFlight())
+}
+
+func testConcurrencyLimitExempt(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		probe   = new(xtransport.Probe)
+		limiter = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{Limit: 1})
+		subject = middleware.ConcurrencyLimit(httpTransport.Health(probe), limiter, middleware.ExemptPaths("/health"))
+		w       = httptest.NewRecorder()
+	)
+	probe.SetReady(true)
+	release, acquired := limiter.Acquire(context.Background()) // occupy the only slot
+	assert.True(t, acquired)
+	defer release()
+
+	// act
+	subject.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/health", nil))
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("waiter gets the released slot", testConcurrencyLimiterWaiterAcquires)
+	t.Run("waiter is shed after max wait", testConcurrencyLimiterWaiterTimesOut)
+	t.Run("waiter is shed when queue is full", testConcurrencyLimiterQueueFull)
+	t.Run("adaptive limit decreases on latency increase", testConcurrencyLimiterAdaptiveDecrease)
+	t.Run("adaptive limit increases under load", testConcurrencyLimiterAdaptiveIncrease)
+	t.Run("adaptive limit decreases once per round trip", testConcurrencyLimiterAdaptiveDecreaseOncePerRTT)
+	t.Run("adaptive limit does not collapse on shed requests", testConcurrencyLimiterAdaptiveShedBurst)
+	t.Run("adaptive baseline is not snapped by a fast request", testConcurrencyLimiterAdaptiveFastOutlier)
+}
+
+func testConcurrencyLimiterWaiterAcquires(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:   1,
+			MaxWait: time.Second,
+		})
+		ctx = context.Background()
+	)
+	release1, acquired1 := subject.Acquire(ctx)
+	assert.True(t, acquired1)
+	time.AfterFunc(20*time.Millisecond, release1)
+
+	// act
+	release2, acquired2 := subject.Acquire(ctx)
+
+	// assert
+	if assert.True(t, acquired2) {
+		assert.Equal(t, 1, subject.InFlight())
+		release2()
+		release2() // idempotent
+		assert.Equal(t, 0, subject.InFlight())
+	}
+}
+
+func testConcurrencyLimiterWaiterTimesOut(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:   1,
+			MaxWait: 10 * time.Millisecond,
+		})
+		ctx = context.Background()
+	)
+	release1, _ := subject.Acquire(ctx)
+	defer release1()
+
+	// act
+	start := time.Now()
+	_, acquired := subject.Acquire(ctx)
+
+	// assert
+	assert.Equal(t, false, acquired)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+}
+
+func testConcurrencyLimiterQueueFull(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:    1,
+			MaxWait:  time.Second,
+			MaxQueue: 1,
+		})
+		ctx                     = context.Background()
+		waiterCtx, cancelWaiter = context.WithCancel(ctx)
+	)
+	release1, _ := subject.Acquire(ctx)
+	waiterDone := make(chan bool)
+	go func() {
+		_, acquired := subject.Acquire(waiterCtx)
+		waiterDone <- acquired
+	}()
+	time.Sleep(10 * time.Millisecond) // let the waiter enqueue
+
+	// act
+	start := time.Now()
+	_, acquired := subject.Acquire(ctx)
+
+	// assert
+	assert.Equal(t, false, acquired)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	cancelWaiter()
+	assert.Equal(t, false, <-waiterDone)
+	release1()
+	assert.Equal(t, 0, subject.InFlight())
+}
+
+func testConcurrencyLimiterAdaptiveDecrease(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:    10,
+			Adaptive: true,
+		})
+		ctx = context.Background()
+	)
+	for range 5 { // establish baseline
+		release, _ := subject.Acquire(ctx)
+		release()
+	}
+
+	// act
+	release, _ := subject.Acquire(ctx)
+	time.Sleep(20 * time.Millisecond)
+	release()
+
+	// assert
+	assert.True(t, subject.Limit() < 10)
+}
+
+func testConcurrencyLimiterAdaptiveIncrease(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:     2,
+			Adaptive:  true,
+			Tolerance: 1000,
+		})
+		ctx = context.Background()
+	)
+
+	// act
+	for range 20 {
+		release1, _ := subject.Acquire(ctx)
+		release2, _ := subject.Acquire(ctx)
+		release1()
+		release2()
+	}
+
+	// assert
+	assert.True(t, subject.Limit() > 2)
+}
+
+func testConcurrencyLimiterAdaptiveDecreaseOncePerRTT(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:     10,
+			Adaptive:  true,
+			Tolerance: 10,
+		})
+		ctx      = context.Background()
+		releases = make([]func(), 0, 5)
+	)
+	for range 5 { // establish baseline
+		release, _ := subject.Acquire(ctx)
+		time.Sleep(time.Millisecond)
+		release()
+	}
+	for range 5 {
+		release, acquired := subject.Acquire(ctx)
+		assert.True(t, acquired)
+		releases = append(releases, release)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// act
+	for _, release := range releases {
+		release()
+	}
+
+	// assert
+	assert.Equal(t, 9, subject.Limit())
+}
+
+func testConcurrencyLimiterAdaptiveShedBurst(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:    5,
+			Adaptive: true,
+		})
+		ctx      = context.Background()
+		releases = make([]func(), 0, 5)
+	)
+	for range 5 {
+		release, acquired := subject.Acquire(ctx)
+		assert.True(t, acquired)
+		releases = append(releases, release)
+	}
+
+	// act
+	for range 50 {
+		_, acquired := subject.Acquire(ctx)
+		assert.True(t, !acquired)
+	}
+
+	// assert
+	assert.Equal(t, 5, subject.Limit())
+	for _, release := range releases {
+		release()
+	}
+	assert.Equal(t, 5, subject.Limit())
+}
+
+func testConcurrencyLimiterAdaptiveFastOutlier(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewConcurrencyLimiter(middleware.ConcurrencyLimiterConfig{
+			Limit:     10,
+			Adaptive:  true,
+			Tolerance: 4,
+		})
+		ctx   = context.Background()
+		serve = func(latency time.Duration) {
+			release, _ := subject.Acquire(ctx)
+			time.Sleep(latency)
+			release()
+		}
+	)
+	for range 5 { // establish baseline
+		serve(10 * time.Millisecond)
+	}
+
+	// act
+	serve(0) // like a fast 404
+	serve(10 * time.Millisecond)
+
+	// assert
+	assert.Equal(t, 10, subject.Limit())
+}
@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actforgood/xtransport"
+)
+
+// CORSPolicy holds the Cross-Origin Resource Sharing configuration.
+type CORSPolicy struct {
+	// AllowedOrigins is the list of origins allowed to make cross-origin requests.
+	// An origin may contain wildcards, like "https://*.example.com", or be "*" to allow any origin.
+	AllowedOrigins []string
+	// AllowedMethods is the list of methods allowed for cross-origin requests.
+	// Defaults to simple methods: GET, HEAD, POST.
+	AllowedMethods []string
+	// AllowedHeaders is the list of (non simple) headers allowed for cross-origin requests.
+	// "*" allows any header.
+	AllowedHeaders []string
+	// ExposedHeaders is the list of headers the client is allowed to access.
+	// [xtransport.CorrelationIDHeaderKey] is exposed by default.
+	ExposedHeaders []string
+	// AllowCredentials indicates whether the request can include user credentials (cookies, auth headers, etc.).
+	AllowCredentials bool
+	// MaxAge indicates how long the results of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// corsPolicy is the compiled form of a [CORSPolicy].
+type corsPolicy struct {
+	allowAllOrigins   bool
+	exactOrigins      map[string]struct{}
+	wildcardOrigins   [][2]string // prefix, suffix pairs
+	allowedMethods    []string
+	allowAllHeaders   bool
+	allowedHeaders    map[string]struct{}
+	allowedHeadersStr string
+	exposedHeaders    string
+	allowCredentials  bool
+	maxAge            string
+}
+
+func compileCORSPolicy(policy CORSPolicy) *corsPolicy {
+	compiled := &corsPolicy{
+		exactOrigins:     make(map[string]struct{}, len(policy.AllowedOrigins)),
+		allowedHeaders:   make(map[string]struct{}, len(policy.AllowedHeaders)),
+		allowCredentials: policy.AllowCredentials,
+	}
+	for _, origin := range policy.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			compiled.allowAllOrigins = true
+		} else if prefix, suffix, found := strings.Cut(origin, "*"); found {
+			compiled.wildcardOrigins = append(compiled.wildcardOrigins, [2]string{prefix, suffix})
+		} else if origin != "" {
+			compiled.exactOrigins[origin] = struct{}{}
+		}
+	}
+
+	if len(policy.AllowedMethods) == 0 {
+		compiled.allowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	} else {
+		for _, method := range policy.AllowedMethods {
+			compiled.allowedMethods = append(compiled.allowedMethods, strings.ToUpper(strings.TrimSpace(method)))
+		}
+	}
+
+	headers := make([]string, 0, len(policy.AllowedHeaders))
+	for _, header := range policy.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			compiled.allowAllHeaders = true
+		} else if header != "" {
+			compiled.allowedHeaders[strings.ToLower(header)] = struct{}{}
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	compiled.allowedHeadersStr = strings.Join(headers, ", ")
+
+	exposedHeaders := slices.Clone(policy.ExposedHeaders)
+	if !slices.ContainsFunc(exposedHeaders, func(header string) bool {
+		return strings.EqualFold(header, xtransport.CorrelationIDHeaderKey)
+	}) {
+		exposedHeaders = append(exposedHeaders, xtransport.CorrelationIDHeaderKey)
+	}
+	compiled.exposedHeaders = strings.Join(exposedHeaders, ", ")
+
+	if policy.MaxAge > 0 {
+		compiled.maxAge = strconv.Itoa(int(policy.MaxAge / time.Second))
+	}
+
+	return compiled
+}
+
+func (policy *corsPolicy) isOriginAllowed(origin string) bool {
+	if policy.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, found := policy.exactOrigins[origin]; found {
+		return true
+	}
+	for _, wildcard := range policy.wildcardOrigins {
+		if len(origin) >= len(wildcard[0])+len(wildcard[1]) &&
+			strings.HasPrefix(origin, wildcard[0]) &&
+			strings.HasSuffix(origin, wildcard[1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (policy *corsPolicy) areHeadersAllowed(requestedHeaders string) bool {
+	if policy.allowAllHeaders || requestedHeaders == "" {
+		return true
+	}
+	for header := range strings.SplitSeq(requestedHeaders, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		if _, found := policy.allowedHeaders[header]; !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (policy *corsPolicy) setAllowOrigin(h http.Header, origin string) {
+	if policy.allowAllOrigins && !policy.allowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if policy.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// CORSPolicies holds a default CORS policy and policies attached to [http.ServeMux] patterns.
+// It is concurrent safe to use, policies can be replaced at runtime.
+type CORSPolicies struct {
+	defaultPolicy *corsPolicy
+	routes        map[string]*corsPolicy
+	mu            sync.RWMutex
+}
+
+// NewCORSPolicies instantiates a new CORS policies holder,
+// with given default policy, applied for routes that do not have a policy attached.
+func NewCORSPolicies(defaultPolicy CORSPolicy) *CORSPolicies {
+	return &CORSPolicies{
+		defaultPolicy: compileCORSPolicy(defaultPolicy),
+		routes:        make(map[string]*corsPolicy),
+	}
+}
+
+// Attach attaches a policy for given [http.ServeMux] pattern, like "GET /api/items/{id}".
+func (policies *CORSPolicies) Attach(pattern string, policy CORSPolicy) {
+	compiled := compileCORSPolicy(policy)
+
+	policies.mu.Lock()
+	policies.routes[pattern] = compiled
+	policies.mu.Unlock()
+}
+
+// Replace replaces all policies.
+func (policies *CORSPolicies) Replace(defaultPolicy CORSPolicy, routes map[string]CORSPolicy) {
+	compiledDefault := compileCORSPolicy(defaultPolicy)
+	compiledRoutes := make(map[string]*corsPolicy, len(routes))
+	for pattern, policy := range routes {
+		compiledRoutes[pattern] = compileCORSPolicy(policy)
+	}
+
+	policies.mu.Lock()
+	policies.defaultPolicy = compiledDefault
+	policies.routes = compiledRoutes
+	policies.mu.Unlock()
+}
+
+func (policies *CORSPolicies) lookup(pattern string) *corsPolicy {
+	policies.mu.RLock()
+	defer policies.mu.RUnlock()
+
+	if policy, found := policies.routes[pattern]; found && pattern != "" {
+		return policy
+	}
+
+	return policies.defaultPolicy
+}
+
+// CORS is a decorator/middleware that handles Cross-Origin Resource Sharing,
+// answering preflight requests and setting CORS headers on actual requests.
+//
+// The policy is chosen by the matched [http.ServeMux] pattern (see [CORSPolicies.Attach]).
+// As preflight requests (OPTIONS) usually do not match any route,
+// this middleware should decorate the mux itself, case in which the pattern is resolved through it.
+func CORS(next http.Handler, policies *CORSPolicies) http.Handler {
+	mux, _ := next.(*http.ServeMux)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			origin      = r.Header.Get("Origin")
+			reqMethod   = r.Header.Get("Access-Control-Request-Method")
+			isPreflight = r.Method == http.MethodOptions && reqMethod != ""
+			pattern     = r.Pattern
+		)
+		if pattern == "" && mux != nil {
+			muxReq := r
+			if isPreflight {
+				muxReq = r.Clone(r.Context())
+				muxReq.Method = reqMethod
+			}
+			_, pattern = mux.Handler(muxReq)
+		}
+		policy := policies.lookup(pattern)
+
+		if isPreflight {
+			w.Header().Add("Vary", "Origin")
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			reqHeaders := r.Header.Get("Access-Control-Request-Headers")
+			if origin != "" && policy.isOriginAllowed(origin) &&
+				slices.Contains(policy.allowedMethods, reqMethod) &&
+				policy.areHeadersAllowed(reqHeaders) {
+				policy.setAllowOrigin(w.Header(), origin)
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.allowedMethods, ", "))
+				if policy.allowAllHeaders {
+					if reqHeaders != "" {
+						w.Header().Set("Access-Control-Allow-Headers", reqHeaders)
+					}
+				} else if policy.allowedHeadersStr != "" {
+					w.Header().Set("Access-Control-Allow-Headers", policy.allowedHeadersStr)
+				}
+				if policy.maxAge != "" {
+					w.Header().Set("Access-Control-Max-Age", policy.maxAge)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		if !policy.allowAllOrigins || policy.allowCredentials {
+			w.Header().Add("Vary", "Origin") // response differs by origin.
+		}
+		if origin != "" && policy.isOriginAllowed(origin) {
+			policy.setAllowOrigin(w.Header(), origin)
+			w.Header().Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
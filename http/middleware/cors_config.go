@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"slices"
+	"time"
+
+	"github.com/actforgood/xconf"
+	"github.com/actforgood/xerr"
+)
+
+// NewXConfCORSPolicies instantiates a new CORS policies holder, configured from given xconf.Config key.
+// If the config is a [xconf.DefaultConfig], policies are reloaded when the key's value changes;
+// an invalid new value is disregarded, previous policies being kept.
+//
+// The expected config value structure is (yaml example):
+//
+//	cors:
+//	  default:
+//	    allowedOrigins: ["https://example.com", "https://*.example.com"]
+//	    allowedMethods: ["GET", "POST"]
+//	    allowedHeaders: ["Content-Type", "Authorization"]
+//	    exposedHeaders: ["X-Total-Count"]
+//	    allowCredentials: true
+//	    maxAge: 10m # or no. of seconds
+//	  routes:
+//	    "GET /public/":
+//	      allowedOrigins: ["*"]
+func NewXConfCORSPolicies(config xconf.Config, corsKey string) (*CORSPolicies, error) {
+	defaultPolicy, routes, err := corsPoliciesFromConfig(config.Get(corsKey))
+	if err != nil {
+		return nil, err
+	}
+	policies := NewCORSPolicies(CORSPolicy{})
+	policies.Replace(defaultPolicy, routes)
+
+	if defConfig, ok := config.(*xconf.DefaultConfig); ok {
+		defConfig.RegisterObserver(func(cfg xconf.Config, changedKeys ...string) {
+			if !slices.Contains(changedKeys, corsKey) {
+				return
+			}
+			if defaultPolicy, routes, err := corsPoliciesFromConfig(cfg.Get(corsKey)); err == nil {
+				policies.Replace(defaultPolicy, routes)
+			}
+		})
+	}
+
+	return policies, nil
+}
+
+func corsPoliciesFromConfig(value any) (CORSPolicy, map[string]CORSPolicy, error) {
+	var defaultPolicy CORSPolicy
+	cfgMap, ok := value.(map[string]any)
+	if !ok {
+		return defaultPolicy, nil, xerr.New("cors config should be a map")
+	}
+
+	var err error
+	if defaultPolicyValue, found := cfgMap["default"]; found {
+		if defaultPolicy, err = corsPolicyFromConfig(defaultPolicyValue); err != nil {
+			return defaultPolicy, nil, xerr.Wrap(err, "invalid default cors policy")
+		}
+	}
+
+	routes := make(map[string]CORSPolicy)
+	if routesValue, found := cfgMap["routes"]; found {
+		routesMap, ok := routesValue.(map[string]any)
+		if !ok {
+			return defaultPolicy, nil, xerr.New("cors routes config should be a map")
+		}
+		for pattern, policyValue := range routesMap {
+			if routes[pattern], err = corsPolicyFromConfig(policyValue); err != nil {
+				return defaultPolicy, nil, xerr.Wrapf(err, "invalid cors policy for route %q", pattern)
+			}
+		}
+	}
+
+	return defaultPolicy, routes, nil
+}
+
+func corsPolicyFromConfig(value any) (CORSPolicy, error) {
+	var (
+		policy CORSPolicy
+		err    error
+	)
+	policyMap, ok := value.(map[string]any)
+	if !ok {
+		return policy, xerr.New("cors policy should be a map")
+	}
+	if policy.AllowedOrigins, err = stringsFromConfig(policyMap["allowedOrigins"]); err != nil {
+		return policy, xerr.Wrap(err, "invalid allowedOrigins")
+	}
+	if policy.AllowedMethods, err = stringsFromConfig(policyMap["allowedMethods"]); err != nil {
+		return policy, xerr.Wrap(err, "invalid allowedMethods")
+	}
+	if policy.AllowedHeaders, err = stringsFromConfig(policyMap["allowedHeaders"]); err != nil {
+		return policy, xerr.Wrap(err, "invalid allowedHeaders")
+	}
+	if policy.ExposedHeaders, err = stringsFromConfig(policyMap["exposedHeaders"]); err != nil {
+		return policy, xerr.Wrap(err, "invalid exposedHeaders")
+	}
+	if allowCredentials, found := policyMap["allowCredentials"]; found {
+		if policy.AllowCredentials, ok = allowCredentials.(bool); !ok {
+			return policy, xerr.New("invalid allowCredentials")
+		}
+	}
+	switch maxAge := policyMap["maxAge"].(type) {
+	case nil:
+	case string:
+		if policy.MaxAge, err = time.ParseDuration(maxAge); err != nil {
+			return policy, xerr.Wrap(err, "invalid maxAge")
+		}
+	case int:
+		policy.MaxAge = time.Duration(maxAge) * time.Second
+	case int64:
+		policy.MaxAge = time.Duration(maxAge) * time.Second
+	case float64:
+		policy.MaxAge = time.Duration(maxAge * float64(time.Second))
+	case time.Duration:
+		policy.MaxAge = maxAge
+	default:
+		return policy, xerr.New("invalid maxAge")
+	}
+
+	return policy, nil
+}
+
+func stringsFromConfig(value any) ([]string, error) {
+	switch values := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return values, nil
+	case []any:
+		strs := make([]string, 0, len(values))
+		for _, v := range values {
+			str, ok := v.(string)
+			if !ok {
+				return nil, xerr.New("value should be a list of strings")
+			}
+			strs = append(strs, str)
+		}
+
+		return strs, nil
+	default:
+		return nil, xerr.New("value should be a list of strings")
+	}
+}
@@ -0,0 +1,293 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xconf"
+
+	"github.com/actforgood/xtransport"
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("preflight request is answered for allowed origin", testCORSPreflightAllowed)
+	t.Run("preflight request is answered without CORS headers for not allowed origin", testCORSPreflightNotAllowed)
+	t.Run("preflight request with not allowed header", testCORSPreflightHeaderNotAllowed)
+	t.Run("actual request gets CORS headers", testCORSActualRequest)
+	t.Run("any origin with credentials reflects origin", testCORSAnyOriginWithCredentials)
+	t.Run("policy is chosen by mux pattern", testCORSPolicyPerPattern)
+}
+
+func testCORSPreflightAllowed(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandlerCallsCnt int
+		nextHandler         = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			nextHandlerCallsCnt++
+		})
+		policies = middleware.NewCORSPolicies(middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://*.example.com"},
+			AllowedMethods: []string{"GET", "PUT"},
+			AllowedHeaders: []string{"content-type", "Authorization"},
+			MaxAge:         10 * time.Minute,
+		})
+		req = httptest.NewRequest(http.MethodOptions, "http://api.example.com/items", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, authorization")
+
+	// act
+	middleware.CORS(nextHandler, policies).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, 0, nextHandlerCallsCnt)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, PUT", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(
+		t,
+		[]string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		w.Header().Values("Vary"),
+	)
+}
+
+func testCORSPreflightNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})
+		policies    = middleware.NewCORSPolicies(middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://*.example.com"},
+		})
+		req = httptest.NewRequest(http.MethodOptions, "http://api.example.com/items", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("Origin", "https://example.com.evil.org")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	// act
+	middleware.CORS(nextHandler, policies).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func testCORSPreflightHeaderNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})
+		policies    = middleware.NewCORSPolicies(middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://example.com"},
+			AllowedHeaders: []string{"Content-Type"},
+		})
+		req = httptest.NewRequest(http.MethodOptions, "http://api.example.com/items", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+
+	// act
+	middleware.CORS(nextHandler, policies).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func testCORSActualRequest(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandlerCallsCnt int
+		nextHandler         = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			nextHandlerCallsCnt++
+			w.WriteHeader(http.StatusOK)
+		})
+		policies = middleware.NewCORSPolicies(middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://example.com"},
+			ExposedHeaders: []string{"X-Total-Count"},
+		})
+		req = httptest.NewRequest(http.MethodGet, "http://api.example.com/items", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("Origin", "https://EXAMPLE.com")
+
+	// act
+	middleware.CORS(nextHandler, policies).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, 1, nextHandlerCallsCnt)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://EXAMPLE.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(
+		t,
+		"X-Total-Count, "+xtransport.CorrelationIDHeaderKey,
+		w.Header().Get("Access-Control-Expose-Headers"),
+	)
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+
+func testCORSAnyOriginWithCredentials(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		policies = middleware.NewCORSPolicies(middleware.CORSPolicy{
+			AllowedOrigins:   []string{"*"},
+			AllowCredentials: true,
+		})
+		req = httptest.NewRequest(http.MethodGet, "http://api.example.com/items", nil)
+		w   = httptest.NewRecorder()
+	)
+	req.Header.Set("Origin", "https://foo.org")
+
+	// act
+	middleware.CORS(nextHandler, policies).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, "https://foo.org", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+
+func testCORSPolicyPerPattern(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		mux      = http.NewServeMux()
+		policies = middleware.NewCORSPolicies(middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://example.com"},
+		})
+		subject = middleware.CORS(mux, policies)
+	)
+	mux.HandleFunc("GET /public/", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux.HandleFunc("GET /private/", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	policies.Attach("GET /public/", middleware.CORSPolicy{AllowedOrigins: []string{"*"}})
+
+	tests := [...]struct {
+		name           string
+		method         string
+		path           string
+		expectedOrigin string
+	}{
+		{"public preflight", http.MethodOptions, "/public/foo", "*"},
+		{"public actual", http.MethodGet, "/public/foo", "*"},
+		{"private preflight", http.MethodOptions, "/private/foo", ""},
+		{"private actual", http.MethodGet, "/private/foo", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "http://api.example.com"+test.path, nil)
+			req.Header.Set("Origin", "https://foo.org")
+			if test.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			w := httptest.NewRecorder()
+
+			// act
+			subject.ServeHTTP(w, req)
+
+			// assert
+			assert.Equal(t, test.expectedOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestNewXConfCORSPolicies(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid config returns error", testNewXConfCORSPoliciesInvalid)
+	t.Run("policies are reloaded", testNewXConfCORSPoliciesReload)
+}
+
+func testNewXConfCORSPoliciesInvalid(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	config := xconf.NewMockConfig("cors", map[string]any{
+		"default": map[string]any{"allowedOrigins": "not-a-list"},
+	})
+
+	// act
+	policies, err := middleware.NewXConfCORSPolicies(config, "cors")
+
+	// assert
+	assert.NotNil(t, err)
+	assert.Nil(t, policies)
+}
+
+func testNewXConfCORSPoliciesReload(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		allowedOrigin atomic.Value
+		loader        = xconf.LoaderFunc(func() (map[string]any, error) {
+			return map[string]any{
+				"cors": map[string]any{
+					"default": map[string]any{
+						"allowedOrigins":   []any{allowedOrigin.Load().(string)},
+						"allowCredentials": true,
+						"maxAge":           "1m",
+					},
+					"routes": map[string]any{
+						"GET /public/": map[string]any{"allowedOrigins": []any{"*"}, "maxAge": 30},
+					},
+				},
+			}, nil
+		})
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	)
+	allowedOrigin.Store("https://foo.org")
+	config, err := xconf.NewDefaultConfig(loader, xconf.DefaultConfigWithReloadInterval(10*time.Millisecond))
+	assert.RequireNil(t, err)
+	defer config.Close()
+	policies, err := middleware.NewXConfCORSPolicies(config, "cors")
+	assert.RequireNil(t, err)
+	subject := middleware.CORS(nextHandler, policies)
+	doRequest := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "http://api.example.com/items", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		subject.ServeHTTP(w, req)
+
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	// act & assert
+	assert.Equal(t, "https://foo.org", doRequest("https://foo.org"))
+	assert.Equal(t, "", doRequest("https://bar.org"))
+
+	allowedOrigin.Store("https://bar.org")
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, "", doRequest("https://foo.org"))
+	assert.Equal(t, "https://bar.org", doRequest("https://bar.org"))
+}
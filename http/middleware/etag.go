@@ -0,0 +1,300 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETagConfig holds the configuration for [ETag] middleware.
+type ETagConfig struct {
+	// Weak indicates whether generated ETags are weak validators ("W/" prefixed).
+	// Use weak ETags if the response may be compressed / transformed down the chain.
+	Weak bool
+	// MaxBodyBytes is the max response body size buffered in order to compute the ETag.
+	// Larger responses are streamed, without ETag. Defaults to 1Mb.
+	MaxBodyBytes int
+	// Cache, if set, is used to store full responses. Responses are cached according to
+	// their Cache-Control header and vary by headers given in Vary header.
+	// Requests with Cache-Control no-cache / no-store directives are not served from cache,
+	// and, for no-store, their responses are not stored either.
+	Cache ResponseCacheStore
+	// CacheTTL is the period responses without Cache-Control max-age / s-maxage directives
+	// are cached for. Defaults to 0, meaning such responses are not cached.
+	CacheTTL time.Duration
+}
+
+const defaultETagMaxBodyBytes = 1 << 20
+
+// ETag is a decorator/middleware that computes an ETag from the response body for 200 OK
+// responses to GET requests (unless an ETag was already set by the next handler),
+// and answers conditional GET / HEAD requests (If-None-Match, If-Modified-Since)
+// with 304 Not Modified if the resource was not modified.
+// Optionally, full responses are cached, see [ETagConfig].Cache.
+// If decorated by [AccessLog], 304 responses are logged as such.
+func ETag(next http.Handler, config ETagConfig) http.Handler {
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultETagMaxBodyBytes
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		var (
+			cacheKey   string
+			reqCC      = cacheControlDirectives(r.Header.Get("Cache-Control"))
+			_, noStore = reqCC["no-store"]
+			_, noCache = reqCC["no-cache"]
+			useCache   = config.Cache != nil && !noStore
+		)
+		if useCache {
+			cacheKey = responseCacheKey(r)
+		}
+		if useCache && !noCache {
+			if resp, found := getCachedResponse(r, config.Cache, cacheKey); found {
+				writeCachedResponse(w, r, resp)
+
+				return
+			}
+		}
+
+		var headerBefore http.Header
+		if useCache {
+			headerBefore = w.Header().Clone()
+		}
+		bw := &bufferingResponseWriter{ResponseWriter: w, limit: config.MaxBodyBytes}
+		next.ServeHTTP(bw, r)
+		if bw.passthrough {
+			return
+		}
+		if bw.statusCode == 0 {
+			bw.statusCode = http.StatusOK
+		}
+
+		header := w.Header()
+		if bw.statusCode == http.StatusOK && r.Method == http.MethodGet && header.Get("ETag") == "" {
+			header.Set("ETag", computeETag(bw.body.Bytes(), config.Weak))
+		}
+		if bw.statusCode == http.StatusOK && r.Method == http.MethodGet && useCache {
+			storeCachedResponse(r, config, cacheKey, headerDiff(headerBefore, header), bw.body.Bytes())
+		}
+		if bw.statusCode == http.StatusOK && isNotModified(r, header) {
+			writeNotModified(w)
+
+			return
+		}
+		bw.flushBuffered()
+	})
+}
+
+// computeETag returns an ETag for given content.
+func computeETag(content []byte, weak bool) string {
+	sum := sha256.Sum256(content)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+
+	return etag
+}
+
+// isNotModified evaluates request's If-None-Match / If-Modified-Since preconditions
+// against response's ETag / Last-Modified headers.
+func isNotModified(r *http.Request, header http.Header) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || etagWeakMatch(candidate, etag) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
+
+// etagWeakMatch compares 2 ETags using weak comparison (RFC 9110 8.8.3.2).
+func etagWeakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// writeNotModified sends a 304 Not Modified response, dropping representation headers.
+func writeNotModified(w http.ResponseWriter) {
+	header := w.Header()
+	for _, name := range [...]string{"Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding"} {
+		header.Del(name)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+func getCachedResponse(r *http.Request, cache ResponseCacheStore, cacheKey string) (CachedResponse, bool) {
+	resp, found := cache.Get(r.Context(), cacheKey)
+	if !found || resp.StatusCode != 0 {
+		return resp, found
+	}
+
+	// variants index entry.
+	return cache.Get(r.Context(), responseCacheVariantKey(cacheKey, r, resp.Vary))
+}
+
+// storeCachedResponse stores the response, having given header, which should hold only the headers
+// set by the handler (and not the per request ones set by outer middlewares, like a correlation id).
+func storeCachedResponse(r *http.Request, config ETagConfig, cacheKey string, header http.Header, body []byte) {
+	ttl := responseCacheTTL(r, header, config.CacheTTL)
+	if ttl <= 0 {
+		return
+	}
+	vary, cacheable := parseVary(header)
+	if !cacheable {
+		return
+	}
+	now := time.Now()
+	if len(vary) > 0 {
+		config.Cache.Set(r.Context(), cacheKey, CachedResponse{Vary: vary, StoredAt: now}, ttl)
+	}
+	config.Cache.Set(r.Context(), responseCacheVariantKey(cacheKey, r, vary), CachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       slices.Clone(body),
+		StoredAt:   now,
+	}, ttl)
+}
+
+// writeCachedResponse writes the cached response. Headers already set upon the response,
+// by outer middlewares, are not overwritten.
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, resp CachedResponse) {
+	header := w.Header()
+	for name, values := range resp.Header {
+		if _, found := header[name]; !found {
+			header[name] = slices.Clone(values)
+		}
+	}
+	header.Set("Age", strconv.FormatInt(int64(time.Since(resp.StoredAt).Seconds()), 10))
+	if isNotModified(r, header) {
+		writeNotModified(w)
+
+		return
+	}
+	header.Set("Content-Length", strconv.Itoa(len(resp.Body)))
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+// headerDiff returns the headers which were added or changed in after, compared to before.
+func headerDiff(before, after http.Header) http.Header {
+	diff := make(http.Header)
+	for name, values := range after {
+		if !slices.Equal(before[name], values) {
+			diff[name] = slices.Clone(values)
+		}
+	}
+
+	return diff
+}
+
+// bufferingResponseWriter is a [http.ResponseWriter] decorator which buffers
+// 200 OK responses up to a limit. Other responses, larger ones, or flushed ones
+// are written through (passthrough mode).
+type bufferingResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	body        bytes.Buffer
+	limit       int
+	passthrough bool
+}
+
+// WriteHeader records the status code. Status codes other than 200 OK are sent through.
+func (w *bufferingResponseWriter) WriteHeader(code int) {
+	if w.statusCode != 0 {
+		return // superfluous call.
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+
+		return
+	}
+	w.statusCode = code
+	if code != http.StatusOK {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write buffers the data, or writes it through if in passthrough mode / limit is exceeded.
+func (w *bufferingResponseWriter) Write(data []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.body.Len()+len(data) > w.limit {
+		w.switchToPassthrough()
+
+		return w.ResponseWriter.Write(data)
+	}
+
+	return w.body.Write(data)
+}
+
+// Flush switches to passthrough mode, and flushes the decorated writer.
+// See [http.Flusher].
+func (w *bufferingResponseWriter) Flush() {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough {
+		w.switchToPassthrough()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the decorated writer.
+// It is used by [http.ResponseController].
+func (w *bufferingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bufferingResponseWriter) switchToPassthrough() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+}
+
+// flushBuffered writes the buffered response.
+func (w *bufferingResponseWriter) flushBuffered() {
+	if w.Header().Get("Content-Length") == "" && w.body.Len() > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
@@ -0,0 +1,461 @@
+package middleware_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport"
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ETag is computed and If-None-Match is honoured", testETagIfNoneMatch)
+	t.Run("weak ETag", testETagWeak)
+	t.Run("If-Modified-Since is honoured", testETagIfModifiedSince)
+	t.Run("non 200 responses are not touched", testETagNon200)
+	t.Run("large responses are streamed", testETagLargeResponse)
+	t.Run("304 is recorded by access log", testETagWithAccessLog)
+	t.Run("responses are cached", testETagCache)
+	t.Run("not cacheable responses", testETagCacheNotCacheable)
+	t.Run("cached responses vary by headers", testETagCacheVary)
+	t.Run("no-store requests bypass the cache", testETagCacheNoStoreRequest)
+	t.Run("per request headers are not cached", testETagCachePerRequestHeaders)
+}
+
+func itemsHandler(callsCnt *atomic.Int32, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		callsCnt.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+func testETagIfNoneMatch(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		subject  = middleware.ETag(itemsHandler(&callsCnt, `{"items":[1,2,3]}`), middleware.ETagConfig{})
+		w1       = httptest.NewRecorder()
+		w2       = httptest.NewRecorder()
+		w3       = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+	etag := w1.Header().Get("ETag")
+	req2 := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req2.Header.Set("If-None-Match", `"other", `+etag)
+	subject.ServeHTTP(w2, req2)
+	req3 := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req3.Header.Set("If-None-Match", `"other"`)
+	subject.ServeHTTP(w3, req3)
+
+	// assert
+	assert.Equal(t, http.StatusOK, w1.Code)
+	assert.Equal(t, `{"items":[1,2,3]}`, w1.Body.String())
+	assert.Equal(t, "17", w1.Header().Get("Content-Length"))
+	assert.True(t, strings.HasPrefix(etag, `"`))
+	assert.Equal(t, http.StatusNotModified, w2.Code)
+	assert.Equal(t, "", w2.Body.String())
+	assert.Equal(t, etag, w2.Header().Get("ETag"))
+	assert.Equal(t, "", w2.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusOK, w3.Code)
+	assert.Equal(t, `{"items":[1,2,3]}`, w3.Body.String())
+	assert.Equal(t, int32(3), callsCnt.Load())
+}
+
+func testETagWeak(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		subject  = middleware.ETag(itemsHandler(&callsCnt, `{"items":[]}`), middleware.ETagConfig{Weak: true})
+		w1       = httptest.NewRecorder()
+		w2       = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+	etag := w1.Header().Get("ETag")
+	req2 := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req2.Header.Set("If-None-Match", strings.TrimPrefix(etag, "W/")) // weak comparison is used
+	subject.ServeHTTP(w2, req2)
+
+	// assert
+	assert.True(t, strings.HasPrefix(etag, `W/"`))
+	assert.Equal(t, http.StatusNotModified, w2.Code)
+}
+
+func testETagIfModifiedSince(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		lastModified = time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+		nextHandler  = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+			_, _ = w.Write([]byte("report"))
+		})
+		subject = middleware.ETag(nextHandler, middleware.ETagConfig{})
+	)
+	tests := [...]struct {
+		name            string
+		ifModifiedSince time.Time
+		expectedStatus  int
+	}{
+		{"not modified since", lastModified.Add(time.Hour), http.StatusNotModified},
+		{"not modified since, same time", lastModified, http.StatusNotModified},
+		{"modified since", lastModified.Add(-time.Hour), http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/report", nil)
+			req.Header.Set("If-Modified-Since", test.ifModifiedSince.Format(http.TimeFormat))
+			w := httptest.NewRecorder()
+
+			// act
+			subject.ServeHTTP(w, req)
+
+			// assert
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func testETagNon200(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+		})
+		subject = middleware.ETag(nextHandler, middleware.ETagConfig{})
+		req     = httptest.NewRequest(http.MethodGet, "http://example.com/items/1", nil)
+		w       = httptest.NewRecorder()
+	)
+	req.Header.Set("If-None-Match", "*")
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not found", w.Body.String())
+	assert.Equal(t, "", w.Header().Get("ETag"))
+}
+
+func testETagLargeResponse(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		body     = strings.Repeat("a", 100)
+		subject  = middleware.ETag(itemsHandler(&callsCnt, body), middleware.ETagConfig{MaxBodyBytes: 10})
+		w        = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, "", w.Header().Get("ETag"))
+}
+
+func testETagWithAccessLog(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt   atomic.Int32
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.AccessLog(
+			middleware.ETag(itemsHandler(&callsCnt, `{"items":[]}`), middleware.ETagConfig{}),
+			slog.New(loggerMock),
+			nil,
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+	subject.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req.Header.Set("If-None-Match", w1.Header().Get("ETag"))
+
+	// act
+	subject.ServeHTTP(w2, req)
+
+	// assert
+	assert.Equal(t, http.StatusNotModified, w2.Code)
+	if assert.Equal(t, 2, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, int64(http.StatusOK), loggerMock.ValueAt(1, "statusCode"))
+		assert.Equal(t, int64(http.StatusNotModified), loggerMock.ValueAt(2, "statusCode"))
+		assert.Equal(t, int64(0), loggerMock.ValueAt(2, "respBodyLength"))
+	}
+}
+
+func testETagCache(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt    atomic.Int32
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			callsCnt.Add(1)
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		})
+		cache   = middleware.NewMemoryResponseCache(10)
+		subject = middleware.ETag(nextHandler, middleware.ETagConfig{Cache: cache})
+		w1      = httptest.NewRecorder()
+		w2      = httptest.NewRecorder()
+		w3      = httptest.NewRecorder()
+		w4      = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+	subject.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+	req3 := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req3.Header.Set("If-None-Match", w1.Header().Get("ETag"))
+	subject.ServeHTTP(w3, req3)
+	req4 := httptest.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	req4.Header.Set("Cache-Control", "no-cache")
+	subject.ServeHTTP(w4, req4)
+
+	// assert
+	assert.Equal(t, int32(2), callsCnt.Load())
+	assert.Equal(t, http.StatusOK, w2.Code)
+	assert.Equal(t, `{"items":[]}`, w2.Body.String())
+	assert.Equal(t, w1.Header().Get("ETag"), w2.Header().Get("ETag"))
+	assert.Equal(t, "0", w2.Header().Get("Age"))
+	assert.Equal(t, http.StatusNotModified, w3.Code)
+	assert.Equal(t, http.StatusOK, w4.Code)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func testETagCacheNotCacheable(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name         string
+		cacheControl string
+		setCookie    bool
+		authorized   bool
+		cacheTTL     time.Duration
+	}{
+		{name: "no-store", cacheControl: "no-store"},
+		{name: "private", cacheControl: "private, max-age=60"},
+		{name: "no cache control and no default ttl"},
+		{name: "set cookie", cacheControl: "max-age=60", setCookie: true},
+		{name: "authorized request", cacheControl: "max-age=60", authorized: true},
+		{name: "zero max-age", cacheControl: "max-age=0", cacheTTL: time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				callsCnt    atomic.Int32
+				nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					callsCnt.Add(1)
+					if test.cacheControl != "" {
+						w.Header().Set("Cache-Control", test.cacheControl)
+					}
+					if test.setCookie {
+						w.Header().Set("Set-Cookie", "session=abc")
+					}
+					_, _ = w.Write([]byte("content"))
+				})
+				cache   = middleware.NewMemoryResponseCache(10)
+				subject = middleware.ETag(nextHandler, middleware.ETagConfig{Cache: cache, CacheTTL: test.cacheTTL})
+			)
+
+			// act
+			for range 2 {
+				req := httptest.NewRequest(http.MethodGet, "http://example.com/content", nil)
+				if test.authorized {
+					req.Header.Set("Authorization", "Bearer token")
+				}
+				subject.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			// assert
+			assert.Equal(t, int32(2), callsCnt.Load())
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+}
+
+func testETagCacheVary(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt    atomic.Int32
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			callsCnt.Add(1)
+			w.Header().Set("Vary", "Accept-Language")
+			_, _ = w.Write([]byte("content-" + r.Header.Get("Accept-Language")))
+		})
+		subject = middleware.ETag(
+			nextHandler,
+			middleware.ETagConfig{Cache: middleware.NewMemoryResponseCache(10), CacheTTL: time.Minute},
+		)
+		serve = func(lang string) string {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/content", nil)
+			req.Header.Set("Accept-Language", lang)
+			w := httptest.NewRecorder()
+			subject.ServeHTTP(w, req)
+
+			return w.Body.String()
+		}
+	)
+
+	// act & assert
+	assert.Equal(t, "content-en", serve("en"))
+	assert.Equal(t, "content-ro", serve("ro"))
+	assert.Equal(t, "content-en", serve("en"))
+	assert.Equal(t, "content-ro", serve("ro"))
+	assert.Equal(t, int32(2), callsCnt.Load())
+}
+
+func testETagCacheNoStoreRequest(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt    atomic.Int32
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			_, _ = w.Write([]byte("content-" + strconv.Itoa(int(callsCnt.Add(1)))))
+		})
+		cache   = middleware.NewMemoryResponseCache(10)
+		subject = middleware.ETag(nextHandler, middleware.ETagConfig{Cache: cache})
+		serve   = func(cacheControl string) string {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/content", nil)
+			req.Header.Set("Cache-Control", cacheControl)
+			w := httptest.NewRecorder()
+			subject.ServeHTTP(w, req)
+
+			return w.Body.String()
+		}
+	)
+
+	// act & assert
+	assert.Equal(t, "content-1", serve("no-store"))
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, "content-2", serve(""))
+	assert.Equal(t, "content-3", serve("no-store"))
+	assert.Equal(t, "content-2", serve(""))
+}
+
+func testETagCachePerRequestHeaders(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		correlationIDs = []string{"first", "second"}
+		callsCnt       atomic.Int32
+		nextHandler    = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			callsCnt.Add(1)
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		})
+		cache   = middleware.NewMemoryResponseCache(10)
+		subject = middleware.CorrelationID(
+			middleware.ETag(nextHandler, middleware.ETagConfig{Cache: cache}),
+			func() string {
+				id := correlationIDs[0]
+				correlationIDs = correlationIDs[1:]
+
+				return id
+			},
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+	subject.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "http://example.com/items", nil))
+
+	// assert
+	assert.Equal(t, int32(1), callsCnt.Load())
+	assert.Equal(t, []string{"first"}, w1.Header().Values(xtransport.CorrelationIDHeaderKey))
+	assert.Equal(t, []string{"second"}, w2.Header().Values(xtransport.CorrelationIDHeaderKey))
+	assert.Equal(t, "application/json", w2.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=60", w2.Header().Get("Cache-Control"))
+	assert.Equal(t, w1.Header().Get("ETag"), w2.Header().Get("ETag"))
+	assert.Equal(t, `{"items":[]}`, w2.Body.String())
+}
+
+func TestMemoryResponseCache(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewMemoryResponseCache(2)
+		ctx     = t.Context()
+		resp    = middleware.CachedResponse{StatusCode: http.StatusOK, Body: []byte("content")}
+	)
+
+	// act & assert
+	subject.Set(ctx, "key-1", resp, 20*time.Millisecond)
+	actual, found := subject.Get(ctx, "key-1")
+	assert.True(t, found)
+	assert.Equal(t, resp, actual)
+
+	subject.Set(ctx, "key-2", resp, time.Minute)
+	subject.Set(ctx, "key-3", resp, time.Minute)
+	assert.Equal(t, 2, subject.Len())
+
+	time.Sleep(30 * time.Millisecond)
+	subject.Set(ctx, "key-4", resp, 10*time.Millisecond)
+	assert.Equal(t, 2, subject.Len())
+	time.Sleep(20 * time.Millisecond)
+	_, found = subject.Get(ctx, "key-4")
+	assert.Equal(t, false, found)
+}
+
+func TestNewMemoryResponseCache_defaultMaxEntries(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewMemoryResponseCache(0)
+		ctx     = t.Context()
+		resp    = middleware.CachedResponse{StatusCode: http.StatusOK, Body: []byte("content")}
+	)
+
+	// act
+	for i := range 5 {
+		subject.Set(ctx, "key-"+strconv.Itoa(i), resp, time.Minute)
+	}
+
+	// assert
+	assert.Equal(t, 5, subject.Len())
+}
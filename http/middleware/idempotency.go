@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	httpTransport "github.com/actforgood/xtransport/http"
+)
+
+// IdempotencyConfig holds the configuration for [Idempotency] middleware.
+type IdempotencyConfig struct {
+	// Store stores the idempotency keys and the responses.
+	Store IdempotencyStore
+	// HeaderName is the header carrying the idempotency key. Defaults to "Idempotency-Key".
+	HeaderName string
+	// Methods is the list of methods subject to idempotency. Defaults to POST.
+	Methods []string
+	// Required, if set, rejects requests without idempotency key with 400 Bad Request.
+	Required bool
+	// TTL is the period a completed response is replayed for. Defaults to 24 hours.
+	TTL time.Duration
+	// InFlightTTL is the max period a key is held by a request in flight,
+	// protecting against keys locked forever by crashed processes. Defaults to 1 minute.
+	InFlightTTL time.Duration
+	// MaxBodyBytes is the max allowed request body size.
+	// Defaults to [httpTransport.GetRequestBody] default limit.
+	MaxBodyBytes int64
+}
+
+const (
+	defaultIdempotencyHeaderName  = "Idempotency-Key"
+	defaultIdempotencyTTL         = 24 * time.Hour
+	defaultIdempotencyInFlightTTL = time.Minute
+	maxIdempotencyKeyLen          = 255
+)
+
+// Idempotency is a decorator/middleware that makes requests carrying an idempotency key
+// safe to be retried.
+// The first response (status, headers, body) for a key is stored and replayed for
+// subsequent requests with the same key, with "Idempotent-Replayed: true" header.
+// A request with a key which is still in flight gets a 409 Conflict response.
+// A request reusing a key with a different payload (method, path, body) gets a 422 Unprocessable Entity response.
+// Keys are scoped to the principal, if one is found in context, see [PrincipalFromContext].
+// Server error (5xx) responses are not stored, so that the request can be retried.
+// Store errors are logged and a 500 Internal Server Error response is sent.
+func Idempotency(next http.Handler, config IdempotencyConfig, logger *slog.Logger) http.Handler {
+	if config.HeaderName == "" {
+		config.HeaderName = defaultIdempotencyHeaderName
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodPost}
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultIdempotencyTTL
+	}
+	if config.InFlightTTL <= 0 {
+		config.InFlightTTL = defaultIdempotencyInFlightTTL
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(config.Methods, r.Method) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+		idempotencyKey := r.Header.Get(config.HeaderName)
+		if idempotencyKey == "" {
+			if config.Required {
+				http.Error(w, "missing "+config.HeaderName+" header", http.StatusBadRequest)
+
+				return
+			}
+			next.ServeHTTP(w, r)
+
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLen {
+			http.Error(w, "invalid "+config.HeaderName+" header", http.StatusBadRequest)
+
+			return
+		}
+
+		body, err := io.ReadAll(httpTransport.GetRequestBody(w, r, config.MaxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			}
+
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+
+		var (
+			ctx         = r.Context()
+			storeKey    = idempotencyStoreKey(r, idempotencyKey)
+			fingerprint = requestFingerprint(r, body)
+		)
+		record, acquired, err := config.Store.Acquire(ctx, storeKey, fingerprint, config.InFlightTTL)
+		if err != nil {
+			logIdempotencyError(logger, r, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+		if !acquired {
+			switch {
+			case record.Fingerprint != fingerprint:
+				http.Error(
+					w,
+					config.HeaderName+" was already used with a different request",
+					http.StatusUnprocessableEntity,
+				)
+			case !record.Completed:
+				http.Error(w, "a request with the same "+config.HeaderName+" is in progress", http.StatusConflict)
+			default:
+				replayIdempotentResponse(w, record)
+			}
+
+			return
+		}
+
+		var (
+			recorder  = &responseRecorder{ResponseWriter: w}
+			rw        = NewResponseWriter(recorder)
+			completed bool
+		)
+		// release the key if request did not complete (panic), so that it can be retried.
+		defer func() {
+			if !completed {
+				if err := config.Store.Release(context.WithoutCancel(ctx), storeKey); err != nil {
+					logIdempotencyError(logger, r, err)
+				}
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+
+		if rw.StatusCode() >= http.StatusInternalServerError || rw.Hijacked() {
+			return
+		}
+		record = IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			StatusCode:  rw.StatusCode(),
+			Header:      recorder.header,
+			Body:        recorder.body.Bytes(),
+		}
+		if recorder.header == nil { // nothing was written explicitly.
+			record.Header = w.Header().Clone()
+		}
+		if err := config.Store.Complete(context.WithoutCancel(ctx), storeKey, record, config.TTL); err != nil {
+			logIdempotencyError(logger, r, err)
+
+			return
+		}
+		completed = true
+	})
+}
+
+// idempotencyStoreKey returns the key under which the request is stored,
+// scoping the idempotency key to the principal, if any.
+func idempotencyStoreKey(r *http.Request, idempotencyKey string) string {
+	if principal, found := PrincipalFromContext(r.Context()); found {
+		return principal.Method + ":" + principal.Subject + ":" + idempotencyKey
+	}
+
+	return idempotencyKey
+}
+
+// requestFingerprint returns a hash of request's method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hasher.Write(body)
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, record IdempotencyRecord) {
+	header := w.Header()
+	for name, values := range record.Header {
+		header[name] = slices.Clone(values)
+	}
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+func logIdempotencyError(logger *slog.Logger, r *http.Request, err error) {
+	logger.Error(
+		"idempotency store error",
+		"err", err,
+		"path", r.URL.Path,
+		"method", r.Method,
+	)
+}
+
+// responseRecorder is a [http.ResponseWriter] decorator which records the response
+// headers (as they were when status code was sent) and body, while writing them through.
+type responseRecorder struct {
+	http.ResponseWriter
+	header http.Header
+	body   bytes.Buffer
+}
+
+// WriteHeader records the headers and sends the status code through the decorated writer.
+func (rec *responseRecorder) WriteHeader(code int) {
+	if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the data and writes it to the decorated writer.
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.body.Write(data)
+
+	return rec.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the decorated writer.
+// It is used by [http.ResponseController].
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the state associated with an idempotency key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string
+	// Completed indicates whether the request was processed, the response fields being available.
+	// A not completed record means the request is still in flight.
+	Completed bool
+	// StatusCode is the response status code.
+	StatusCode int
+	// Header is the response header.
+	Header http.Header
+	// Body is the response body.
+	Body []byte
+}
+
+// IdempotencyStore is the contract for an idempotency keys store.
+// Implementations must make Acquire atomic, as concurrent requests
+// with the same key may race for it.
+type IdempotencyStore interface {
+	// Acquire stores an in flight record with given fingerprint for the key, expiring after ttl,
+	// if the key is not already present. In that case, acquired is true.
+	// Otherwise, the existing record is returned, and acquired is false.
+	Acquire(
+		ctx context.Context,
+		key, fingerprint string,
+		ttl time.Duration,
+	) (record IdempotencyRecord, acquired bool, err error)
+	// Complete stores the completed record for the key, expiring after ttl.
+	Complete(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	// Release removes the key, allowing the request to be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	record    IdempotencyRecord
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore is an in-memory [IdempotencyStore].
+// Expired keys are evicted lazily.
+// It is concurrent safe to use.
+type MemoryIdempotencyStore struct {
+	entries   map[string]idempotencyEntry
+	nextSweep time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// NewMemoryIdempotencyStore instantiates a new in-memory idempotency store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries: make(map[string]idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// Acquire ...see [IdempotencyStore.Acquire].
+func (store *MemoryIdempotencyStore) Acquire(
+	_ context.Context,
+	key, fingerprint string,
+	ttl time.Duration,
+) (IdempotencyRecord, bool, error) {
+	now := store.now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.After(store.nextSweep) {
+		for k, entry := range store.entries {
+			if now.After(entry.expiresAt) {
+				delete(store.entries, k)
+			}
+		}
+		store.nextSweep = now.Add(ttl)
+	}
+
+	if entry, found := store.entries[key]; found && !now.After(entry.expiresAt) {
+		return entry.record, false, nil
+	}
+	record := IdempotencyRecord{Fingerprint: fingerprint}
+	store.entries[key] = idempotencyEntry{record: record, expiresAt: now.Add(ttl)}
+
+	return record, true, nil
+}
+
+// Complete ...see [IdempotencyStore.Complete].
+func (store *MemoryIdempotencyStore) Complete(
+	_ context.Context,
+	key string,
+	record IdempotencyRecord,
+	ttl time.Duration,
+) error {
+	now := store.now()
+
+	store.mu.Lock()
+	store.entries[key] = idempotencyEntry{record: record, expiresAt: now.Add(ttl)}
+	store.mu.Unlock()
+
+	return nil
+}
+
+// Release ...see [IdempotencyStore.Release].
+func (store *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	store.mu.Lock()
+	delete(store.entries, key)
+	store.mu.Unlock()
+
+	return nil
+}
+
+// Len returns the no. of keys currently stored.
+func (store *MemoryIdempotencyStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return len(store.entries)
+}
@@ -0,0 +1,354 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestIdempotency(t *testing.T) {
+	t.Parallel()
+
+	t.Run("duplicate request is replayed", testIdempotencyReplay)
+	t.Run("concurrent duplicate request gets conflict", testIdempotencyConcurrentDuplicate)
+	t.Run("key reused with different payload", testIdempotencyFingerprintMismatch)
+	t.Run("server error response is not stored", testIdempotencyServerError)
+	t.Run("panic releases the key", testIdempotencyPanic)
+	t.Run("requests without key", testIdempotencyWithoutKey)
+	t.Run("keys are scoped to principal", testIdempotencyPrincipalScope)
+	t.Run("store error", testIdempotencyStoreError)
+}
+
+func newIdempotentRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	return req
+}
+
+// orderHandler returns a handler which creates an order, counting the calls.
+func orderHandler(callsCnt *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callsCnt.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/orders/123")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":123,"request":` + string(body) + `}`))
+	})
+}
+
+func testIdempotencyReplay(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		store    = middleware.NewMemoryIdempotencyStore()
+		subject  = middleware.Idempotency(
+			orderHandler(&callsCnt),
+			middleware.IdempotencyConfig{Store: store},
+			slog.New(mock.NewSlogHandler()),
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, newIdempotentRequest("key-1", `{"qty":1}`))
+	subject.ServeHTTP(w2, newIdempotentRequest("key-1", `{"qty":1}`))
+
+	// assert
+	assert.Equal(t, int32(1), callsCnt.Load())
+	assert.Equal(t, http.StatusCreated, w1.Code)
+	assert.Equal(t, `{"id":123,"request":{"qty":1}}`, w1.Body.String())
+	assert.Equal(t, "", w1.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, http.StatusCreated, w2.Code)
+	assert.Equal(t, w1.Body.String(), w2.Body.String())
+	assert.Equal(t, "/orders/123", w2.Header().Get("Location"))
+	assert.Equal(t, "application/json", w2.Header().Get("Content-Type"))
+	assert.Equal(t, "true", w2.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, store.Len())
+}
+
+func testIdempotencyConcurrentDuplicate(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		started     = make(chan struct{})
+		unblock     = make(chan struct{})
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			<-unblock
+			w.WriteHeader(http.StatusCreated)
+		})
+		subject = middleware.Idempotency(
+			nextHandler,
+			middleware.IdempotencyConfig{Store: middleware.NewMemoryIdempotencyStore()},
+			slog.New(mock.NewSlogHandler()),
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+		wg sync.WaitGroup
+	)
+	wg.Go(func() {
+		subject.ServeHTTP(w1, newIdempotentRequest("key-1", `{"qty":1}`))
+	})
+	<-started
+
+	// act
+	subject.ServeHTTP(w2, newIdempotentRequest("key-1", `{"qty":1}`))
+	close(unblock)
+	wg.Wait()
+
+	// assert
+	assert.Equal(t, http.StatusCreated, w1.Code)
+	assert.Equal(t, http.StatusConflict, w2.Code)
+}
+
+func testIdempotencyFingerprintMismatch(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		subject  = middleware.Idempotency(
+			orderHandler(&callsCnt),
+			middleware.IdempotencyConfig{Store: middleware.NewMemoryIdempotencyStore()},
+			slog.New(mock.NewSlogHandler()),
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, newIdempotentRequest("key-1", `{"qty":1}`))
+	subject.ServeHTTP(w2, newIdempotentRequest("key-1", `{"qty":2}`))
+
+	// assert
+	assert.Equal(t, int32(1), callsCnt.Load())
+	assert.Equal(t, http.StatusCreated, w1.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, w2.Code)
+}
+
+func testIdempotencyServerError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt    atomic.Int32
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if callsCnt.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+		store   = middleware.NewMemoryIdempotencyStore()
+		subject = middleware.Idempotency(
+			nextHandler,
+			middleware.IdempotencyConfig{Store: store},
+			slog.New(mock.NewSlogHandler()),
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, newIdempotentRequest("key-1", `{"qty":1}`))
+	subject.ServeHTTP(w2, newIdempotentRequest("key-1", `{"qty":1}`))
+
+	// assert
+	assert.Equal(t, int32(2), callsCnt.Load())
+	assert.Equal(t, http.StatusServiceUnavailable, w1.Code)
+	assert.Equal(t, http.StatusCreated, w2.Code)
+	assert.Equal(t, 1, store.Len())
+}
+
+func testIdempotencyPanic(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("intentionally triggered panic")
+		})
+		store   = middleware.NewMemoryIdempotencyStore()
+		subject = middleware.Idempotency(
+			nextHandler,
+			middleware.IdempotencyConfig{Store: store},
+			slog.New(mock.NewSlogHandler()),
+		)
+		w = httptest.NewRecorder()
+	)
+
+	// act
+	func() {
+		defer func() { _ = recover() }()
+		subject.ServeHTTP(w, newIdempotentRequest("key-1", `{"qty":1}`))
+	}()
+
+	// assert
+	assert.Equal(t, 0, store.Len())
+}
+
+func testIdempotencyWithoutKey(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		subject  = middleware.Idempotency(
+			orderHandler(&callsCnt),
+			middleware.IdempotencyConfig{Store: middleware.NewMemoryIdempotencyStore()},
+			slog.New(mock.NewSlogHandler()),
+		)
+		requiredSubject = middleware.Idempotency(
+			orderHandler(&callsCnt),
+			middleware.IdempotencyConfig{Store: middleware.NewMemoryIdempotencyStore(), Required: true},
+			slog.New(mock.NewSlogHandler()),
+		)
+		getReq = httptest.NewRequest(http.MethodGet, "http://example.com/orders", nil)
+		w1     = httptest.NewRecorder()
+		w2     = httptest.NewRecorder()
+		w3     = httptest.NewRecorder()
+		w4     = httptest.NewRecorder()
+	)
+	getReq.Header.Set("Idempotency-Key", "key-1")
+
+	// act
+	subject.ServeHTTP(w1, newIdempotentRequest("", `{"qty":1}`))
+	subject.ServeHTTP(w2, newIdempotentRequest("", `{"qty":1}`))
+	requiredSubject.ServeHTTP(w3, newIdempotentRequest("", `{"qty":1}`))
+	requiredSubject.ServeHTTP(w4, getReq)
+
+	// assert
+	assert.Equal(t, int32(3), callsCnt.Load())
+	assert.Equal(t, http.StatusCreated, w1.Code)
+	assert.Equal(t, http.StatusCreated, w2.Code)
+	assert.Equal(t, http.StatusBadRequest, w3.Code)
+	assert.Equal(t, http.StatusCreated, w4.Code)
+}
+
+func testIdempotencyPrincipalScope(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt atomic.Int32
+		subject  = middleware.Idempotency(
+			orderHandler(&callsCnt),
+			middleware.IdempotencyConfig{Store: middleware.NewMemoryIdempotencyStore()},
+			slog.New(mock.NewSlogHandler()),
+		)
+		reqAsUser = func(user string) *http.Request {
+			req := newIdempotentRequest("key-1", `{"qty":1}`)
+
+			return req.WithContext(middleware.ContextWithPrincipal(
+				req.Context(),
+				middleware.Principal{Subject: user, Method: "basic"},
+			))
+		}
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, reqAsUser("john"))
+	subject.ServeHTTP(w2, reqAsUser("jane"))
+
+	// assert
+	assert.Equal(t, int32(2), callsCnt.Load())
+	assert.Equal(t, "", w2.Header().Get("Idempotent-Replayed"))
+}
+
+func testIdempotencyStoreError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		callsCnt   atomic.Int32
+		loggerMock = mock.NewSlogHandler()
+		subject    = middleware.Idempotency(
+			orderHandler(&callsCnt),
+			middleware.IdempotencyConfig{Store: errIdempotencyStore{}},
+			slog.New(loggerMock),
+		)
+		w = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, newIdempotentRequest("key-1", `{"qty":1}`))
+
+	// assert
+	assert.Equal(t, int32(0), callsCnt.Load())
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewMemoryIdempotencyStore()
+		ctx     = context.Background()
+	)
+
+	// act & assert
+	record, acquired, err := subject.Acquire(ctx, "key-1", "fp-1", 20*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, middleware.IdempotencyRecord{Fingerprint: "fp-1"}, record)
+
+	record, acquired, _ = subject.Acquire(ctx, "key-1", "fp-2", 20*time.Millisecond)
+	assert.Equal(t, false, acquired)
+	assert.Equal(t, "fp-1", record.Fingerprint)
+
+	time.Sleep(30 * time.Millisecond)
+	_, acquired, _ = subject.Acquire(ctx, "key-1", "fp-2", time.Minute)
+	assert.True(t, acquired)
+
+	completedRecord := middleware.IdempotencyRecord{Fingerprint: "fp-2", Completed: true, StatusCode: 201}
+	assert.Nil(t, subject.Complete(ctx, "key-1", completedRecord, time.Minute))
+	record, acquired, _ = subject.Acquire(ctx, "key-1", "fp-2", time.Minute)
+	assert.Equal(t, false, acquired)
+	assert.Equal(t, completedRecord, record)
+
+	assert.Nil(t, subject.Release(ctx, "key-1"))
+	assert.Equal(t, 0, subject.Len())
+}
+
+type errIdempotencyStore struct{}
+
+func (errIdempotencyStore) Acquire(
+	context.Context,
+	string, string,
+	time.Duration,
+) (middleware.IdempotencyRecord, bool, error) {
+	return middleware.IdempotencyRecord{}, false, errors.New("intentionally triggered store error")
+}
+
+func (errIdempotencyStore) Complete(context.Context, string, middleware.IdempotencyRecord, time.Duration) error {
+	return errors.New("intentionally triggered store error")
+}
+
+func (errIdempotencyStore) Release(context.Context, string) error {
+	return errors.New("intentionally triggered store error")
+}
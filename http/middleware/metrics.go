@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/actforgood/xtransport/metrics"
+)
+
+// unmatchedRoute is the route label value for requests not matched by a [http.ServeMux] pattern.
+const unmatchedRoute = "unmatched"
+
+// HTTPRequestMetrics holds the metrics of a served request.
+type HTTPRequestMetrics struct {
+	// Method is the request method, or "OTHER" for non standard methods.
+	Method string
+	// Route is the matched [http.ServeMux] pattern, or "unmatched".
+	Route string
+	// StatusClass is the response status class, like "2xx".
+	StatusClass string
+	// Duration is the request processing duration.
+	Duration time.Duration
+	// RequestSize is the request body size (Content-Length, or bytes read if unknown).
+	RequestSize int64
+	// ResponseSize is the no. of response body bytes written.
+	ResponseSize int64
+}
+
+// HTTPMetricsRecorder records HTTP requests metrics.
+type HTTPMetricsRecorder interface {
+	// RequestStarted is called when a request starts being served.
+	RequestStarted(method string)
+	// RequestFinished is called when a request was served.
+	RequestFinished(requestMetrics HTTPRequestMetrics)
+}
+
+// HTTPMetricsRecorderFuncs is an [HTTPMetricsRecorder] adapter which calls given functions.
+// It can be used to record metrics with another library, like the official Prometheus client:
+//
+//	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "http_requests_in_flight"}, []string{"method"})
+//	duration := prometheus.NewHistogramVec(
+//	    prometheus.HistogramOpts{Name: "http_request_duration_seconds"},
+//	    []string{"method", "route", "status"},
+//	)
+//	prometheus.MustRegister(inFlight, duration)
+//	recorder := middleware.HTTPMetricsRecorderFuncs{
+//	    OnRequestStarted: func(method string) {
+//	        inFlight.WithLabelValues(method).Inc()
+//	    },
+//	    OnRequestFinished: func(m middleware.HTTPRequestMetrics) {
+//	        inFlight.WithLabelValues(m.Method).Dec()
+//	        duration.WithLabelValues(m.Method, m.Route, m.StatusClass).Observe(m.Duration.Seconds())
+//	    },
+//	}
+type HTTPMetricsRecorderFuncs struct {
+	// OnRequestStarted is called by RequestStarted, if set.
+	OnRequestStarted func(method string)
+	// OnRequestFinished is called by RequestFinished, if set.
+	OnRequestFinished func(requestMetrics HTTPRequestMetrics)
+}
+
+// RequestStarted ...see [HTTPMetricsRecorder.RequestStarted].
+func (funcs HTTPMetricsRecorderFuncs) RequestStarted(method string) {
+	if funcs.OnRequestStarted != nil {
+		funcs.OnRequestStarted(method)
+	}
+}
+
+// RequestFinished ...see [HTTPMetricsRecorder.RequestFinished].
+func (funcs HTTPMetricsRecorderFuncs) RequestFinished(requestMetrics HTTPRequestMetrics) {
+	if funcs.OnRequestFinished != nil {
+		funcs.OnRequestFinished(requestMetrics)
+	}
+}
+
+// registryHTTPMetricsRecorder is an [HTTPMetricsRecorder] which records metrics in a [metrics.Registry].
+type registryHTTPMetricsRecorder struct {
+	requests     *metrics.CounterVec
+	duration     *metrics.HistogramVec
+	inFlight     *metrics.GaugeVec
+	requestSize  *metrics.HistogramVec
+	responseSize *metrics.HistogramVec
+}
+
+// NewRegistryHTTPMetricsRecorder instantiates a new [HTTPMetricsRecorder] which records, into given registry:
+//   - http_requests_total counter, labelled by method, route and status (class),
+//   - http_request_duration_seconds histogram, labelled by method, route and status (class),
+//   - http_requests_in_flight gauge, labelled by method,
+//   - http_request_size_bytes and http_response_size_bytes histograms, labelled by method, route and status (class).
+//
+// If duration buckets are not provided, [metrics.DefaultDurationBuckets] are used.
+func NewRegistryHTTPMetricsRecorder(registry *metrics.Registry, durationBuckets ...float64) HTTPMetricsRecorder {
+	if len(durationBuckets) == 0 {
+		durationBuckets = metrics.DefaultDurationBuckets
+	}
+	labels := []string{"method", "route", "status"}
+
+	return registryHTTPMetricsRecorder{
+		requests: registry.NewCounterVec(
+			"http_requests_total",
+			"Total number of HTTP requests.",
+			labels...,
+		),
+		duration: registry.NewHistogramVec(
+			"http_request_duration_seconds",
+			"HTTP requests duration in seconds.",
+			durationBuckets,
+			labels...,
+		),
+		inFlight: registry.NewGaugeVec(
+			"http_requests_in_flight",
+			"Number of HTTP requests currently being served.",
+			"method",
+		),
+		requestSize: registry.NewHistogramVec(
+			"http_request_size_bytes",
+			"HTTP requests body size in bytes.",
+			metrics.DefaultSizeBuckets,
+			labels...,
+		),
+		responseSize: registry.NewHistogramVec(
+			"http_response_size_bytes",
+			"HTTP responses body size in bytes.",
+			metrics.DefaultSizeBuckets,
+			labels...,
+		),
+	}
+}
+
+// RequestStarted ...see [HTTPMetricsRecorder.RequestStarted].
+func (rec registryHTTPMetricsRecorder) RequestStarted(method string) {
+	rec.inFlight.Add(1, method)
+}
+
+// RequestFinished ...see [HTTPMetricsRecorder.RequestFinished].
+func (rec registryHTTPMetricsRecorder) RequestFinished(m HTTPRequestMetrics) {
+	rec.inFlight.Add(-1, m.Method)
+	rec.requests.Inc(m.Method, m.Route, m.StatusClass)
+	rec.duration.Observe(m.Duration.Seconds(), m.Method, m.Route, m.StatusClass)
+	rec.requestSize.Observe(float64(m.RequestSize), m.Method, m.Route, m.StatusClass)
+	rec.responseSize.Observe(float64(m.ResponseSize), m.Method, m.Route, m.StatusClass)
+}
+
+// MetricsConfig holds the configuration for [Metrics] middleware.
+type MetricsConfig struct {
+	// Recorder records the metrics.
+	// Defaults to a recorder created with [NewRegistryHTTPMetricsRecorder] upon [metrics.DefaultRegistry].
+	Recorder HTTPMetricsRecorder
+}
+
+// Metrics is a decorator/middleware that records requests metrics: count, duration,
+// in-flight requests, request and response sizes.
+// Metrics are labelled by method, route pattern (r.Pattern, not the raw path,
+// to keep cardinality under control) and status class.
+// It should be placed outside a [http.ServeMux], in order to have access to the matched pattern.
+// If it does not decorate the mux directly, the mux must be decorated with [RoutePattern],
+// otherwise middlewares in between which replace the request hide the pattern,
+// and requests are labelled as "unmatched".
+func Metrics(next http.Handler, config MetricsConfig) http.Handler {
+	if config.Recorder == nil {
+		config.Recorder = NewRegistryHTTPMetricsRecorder(metrics.DefaultRegistry)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r = r.WithContext(contextWithRoutePatternHolder(r.Context()))
+		method := metricsMethod(r.Method)
+		config.Recorder.RequestStarted(method)
+		newW, ok := w.(*ResponseWriter)
+		if !ok {
+			newW = NewResponseWriter(w)
+		}
+		bytesWrittenBefore := newW.BytesWritten()
+		var bodyCounter *countingReadCloser
+		if r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody {
+			bodyCounter = &countingReadCloser{ReadCloser: r.Body}
+			r.Body = bodyCounter
+		}
+
+		defer func() {
+			requestSize := max(r.ContentLength, 0)
+			if bodyCounter != nil {
+				requestSize = bodyCounter.n
+			}
+			route := routePattern(r)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			config.Recorder.RequestFinished(HTTPRequestMetrics{
+				Method:       method,
+				Route:        route,
+				StatusClass:  strconv.Itoa(newW.StatusCode()/100) + "xx",
+				Duration:     time.Since(start),
+				RequestSize:  requestSize,
+				ResponseSize: newW.BytesWritten() - bytesWrittenBefore,
+			})
+		}()
+
+		next.ServeHTTP(newW, r)
+	})
+}
+
+// metricsMethod returns given method if it is a standard one, "OTHER" otherwise,
+// avoiding cardinality explosion due to arbitrary methods.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
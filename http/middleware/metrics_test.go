@@ -0,0 +1,146 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/metrics"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("request metrics are recorded", testMetricsRecorded)
+	t.Run("metrics are recorded in registry", testMetricsRegistry)
+	t.Run("route pattern is passed back through middlewares", testMetricsRoutePattern)
+}
+
+func testMetricsRecorded(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		mux                  = http.NewServeMux()
+		inFlightWhileServing int
+		inFlight             int
+		recorded             []middleware.HTTPRequestMetrics
+		recorder             = middleware.HTTPMetricsRecorderFuncs{
+			OnRequestStarted: func(string) {
+				inFlight++
+			},
+			OnRequestFinished: func(m middleware.HTTPRequestMetrics) {
+				inFlight--
+				recorded = append(recorded, m)
+			},
+		}
+		subject = middleware.Metrics(mux, middleware.MetricsConfig{Recorder: recorder})
+	)
+	mux.HandleFunc("POST /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inFlightWhileServing = inFlight
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+	reqWithUnknownLength := httptest.NewRequest(http.MethodPost, "http://example.com/users/1", strings.NewReader("abc"))
+	reqWithUnknownLength.ContentLength = -1
+
+	// act
+	subject.ServeHTTP(
+		httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodPost, "http://example.com/users/123", strings.NewReader("payload")),
+	)
+	subject.ServeHTTP(httptest.NewRecorder(), reqWithUnknownLength)
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "http://example.com/not-found", nil))
+
+	// assert
+	assert.Equal(t, 1, inFlightWhileServing)
+	assert.Equal(t, 0, inFlight)
+	if assert.Equal(t, 3, len(recorded)) {
+		assert.Equal(t, http.MethodPost, recorded[0].Method)
+		assert.Equal(t, "POST /users/{id}", recorded[0].Route)
+		assert.Equal(t, "2xx", recorded[0].StatusClass)
+		assert.Equal(t, int64(7), recorded[0].RequestSize)
+		assert.Equal(t, int64(7), recorded[0].ResponseSize)
+		assert.True(t, recorded[0].Duration > 0)
+
+		assert.Equal(t, int64(3), recorded[1].RequestSize)
+
+		assert.Equal(t, "OTHER", recorded[2].Method)
+		assert.Equal(t, "unmatched", recorded[2].Route)
+		assert.Equal(t, "4xx", recorded[2].StatusClass)
+	}
+}
+
+func testMetricsRegistry(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		registry    = metrics.NewRegistry()
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		subject = middleware.Metrics(nextHandler, middleware.MetricsConfig{
+			Recorder: middleware.NewRegistryHTTPMetricsRecorder(registry, 0.1, 1),
+		})
+		output strings.Builder
+	)
+
+	// act
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+
+	// assert
+	_ = registry.WriteText(&output)
+	for _, expected := range [...]string{
+		`http_requests_total{method="GET",route="unmatched",status="5xx"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="unmatched",status="5xx",le="0.1"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="unmatched",status="5xx"} 1`,
+		`http_requests_in_flight{method="GET"} 0`,
+		`http_request_size_bytes_count{method="GET",route="unmatched",status="5xx"} 1`,
+		`http_response_size_bytes_sum{method="GET",route="unmatched",status="5xx"} 0`,
+	} {
+		assert.True(t, strings.Contains(output.String(), expected))
+	}
+}
+
+func testMetricsRoutePattern(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		mux      = http.NewServeMux()
+		recorded []middleware.HTTPRequestMetrics
+		recorder = middleware.HTTPMetricsRecorderFuncs{
+			OnRequestFinished: func(m middleware.HTTPRequestMetrics) {
+				recorded = append(recorded, m)
+			},
+		}
+		makeCorrelationID = func() string { return "abcd-1234" }
+		withRoutePattern  = middleware.Metrics(
+			middleware.CorrelationID(middleware.RoutePattern(mux), makeCorrelationID),
+			middleware.MetricsConfig{Recorder: recorder},
+		)
+		withoutRoutePattern = middleware.Metrics(
+			middleware.CorrelationID(mux, makeCorrelationID),
+			middleware.MetricsConfig{Recorder: recorder},
+		)
+	)
+	mux.HandleFunc("GET /users/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	// act
+	withRoutePattern.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+	withRoutePattern.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/not-found", nil))
+	withoutRoutePattern.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+	// assert
+	if assert.Equal(t, 3, len(recorded)) {
+		assert.Equal(t, "GET /users/{id}", recorded[0].Route)
+		assert.Equal(t, "unmatched", recorded[1].Route)
+		assert.Equal(t, "unmatched", recorded[2].Route) // pattern is hidden by the request replaced in between
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	httpTransport "github.com/actforgood/xtransport/http"
+)
+
+// RateLimitResult is the outcome of a rate limit check.
+type RateLimitResult struct {
+	// Allowed indicates whether the request is allowed to proceed.
+	Allowed bool
+	// Limit is the max no. of requests allowed within the policy period.
+	Limit int
+	// Remaining is the no. of requests still allowed within current period.
+	Remaining int
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time a denied client should wait before retrying.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore is the contract for a rate limiter state store.
+// The store also owns the limiting algorithm, so that a distributed implementation
+// (Redis for example) can apply it atomically.
+type RateLimitStore interface {
+	// Take consumes one request from the quota associated with given key.
+	Take(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc extracts the key a request is rate limited by.
+// An empty key means the request is not subject to rate limiting.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByClientIP is a [RateLimitKeyFunc] which limits requests by client ip,
+// as resolved by [httpTransport.DefaultClientIPResolver], which trusts no proxy.
+// For a service behind proxies, see [RateLimitByClientIPResolver].
+func RateLimitByClientIP(r *http.Request) string {
+	return clientIP(nil, r)
+}
+
+// RateLimitByClientIPResolver returns a [RateLimitKeyFunc] which limits requests by client ip,
+// as resolved by given resolver, usually configured with the trusted proxies.
+func RateLimitByClientIPResolver(resolver *httpTransport.ClientIPResolver) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return clientIP(resolver, r)
+	}
+}
+
+// RateLimitByHeader returns a [RateLimitKeyFunc] which limits requests by
+// given header's value, an API key header for example.
+func RateLimitByHeader(headerName string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(headerName)
+	}
+}
+
+// RateLimitByRoute is a [RateLimitKeyFunc] which limits requests by the matched [http.ServeMux] pattern.
+// Note: the pattern is available only if the middleware decorates the handler registered on the mux,
+// and not the mux itself.
+func RateLimitByRoute(r *http.Request) string {
+	return r.Pattern
+}
+
+// RateLimit is a decorator/middleware that limits the rate of requests per key
+// returned by the key function.
+// Requests exceeding the quota get a 429 Too Many Requests response,
+// with Retry-After header.
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset headers are sent on every limited request.
+// In case store returns an error, the error is logged and the request is allowed (fail open).
+func RateLimit(
+	next http.Handler,
+	store RateLimitStore,
+	keyFunc RateLimitKeyFunc,
+	logger *slog.Logger,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := keyFunc(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		result, err := store.Take(r.Context(), key)
+		if err != nil {
+			logger.Error(
+				"could not apply rate limit",
+				"err", err,
+				"path", r.URL.Path,
+				"method", r.Method,
+			)
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(durationToSeconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(durationToSeconds(result.RetryAfter)))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// durationToSeconds rounds up given duration to whole seconds.
+func durationToSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
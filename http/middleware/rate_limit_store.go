@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitStoreShards   = 32
+	defaultRateLimitPeriod = time.Second
+)
+
+// rateLimitAlgorithm is the contract for a rate limiting algorithm
+// applied upon an in-memory entry.
+type rateLimitAlgorithm interface {
+	// take consumes one request from the entry's quota.
+	take(entry *rateLimitEntry, isNew bool, now time.Time) RateLimitResult
+	// ttl returns the idle time after which an entry is equivalent to a new one.
+	ttl() time.Duration
+}
+
+// rateLimitEntry holds the state of a key.
+type rateLimitEntry struct {
+	value     float64   // tokens (token bucket) / current window count (sliding window)
+	prevValue float64   // previous window count (sliding window)
+	at        time.Time // last refill (token bucket) / current window start (sliding window)
+	expiresAt time.Time // the moment the entry can be evicted
+}
+
+type rateLimitShard struct {
+	entries   map[string]*rateLimitEntry
+	nextSweep time.Time
+	mu        sync.Mutex
+}
+
+// MemoryRateLimitStore is an in-memory, sharded [RateLimitStore].
+// Idle keys are evicted lazily, once their state is equivalent to a fresh one.
+// It is concurrent safe to use.
+type MemoryRateLimitStore struct {
+	algo   rateLimitAlgorithm
+	shards [rateLimitStoreShards]rateLimitShard
+	seed   maphash.Seed
+	now    func() time.Time
+}
+
+// NewTokenBucketRateLimitStore instantiates a new in-memory store applying token bucket algorithm.
+// The bucket holds up to burst tokens (limit, if not positive), refilled at limit tokens per period.
+// A not positive limit defaults to 1, and a not positive period to 1 second.
+func NewTokenBucketRateLimitStore(limit int, period time.Duration, burst int) *MemoryRateLimitStore {
+	if limit <= 0 {
+		limit = 1
+	}
+	if period <= 0 {
+		period = defaultRateLimitPeriod
+	}
+	if burst <= 0 {
+		burst = limit
+	}
+
+	return newMemoryRateLimitStore(tokenBucket{
+		limit:  burst,
+		rate:   float64(limit) / float64(period),
+		period: period,
+	})
+}
+
+// NewSlidingWindowRateLimitStore instantiates a new in-memory store applying
+// sliding window (counter) algorithm, allowing limit requests per window.
+// A not positive limit defaults to 1, and a not positive window to 1 second.
+func NewSlidingWindowRateLimitStore(limit int, window time.Duration) *MemoryRateLimitStore {
+	if limit <= 0 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = defaultRateLimitPeriod
+	}
+
+	return newMemoryRateLimitStore(slidingWindow{
+		limit:  limit,
+		window: window,
+	})
+}
+
+func newMemoryRateLimitStore(algo rateLimitAlgorithm) *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{
+		algo: algo,
+		seed: maphash.MakeSeed(),
+		now:  time.Now,
+	}
+	for i := range store.shards {
+		store.shards[i].entries = make(map[string]*rateLimitEntry)
+	}
+
+	return store
+}
+
+// Take ...see [RateLimitStore.Take].
+func (store *MemoryRateLimitStore) Take(_ context.Context, key string) (RateLimitResult, error) {
+	now := store.now()
+	shard := &store.shards[maphash.String(store.seed, key)%rateLimitStoreShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.After(shard.nextSweep) {
+		for k, entry := range shard.entries {
+			if now.After(entry.expiresAt) {
+				delete(shard.entries, k)
+			}
+		}
+		shard.nextSweep = now.Add(store.algo.ttl())
+	}
+
+	entry, found := shard.entries[key]
+	if !found {
+		entry = new(rateLimitEntry)
+		shard.entries[key] = entry
+	}
+	result := store.algo.take(entry, !found, now)
+	entry.expiresAt = now.Add(store.algo.ttl())
+
+	return result, nil
+}
+
+// Len returns the no. of keys currently tracked.
+func (store *MemoryRateLimitStore) Len() int {
+	var length int
+	for i := range store.shards {
+		store.shards[i].mu.Lock()
+		length += len(store.shards[i].entries)
+		store.shards[i].mu.Unlock()
+	}
+
+	return length
+}
+
+// tokenBucket implements the token bucket algorithm.
+type tokenBucket struct {
+	limit  int     // bucket capacity
+	rate   float64 // tokens per nanosecond
+	period time.Duration
+}
+
+func (tb tokenBucket) take(entry *rateLimitEntry, isNew bool, now time.Time) RateLimitResult {
+	if isNew {
+		entry.value = float64(tb.limit)
+	} else {
+		entry.value = math.Min(float64(tb.limit), entry.value+float64(now.Sub(entry.at))*tb.rate)
+	}
+	entry.at = now
+
+	result := RateLimitResult{Limit: tb.limit}
+	if entry.value >= 1 {
+		entry.value--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - entry.value) / tb.rate)
+	}
+	result.Remaining = int(entry.value)
+	result.Reset = time.Duration((float64(tb.limit) - entry.value) / tb.rate)
+
+	return result
+}
+
+func (tb tokenBucket) ttl() time.Duration {
+	return time.Duration(float64(tb.limit) / tb.rate)
+}
+
+// slidingWindow implements the sliding window counter algorithm.
+// The count of the previous window is weighted by its overlap with the sliding window.
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+}
+
+func (sw slidingWindow) take(entry *rateLimitEntry, isNew bool, now time.Time) RateLimitResult {
+	windowStart := now.Truncate(sw.window)
+	if isNew {
+		entry.at = windowStart
+	}
+	switch elapsedWindows := windowStart.Sub(entry.at) / sw.window; {
+	case elapsedWindows == 1:
+		entry.prevValue, entry.value = entry.value, 0
+	case elapsedWindows > 1:
+		entry.prevValue, entry.value = 0, 0
+	}
+	entry.at = windowStart
+
+	elapsed := now.Sub(windowStart)
+	prevWeight := 1 - float64(elapsed)/float64(sw.window)
+	estimated := entry.prevValue*prevWeight + entry.value
+
+	result := RateLimitResult{Limit: sw.limit}
+	if estimated+1 <= float64(sw.limit) {
+		entry.value++
+		estimated++
+		result.Allowed = true
+	} else if entry.value+1 > float64(sw.limit) || entry.prevValue == 0 {
+		result.RetryAfter = sw.window - elapsed
+	} else {
+		// previous window's weight has to decrease enough for one more request to fit.
+		neededWeight := (float64(sw.limit) - 1 - entry.value) / entry.prevValue
+		result.RetryAfter = time.Duration((prevWeight - neededWeight) * float64(sw.window))
+	}
+	result.Remaining = max(0, int(float64(sw.limit)-estimated))
+	switch {
+	case entry.value > 0: // current window's count becomes previous window's count, which needs another window to fade.
+		result.Reset = 2*sw.window - elapsed
+	case entry.prevValue > 0:
+		result.Reset = sw.window - elapsed
+	}
+
+	return result
+}
+
+func (sw slidingWindow) ttl() time.Duration {
+	return 2 * sw.window
+}
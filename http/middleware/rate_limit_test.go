@@ -0,0 +1,347 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	httpTransport "github.com/actforgood/xtransport/http"
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("requests over quota are rejected", testRateLimitRejectsOverQuota)
+	t.Run("keys are limited independently", testRateLimitIndependentKeys)
+	t.Run("client ip key is resolved through trusted proxies only", testRateLimitClientIPKey)
+	t.Run("empty key is not limited", testRateLimitEmptyKey)
+	t.Run("store error fails open", testRateLimitStoreError)
+}
+
+func testRateLimitRejectsOverQuota(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandlerCallsCnt int
+		nextHandler         = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			nextHandlerCallsCnt++
+			w.WriteHeader(http.StatusNoContent)
+		})
+		store   = middleware.NewTokenBucketRateLimitStore(2, time.Minute, 0)
+		logger  = slog.New(mock.NewSlogHandler())
+		subject = middleware.RateLimit(nextHandler, store, middleware.RateLimitByClientIP, logger)
+	)
+
+	for i := range 3 {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/limited", nil)
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		// assert
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		if i < 2 {
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, strconv.Itoa(1-i), w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "", w.Header().Get("Retry-After"))
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		}
+	}
+	assert.Equal(t, 2, nextHandlerCallsCnt)
+}
+
+func testRateLimitClientIPKey(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		logger         = slog.New(mock.NewSlogHandler())
+		trustedProxies = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+		defaultSubject = middleware.RateLimit(
+			nextHandler,
+			middleware.NewSlidingWindowRateLimitStore(1, time.Minute),
+			middleware.RateLimitByClientIP,
+			logger,
+		)
+		proxiedSubject = middleware.RateLimit(
+			nextHandler,
+			middleware.NewSlidingWindowRateLimitStore(1, time.Minute),
+			middleware.RateLimitByClientIPResolver(httpTransport.NewClientIPResolver(trustedProxies...)),
+			logger,
+		)
+		serve = func(subject http.Handler, xff string) int {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/limited", nil)
+			req.Header.Set("X-Forwarded-For", xff)
+			w := httptest.NewRecorder()
+			subject.ServeHTTP(w, req)
+
+			return w.Code
+		}
+	)
+
+	// act & assert
+	assert.Equal(t, http.StatusOK, serve(defaultSubject, "8.8.8.8"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(defaultSubject, "9.9.9.9")) // spoofed header is ignored
+
+	assert.Equal(t, http.StatusOK, serve(proxiedSubject, "8.8.8.8"))
+	assert.Equal(t, http.StatusOK, serve(proxiedSubject, "9.9.9.9"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(proxiedSubject, "1.1.1.1, 8.8.8.8"))
+}
+
+func testRateLimitIndependentKeys(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		store   = middleware.NewSlidingWindowRateLimitStore(1, time.Minute)
+		logger  = slog.New(mock.NewSlogHandler())
+		subject = middleware.RateLimit(nextHandler, store, middleware.RateLimitByHeader("X-Api-Key"), logger)
+	)
+	codes := make(map[string][]int)
+
+	for _, apiKey := range [...]string{"key-1", "key-2", "key-1", "key-2"} {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/limited", nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		codes[apiKey] = append(codes[apiKey], w.Code)
+	}
+
+	// assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes["key-1"])
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes["key-2"])
+	assert.Equal(t, 2, store.Len())
+}
+
+func testRateLimitEmptyKey(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandlerCallsCnt int
+		nextHandler         = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			nextHandlerCallsCnt++
+			w.WriteHeader(http.StatusOK)
+		})
+		store   = middleware.NewSlidingWindowRateLimitStore(1, time.Minute)
+		logger  = slog.New(mock.NewSlogHandler())
+		subject = middleware.RateLimit(nextHandler, store, middleware.RateLimitByRoute, logger)
+	)
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/not-routed", nil)
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		// assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "", w.Header().Get("RateLimit-Limit"))
+	}
+	assert.Equal(t, 3, nextHandlerCallsCnt)
+	assert.Equal(t, 0, store.Len())
+}
+
+func testRateLimitStoreError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandlerCallsCnt int
+		nextHandler         = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			nextHandlerCallsCnt++
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		logger     = slog.New(loggerMock)
+		subject    = middleware.RateLimit(nextHandler, errRateLimitStore{}, middleware.RateLimitByClientIP, logger)
+		req        = httptest.NewRequest(http.MethodGet, "http://example.com/limited", nil)
+		w          = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, 1, nextHandlerCallsCnt)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func TestTokenBucketRateLimitStore(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewTokenBucketRateLimitStore(1, 50*time.Millisecond, 2)
+		ctx     = context.Background()
+	)
+
+	// act & assert
+	res, err := subject.Take(ctx, "foo")
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Limit)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, _ = subject.Take(ctx, "foo")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, _ = subject.Take(ctx, "foo")
+	assert.Equal(t, false, res.Allowed)
+	assert.True(t, res.RetryAfter > 0)
+	assert.True(t, res.RetryAfter <= 50*time.Millisecond)
+
+	time.Sleep(res.RetryAfter + 5*time.Millisecond)
+	res, _ = subject.Take(ctx, "foo")
+	assert.True(t, res.Allowed)
+}
+
+func TestNewRateLimitStore_invalidConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name    string
+		subject *middleware.MemoryRateLimitStore
+	}{
+		{name: "token bucket", subject: middleware.NewTokenBucketRateLimitStore(0, -time.Second, 0)},
+		{name: "sliding window", subject: middleware.NewSlidingWindowRateLimitStore(-1, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			res1, err1 := test.subject.Take(context.Background(), "foo")
+			res2, err2 := test.subject.Take(context.Background(), "foo")
+
+			// assert
+			assert.Nil(t, err1)
+			assert.Nil(t, err2)
+			assert.True(t, res1.Allowed)
+			assert.Equal(t, 1, res1.Limit)
+			assert.Equal(t, false, res2.Allowed)
+			assert.True(t, res2.RetryAfter > 0)
+			assert.True(t, res2.RetryAfter <= time.Second)
+		})
+	}
+}
+
+func TestSlidingWindowRateLimitStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("limits requests within window", testSlidingWindowRateLimitStoreLimits)
+	t.Run("idle keys are evicted", testSlidingWindowRateLimitStoreEviction)
+	t.Run("concurrent safe", testSlidingWindowRateLimitStoreConcurrency)
+}
+
+func testSlidingWindowRateLimitStoreLimits(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewSlidingWindowRateLimitStore(3, time.Minute)
+		ctx     = context.Background()
+	)
+
+	// act & assert
+	for i := range 3 {
+		res, err := subject.Take(ctx, "foo")
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, 2-i, res.Remaining)
+		assert.True(t, res.Reset > time.Minute)
+	}
+	res, _ := subject.Take(ctx, "foo")
+	assert.Equal(t, false, res.Allowed)
+	assert.True(t, res.RetryAfter > 0)
+	assert.True(t, res.RetryAfter <= time.Minute)
+}
+
+func testSlidingWindowRateLimitStoreEviction(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = middleware.NewSlidingWindowRateLimitStore(1, 10*time.Millisecond)
+		ctx     = context.Background()
+	)
+	_, _ = subject.Take(ctx, "foo")
+	_, _ = subject.Take(ctx, "bar")
+	assert.Equal(t, 2, subject.Len())
+	time.Sleep(50 * time.Millisecond)
+
+	// act
+	for i := range 1000 { // make sure (statistically) every shard got to be swept
+		_, _ = subject.Take(ctx, "key-"+strconv.Itoa(i))
+	}
+
+	// assert
+	assert.Equal(t, 1000, subject.Len())
+	res, _ := subject.Take(ctx, "foo")
+	assert.True(t, res.Allowed)
+}
+
+func testSlidingWindowRateLimitStoreConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject    = middleware.NewSlidingWindowRateLimitStore(100, time.Hour)
+		ctx        = context.Background()
+		wg         sync.WaitGroup
+		allowedCnt int
+		mu         sync.Mutex
+	)
+
+	// act
+	for range 10 {
+		wg.Go(func() {
+			for range 20 {
+				if res, _ := subject.Take(ctx, "foo"); res.Allowed {
+					mu.Lock()
+					allowedCnt++
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	// assert
+	assert.True(t, allowedCnt >= 99 && allowedCnt <= 100)
+}
+
+type errRateLimitStore struct{}
+
+func (errRateLimitStore) Take(context.Context, string) (middleware.RateLimitResult, error) {
+	return middleware.RateLimitResult{}, errors.New("intentionally triggered store error")
+}
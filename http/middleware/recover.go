@@ -1,35 +1,107 @@
 package middleware
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+	"time"
 
 	"github.com/actforgood/xtransport"
 	httpTransport "github.com/actforgood/xtransport/http"
 )
 
+// RecoverResponder is a function type through which you can customize the response
+// sent to the client when a panic occurred. The recovered value is passed to it.
+type RecoverResponder func(w http.ResponseWriter, r *http.Request, recovered any)
+
+// RecoverConfig holds the configuration for [RecoverWithConfig] middleware.
+type RecoverConfig struct {
+	// Responder writes the response upon panic. It is not called if headers were already sent.
+	// Defaults to [DefaultRecoverResponder].
+	Responder RecoverResponder
+	// Reporters are called with each reported panic, see [xtransport.PanicReporter].
+	Reporters []xtransport.PanicReporter
+	// StackMaxFrames is the max no. of stack frames logged / reported.
+	// Zero value means all the frames are kept.
+	StackMaxFrames int
+	// DedupWindow, if set, suppresses the logging / reporting of identical panics
+	// (same value and location) occurred within this window after one was reported.
+	// Responses are sent regardless of it.
+	DedupWindow time.Duration
+	// ClientIPResolver resolves the logged client ip. Defaults to [httpTransport.DefaultClientIPResolver],
+	// which trusts no proxy.
+	ClientIPResolver *httpTransport.ClientIPResolver
+}
+
+// DefaultRecoverResponder writes a 500 response with a generic message.
+func DefaultRecoverResponder(w http.ResponseWriter, _ *http.Request, _ any) {
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte("an unexpected error occurred, please try again later"))
+}
+
 // Recover is a decorator/middleware that gracefully logs
 // any panic occurred while serving a request.
 func Recover(next http.Handler, logger *slog.Logger) http.Handler {
+	return RecoverWithConfig(next, RecoverConfig{}, logger)
+}
+
+// RecoverWithConfig is a decorator/middleware that gracefully logs
+// any panic occurred while serving a request, configured through given config
+// (responder, reporters, stack trimming, de-duplication).
+// [http.ErrAbortHandler] panics are re-panicked, as they are meant to abort the response,
+// being silently handled by [http.Server].
+// If headers were already sent when the panic occurred, the responder is not called.
+func RecoverWithConfig(next http.Handler, config RecoverConfig, logger *slog.Logger) http.Handler {
+	if config.Responder == nil {
+		config.Responder = DefaultRecoverResponder
+	}
+	var dedup *xtransport.PanicDeduplicator
+	if config.DedupWindow > 0 {
+		dedup = xtransport.NewPanicDeduplicator(config.DedupWindow)
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw, ok := w.(*ResponseWriter)
+		if !ok {
+			rw = NewResponseWriter(w)
+		}
 		defer func() {
-			if err := recover(); err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte("an unexpected error occurred, please try again later"))
-				logger.Error(
-					"handler panic catched",
-					"err", err,
-					"path", r.URL.Path,
-					"method", r.Method,
-					"ip", httpTransport.GetClientIP(r).String(),
-					"agent", r.Header.Get("User-Agent"),
-					"stack", string(debug.Stack()),
-					"correlationId", xtransport.CorrelationIDFromContext(r.Context()),
-				)
+			err := recover()
+			if err == nil {
+				return
+			}
+			if abortErr, ok := err.(error); ok && errors.Is(abortErr, http.ErrAbortHandler) {
+				panic(err)
+			}
+			headerWritten := rw.HeaderWritten()
+			if !headerWritten {
+				config.Responder(rw, r, err)
+			}
+			report, shouldLog := xtransport.HandlePanic(
+				r.Context(), err, debug.Stack(), config.StackMaxFrames, dedup, config.Reporters...,
+			)
+			if !shouldLog {
+				return
+			}
+			logParams := []any{
+				"err", err,
+				"path", r.URL.Path,
+				"method", r.Method,
+				"ip", clientIP(config.ClientIPResolver, r),
+				"agent", r.Header.Get("User-Agent"),
+				"stack", report.Stack,
+				"correlationId", xtransport.CorrelationIDFromContext(r.Context()),
+			}
+			if headerWritten {
+				logParams = append(logParams, "headerWritten", true)
+			}
+			if report.Suppressed > 0 {
+				logParams = append(logParams, "suppressedCount", report.Suppressed)
 			}
+			logger.Error("handler panic catched", logParams...)
 		}()
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rw, r)
 	})
 }
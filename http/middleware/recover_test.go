@@ -1,13 +1,21 @@
 package middleware_test
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/actforgood/xtransport"
+
+	httpTransport "github.com/actforgood/xtransport/http"
 	"github.com/actforgood/xtransport/http/middleware"
 	"github.com/actforgood/xtransport/testing/assert"
 	"github.com/actforgood/xtransport/testing/mock"
@@ -20,6 +28,16 @@ func TestRecover(t *testing.T) {
 	t.Run("panic is catched and 500 response returned", testRecoverWithPanic)
 }
 
+func TestRecoverWithConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("custom responder and reporters are called", testRecoverWithConfigResponderAndReporters)
+	t.Run("http.ErrAbortHandler is re-panicked", testRecoverWithConfigAbortHandler)
+	t.Run("responder is not called if headers were sent", testRecoverWithConfigHeadersSent)
+	t.Run("repeated panics are de-duplicated", testRecoverWithConfigDedup)
+	t.Run("client ip is resolved with configured resolver", testRecoverWithConfigClientIPResolver)
+}
+
 func testRecoverNoPanic(t *testing.T) {
 	t.Parallel()
 
@@ -74,3 +92,173 @@ func testRecoverWithPanic(t *testing.T) {
 	assert.Equal(t, "an unexpected error occurred, please try again later", string(respBody))
 	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
 }
+
+func testRecoverWithConfigResponderAndReporters(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		panicErr    = errors.New("intentionally triggered panic")
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			panic(panicErr)
+		})
+		loggerMock = mock.NewSlogHandler()
+		reports    []xtransport.PanicReport
+		config     = middleware.RecoverConfig{
+			Responder: func(w http.ResponseWriter, _ *http.Request, recovered any) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error":"` + recovered.(error).Error() + `"}`))
+			},
+			Reporters: []xtransport.PanicReporter{
+				xtransport.PanicReporterFunc(func(_ context.Context, report xtransport.PanicReport) {
+					reports = append(reports, report)
+				}),
+			},
+			StackMaxFrames: 1,
+		}
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/panicRoute", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	middleware.RecoverWithConfig(nextHandler, config, slog.New(loggerMock)).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, `{"error":"intentionally triggered panic"}`, w.Body.String())
+	if assert.Equal(t, 1, len(reports)) {
+		assert.Equal(t, panicErr, reports[0].Value)
+		assert.Equal(t, 0, reports[0].Suppressed)
+		assert.True(t, strings.Contains(reports[0].Stack, "testRecoverWithConfigResponderAndReporters"))
+		assert.True(t, strings.HasSuffix(reports[0].Stack, "...additional frames elided...\n"))
+		assert.True(t, !strings.Contains(reports[0].Stack, "runtime/debug.Stack"))
+	}
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError)) {
+		assert.Equal(t, reports[0].Stack, loggerMock.ValueAt(1, "stack"))
+	}
+}
+
+func testRecoverWithConfigAbortHandler(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+		loggerMock = mock.NewSlogHandler()
+		req        = httptest.NewRequest(http.MethodGet, "http://example.com/abort", nil)
+		w          = httptest.NewRecorder()
+		recovered  any
+	)
+
+	// act
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		middleware.RecoverWithConfig(nextHandler, middleware.RecoverConfig{}, slog.New(loggerMock)).ServeHTTP(w, req)
+	}()
+
+	// assert
+	assert.Equal(t, http.ErrAbortHandler, recovered)
+	assert.Equal(t, 0, loggerMock.LogCallsCount(slog.LevelError))
+	assert.Equal(t, "", w.Body.String())
+}
+
+func testRecoverWithConfigHeadersSent(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("partial"))
+			panic("intentionally triggered panic")
+		})
+		responderCallsCnt int
+		loggerMock        = mock.NewSlogHandler()
+		config            = middleware.RecoverConfig{
+			Responder: func(http.ResponseWriter, *http.Request, any) {
+				responderCallsCnt++
+			},
+		}
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/stream", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	middleware.RecoverWithConfig(nextHandler, config, slog.New(loggerMock)).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, 0, responderCallsCnt)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError)) {
+		assert.Equal(t, true, loggerMock.ValueAt(1, "headerWritten"))
+	}
+}
+
+func testRecoverWithConfigDedup(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			panic("intentionally triggered panic")
+		})
+		reportsCnt atomic.Int32
+		loggerMock = mock.NewSlogHandler()
+		config     = middleware.RecoverConfig{
+			Reporters: []xtransport.PanicReporter{
+				xtransport.PanicReporterFunc(func(context.Context, xtransport.PanicReport) {
+					reportsCnt.Add(1)
+				}),
+			},
+			DedupWindow: 100 * time.Millisecond,
+		}
+		subject = middleware.RecoverWithConfig(nextHandler, config, slog.New(loggerMock))
+	)
+
+	// act
+	for range 3 {
+		w := httptest.NewRecorder()
+		subject.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/panicRoute", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+	time.Sleep(150 * time.Millisecond)
+	subject.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/panicRoute", nil))
+
+	// assert
+	assert.Equal(t, int32(2), reportsCnt.Load())
+	if assert.Equal(t, 2, loggerMock.LogCallsCount(slog.LevelError)) {
+		assert.Equal(t, mock.KeyNotFound{}, loggerMock.ValueAt(1, "suppressedCount"))
+		assert.Equal(t, int64(2), loggerMock.ValueAt(2, "suppressedCount"))
+	}
+}
+
+func testRecoverWithConfigClientIPResolver(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("intentionally triggered panic")
+		})
+		loggerMock = mock.NewSlogHandler()
+		config     = middleware.RecoverConfig{
+			ClientIPResolver: httpTransport.NewClientIPResolver(netip.MustParsePrefix("192.0.2.0/24")),
+		}
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/panic", nil)
+	)
+	req.Header.Set("X-Forwarded-For", "8.8.8.8")
+
+	// act
+	middleware.RecoverWithConfig(nextHandler, config, slog.New(loggerMock)).ServeHTTP(httptest.NewRecorder(), req)
+
+	// assert
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError)) {
+		assert.Equal(t, "8.8.8.8", loggerMock.ValueAt(1, "ip"))
+	}
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored by [ResponseCacheStore].
+type CachedResponse struct {
+	// StatusCode is the response status code.
+	StatusCode int
+	// Header is the response header, as set by the handler.
+	Header http.Header
+	// Body is the response body.
+	Body []byte
+	// StoredAt is the moment the response was stored.
+	StoredAt time.Time
+	// Vary holds the request header names the response varies by.
+	// It is set only on the variants index entry.
+	Vary []string
+}
+
+// ResponseCacheStore is the contract for a responses cache store.
+type ResponseCacheStore interface {
+	// Get returns the response stored for given key, if any.
+	Get(ctx context.Context, key string) (CachedResponse, bool)
+	// Set stores the response for given key, expiring after ttl.
+	Set(ctx context.Context, key string, resp CachedResponse, ttl time.Duration)
+}
+
+const defaultResponseCacheMaxEntries = 1000
+
+type responseCacheEntry struct {
+	resp      CachedResponse
+	expiresAt time.Time
+}
+
+// MemoryResponseCache is an in-memory [ResponseCacheStore], bounded by the no. of entries.
+// When the limit is reached, expired entries are evicted, and if none are expired,
+// an arbitrary entry is evicted.
+// It is concurrent safe to use.
+type MemoryResponseCache struct {
+	entries    map[string]responseCacheEntry
+	maxEntries int
+	mu         sync.Mutex
+	now        func() time.Time
+}
+
+// NewMemoryResponseCache instantiates a new in-memory response cache, holding up to maxEntries responses
+// (1000 if not positive).
+func NewMemoryResponseCache(maxEntries int) *MemoryResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultResponseCacheMaxEntries
+	}
+
+	return &MemoryResponseCache{
+		entries:    make(map[string]responseCacheEntry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// Get ...see [ResponseCacheStore.Get].
+func (cache *MemoryResponseCache) Get(_ context.Context, key string) (CachedResponse, bool) {
+	now := cache.now()
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry, found := cache.entries[key]
+	if !found {
+		return CachedResponse{}, false
+	}
+	if now.After(entry.expiresAt) {
+		delete(cache.entries, key)
+
+		return CachedResponse{}, false
+	}
+
+	return entry.resp, true
+}
+
+// Set ...see [ResponseCacheStore.Set].
+func (cache *MemoryResponseCache) Set(_ context.Context, key string, resp CachedResponse, ttl time.Duration) {
+	now := cache.now()
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if _, found := cache.entries[key]; !found && len(cache.entries) >= cache.maxEntries {
+		for k, entry := range cache.entries {
+			if now.After(entry.expiresAt) {
+				delete(cache.entries, k)
+			}
+		}
+		if len(cache.entries) >= cache.maxEntries {
+			for k := range cache.entries {
+				delete(cache.entries, k)
+
+				break
+			}
+		}
+	}
+	cache.entries[key] = responseCacheEntry{resp: resp, expiresAt: now.Add(ttl)}
+}
+
+// Len returns the no. of entries currently stored.
+func (cache *MemoryResponseCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return len(cache.entries)
+}
+
+// responseCacheKey returns the cache key of the request, not taking into account variants.
+func responseCacheKey(r *http.Request) string {
+	return r.Host + r.URL.RequestURI()
+}
+
+// responseCacheVariantKey returns the cache key of the request's variant, given the Vary header names.
+func responseCacheVariantKey(baseKey string, r *http.Request, vary []string) string {
+	if len(vary) == 0 {
+		return baseKey
+	}
+	var key strings.Builder
+	key.WriteString(baseKey)
+	for _, name := range vary {
+		key.WriteString("\n" + name + ":" + strings.Join(r.Header.Values(name), ","))
+	}
+
+	return key.String()
+}
+
+// parseVary returns the canonical header names from Vary header.
+// The second returned value is false if response varies by "*", meaning it cannot be cached.
+func parseVary(header http.Header) ([]string, bool) {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for name := range strings.SplitSeq(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names, true
+}
+
+// responseCacheTTL returns the period a response can be cached for, based on its Cache-Control header.
+// Zero is returned if response should not be cached.
+func responseCacheTTL(r *http.Request, header http.Header, defaultTTL time.Duration) time.Duration {
+	if header.Get("Set-Cookie") != "" {
+		return 0
+	}
+	var (
+		directives = cacheControlDirectives(header.Get("Cache-Control"))
+		_, public  = directives["public"]
+		ttl        = defaultTTL
+	)
+	for _, directive := range [...]string{"no-store", "no-cache", "private"} {
+		if _, found := directives[directive]; found {
+			return 0
+		}
+	}
+	if r.Header.Get("Authorization") != "" && !public {
+		if _, found := directives["s-maxage"]; !found {
+			return 0
+		}
+	}
+	if maxAge, found := directives["max-age"]; found {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+	if sMaxAge, found := directives["s-maxage"]; found {
+		if seconds, err := strconv.Atoi(sMaxAge); err == nil {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return max(ttl, 0)
+}
+
+// cacheControlDirectives parses a Cache-Control header value.
+func cacheControlDirectives(value string) map[string]string {
+	directives := make(map[string]string)
+	for directive := range strings.SplitSeq(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+
+	return directives
+}
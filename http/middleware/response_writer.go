@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseWriter is a [http.ResponseWriter] decorator which records
+// information about the response (status code, written bytes, first byte time, etc.).
+// It preserves the optional interfaces of the decorated writer
+// ([http.Flusher] - including FlushError, [http.Hijacker], [io.ReaderFrom]) and exposes it through Unwrap,
+// so it plays well with [http.ResponseController].
+// If the decorated writer does not support an optional feature,
+// the call results in a no-op (Flush) or in an [http.ErrNotSupported] error (FlushError, Hijack).
+type ResponseWriter struct {
+	origW         http.ResponseWriter // original response writer
+	statusCode    int                 // captures response status code
+	bytesWritten  int64               // captures response body length
+	firstByteAt   time.Time           // captures the moment headers / first byte were written
+	headerWritten bool                // flag indicating whether headers were written
+	hijacked      bool                // flag indicating whether connection was hijacked
+	beforeHeader  []func(int)         // hooks called before status code is sent
+	tee           io.Writer           // if set, receives a copy of the written body
+}
+
+// NewResponseWriter instantiates a new [ResponseWriter] decorating the given writer.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{origW: w}
+}
+
+// Header returns the header map of the decorated writer.
+func (w *ResponseWriter) Header() http.Header {
+	return w.origW.Header()
+}
+
+// Write writes the data to the decorated writer, recording the no. of bytes written.
+func (w *ResponseWriter) Write(data []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.origW.Write(data)
+	w.bytesWritten += int64(n)
+	if w.tee != nil && n > 0 {
+		_, _ = w.tee.Write(data[:n])
+	}
+
+	return n, err
+}
+
+// WriteHeader sends the status code through the decorated writer, recording it.
+// Informational (1xx) status codes, except 101 Switching Protocols, are passed through,
+// without being recorded, as they can be followed by a final status code.
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.headerWritten {
+		return // superfluous call, let the original writer be silent about it.
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.origW.WriteHeader(code)
+
+		return
+	}
+	for _, hook := range w.beforeHeader {
+		hook(code)
+	}
+	w.markHeaderWritten(code)
+	w.origW.WriteHeader(code)
+}
+
+// BeforeWriteHeader registers a hook to be called with the final status code,
+// just before it is sent. Headers can still be altered inside the hook.
+func (w *ResponseWriter) BeforeWriteHeader(hook func(statusCode int)) {
+	w.beforeHeader = append(w.beforeHeader, hook)
+}
+
+// Flush sends any buffered data to the client.
+// See [http.Flusher].
+func (w *ResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError sends any buffered data to the client, returning the error of the decorated writer, if any.
+// It is used by [http.ResponseController].
+func (w *ResponseWriter) FlushError() error {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return http.NewResponseController(w.origW).Flush()
+}
+
+// Hijack lets the caller take over the connection.
+// See [http.Hijacker].
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.origW).Hijack()
+	if err == nil {
+		w.hijacked = true
+		if !w.headerWritten {
+			w.markHeaderWritten(http.StatusSwitchingProtocols)
+		}
+	}
+
+	return conn, rw, err
+}
+
+// ReadFrom reads data from given reader until EOF or error, writing it to the decorated writer.
+// If the decorated writer implements [io.ReaderFrom], its implementation is used,
+// thus optimizations like sendfile are preserved (unless the body is captured by access log).
+// See [io.ReaderFrom].
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	var (
+		n   int64
+		err error
+	)
+	if w.tee != nil {
+		r = io.TeeReader(r, w.tee)
+	}
+	if rf, ok := w.origW.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{w.origW}, r)
+	}
+	w.bytesWritten += n
+
+	return n, err
+}
+
+// Unwrap returns the decorated writer.
+// It is used by [http.ResponseController].
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.origW
+}
+
+// StatusCode returns the response status code.
+// If no status code was explicitly written, [http.StatusOK] is returned.
+func (w *ResponseWriter) StatusCode() int {
+	if w.statusCode == 0 {
+		return http.StatusOK
+	}
+
+	return w.statusCode
+}
+
+// BytesWritten returns the no. of response body bytes written.
+func (w *ResponseWriter) BytesWritten() int64 {
+	return w.bytesWritten
+}
+
+// FirstByteAt returns the moment headers / first byte were written.
+// Zero value is returned if nothing was written yet.
+func (w *ResponseWriter) FirstByteAt() time.Time {
+	return w.firstByteAt
+}
+
+// HeaderWritten returns whether headers were already sent.
+func (w *ResponseWriter) HeaderWritten() bool {
+	return w.headerWritten
+}
+
+// Hijacked returns whether the underlying connection was hijacked.
+func (w *ResponseWriter) Hijacked() bool {
+	return w.hijacked
+}
+
+func (w *ResponseWriter) markHeaderWritten(code int) {
+	w.statusCode = code
+	w.headerWritten = true
+	w.firstByteAt = time.Now()
+}
+
+// writerOnly hides other methods than Write of an [io.Writer],
+// in order to avoid infinite recursion through [io.Copy] optimizations.
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,250 @@
+package middleware_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestResponseWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records default status, bytes and first byte time", testResponseWriterDefaults)
+	t.Run("records explicit status code", testResponseWriterExplicitStatus)
+	t.Run("informational status is not recorded", testResponseWriterInformationalStatus)
+	t.Run("flush is delegated", testResponseWriterFlush)
+	t.Run("flush error is propagated", testResponseWriterFlushError)
+	t.Run("hijack is delegated", testResponseWriterHijack)
+	t.Run("hijack is not supported", testResponseWriterHijackNotSupported)
+	t.Run("read from is delegated", testResponseWriterReadFrom)
+	t.Run("works with response controller", testResponseWriterResponseController)
+}
+
+func testResponseWriterDefaults(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		w       = httptest.NewRecorder()
+		subject = middleware.NewResponseWriter(w)
+		before  = time.Now()
+	)
+
+	// act & assert
+	assert.Equal(t, false, subject.HeaderWritten())
+	assert.True(t, subject.FirstByteAt().IsZero())
+	assert.Equal(t, http.StatusOK, subject.StatusCode())
+
+	n, err := subject.Write([]byte(t.Name()))
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(t.Name()), n)
+	assert.Equal(t, int64(len(t.Name())), subject.BytesWritten())
+	assert.Equal(t, http.StatusOK, subject.StatusCode())
+	assert.True(t, subject.HeaderWritten())
+	assert.True(t, !subject.FirstByteAt().Before(before))
+	assert.Equal(t, t.Name(), w.Body.String())
+	assert.Equal(t, http.ResponseWriter(w), subject.Unwrap())
+}
+
+func testResponseWriterExplicitStatus(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		w       = httptest.NewRecorder()
+		subject = middleware.NewResponseWriter(w)
+	)
+
+	// act
+	subject.WriteHeader(http.StatusTeapot)
+	subject.WriteHeader(http.StatusInternalServerError) // superfluous
+	_, _ = subject.Write([]byte("abc"))
+
+	// assert
+	assert.Equal(t, http.StatusTeapot, subject.StatusCode())
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, int64(3), subject.BytesWritten())
+}
+
+func testResponseWriterInformationalStatus(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		w       = httptest.NewRecorder()
+		subject = middleware.NewResponseWriter(w)
+	)
+
+	// act
+	subject.WriteHeader(http.StatusEarlyHints)
+
+	// assert
+	assert.Equal(t, false, subject.HeaderWritten())
+
+	// act
+	subject.WriteHeader(http.StatusCreated)
+
+	// assert
+	assert.True(t, subject.HeaderWritten())
+	assert.Equal(t, http.StatusCreated, subject.StatusCode())
+}
+
+func testResponseWriterFlush(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		w       = httptest.NewRecorder()
+		subject = middleware.NewResponseWriter(w)
+	)
+	var _ http.Flusher = subject
+
+	// act
+	subject.Flush()
+
+	// assert
+	assert.True(t, w.Flushed)
+	assert.True(t, subject.HeaderWritten())
+}
+
+func testResponseWriterFlushError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		origW        = struct{ http.ResponseWriter }{httptest.NewRecorder()} // hides Flush
+		subject      = middleware.NewResponseWriter(origW)
+		flushErr     = errors.New("intentionally triggered flush error")
+		errFlushW    = &flushErrorRecorder{ResponseRecorder: httptest.NewRecorder(), err: flushErr}
+		errFlushSubj = middleware.NewResponseWriter(errFlushW)
+	)
+
+	// act
+	err := http.NewResponseController(subject).Flush()
+	errDirect := subject.FlushError()
+	errDelegated := http.NewResponseController(errFlushSubj).Flush()
+
+	// assert
+	assert.True(t, errors.Is(err, http.ErrNotSupported))
+	assert.True(t, errors.Is(errDirect, http.ErrNotSupported))
+	assert.True(t, subject.HeaderWritten())
+	assert.Equal(t, flushErr, errDelegated)
+	assert.Equal(t, 1, errFlushW.flushErrorCallsCnt)
+}
+
+func testResponseWriterHijack(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		serverConn, clientConn = net.Pipe()
+		origW                  = &hijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: serverConn}
+		subject                = middleware.NewResponseWriter(origW)
+	)
+	defer clientConn.Close()
+
+	// act
+	conn, _, err := subject.Hijack()
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, serverConn, conn)
+	assert.True(t, subject.Hijacked())
+	assert.Equal(t, http.StatusSwitchingProtocols, subject.StatusCode())
+	_ = conn.Close()
+}
+
+func testResponseWriterHijackNotSupported(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := middleware.NewResponseWriter(httptest.NewRecorder())
+
+	// act
+	_, _, err := subject.Hijack()
+
+	// assert
+	assert.True(t, errors.Is(err, http.ErrNotSupported))
+	assert.Equal(t, false, subject.Hijacked())
+}
+
+func testResponseWriterReadFrom(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		origW   = &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+		subject = middleware.NewResponseWriter(origW)
+	)
+	var _ io.ReaderFrom = subject
+
+	// act
+	n, err := io.Copy(subject, struct{ io.Reader }{strings.NewReader("hello world")})
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), n)
+	assert.Equal(t, int64(11), subject.BytesWritten())
+	assert.Equal(t, 1, origW.readFromCallsCnt)
+	assert.Equal(t, "hello world", origW.Body.String())
+}
+
+func testResponseWriterResponseController(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		w       = httptest.NewRecorder()
+		subject = middleware.NewResponseWriter(middleware.NewResponseWriter(w))
+		rc      = http.NewResponseController(subject)
+	)
+
+	// act
+	err := rc.Flush()
+
+	// assert
+	assert.Nil(t, err)
+	assert.True(t, w.Flushed)
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (rec *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rec.conn, bufio.NewReadWriter(bufio.NewReader(rec.conn), bufio.NewWriter(rec.conn)), nil
+}
+
+type flushErrorRecorder struct {
+	*httptest.ResponseRecorder
+	err                error
+	flushErrorCallsCnt int
+}
+
+func (rec *flushErrorRecorder) FlushError() error {
+	rec.flushErrorCallsCnt++
+
+	return rec.err
+}
+
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFromCallsCnt int
+}
+
+func (rec *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	rec.readFromCallsCnt++
+
+	return io.Copy(rec.ResponseRecorder, r)
+}
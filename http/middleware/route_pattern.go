@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// routePatternHolderCtxKey is the context key where a route pattern holder is stored.
+// A holder allows outer middlewares (like [Metrics], [AccessLog]) to get the pattern matched
+// by the [http.ServeMux], even if middlewares in between replaced the request, see [RoutePattern].
+type routePatternHolderCtxKey struct{}
+
+// contextWithRoutePatternHolder returns a context enriched with an (empty) route pattern holder,
+// or given context, if it already has one.
+func contextWithRoutePatternHolder(ctx context.Context) context.Context {
+	if _, found := ctx.Value(routePatternHolderCtxKey{}).(*atomic.Pointer[string]); found {
+		return ctx
+	}
+
+	return context.WithValue(ctx, routePatternHolderCtxKey{}, new(atomic.Pointer[string]))
+}
+
+// RoutePattern is a decorator/middleware which passes the pattern matched by the decorated [http.ServeMux]
+// back to the outer [Metrics] and [AccessLog] middlewares.
+// Without it, they see the pattern only if no middleware in between replaces the request,
+// like [CorrelationID], [Auth], [Timeout] do (through [http.Request.WithContext]).
+// It must decorate the mux directly.
+//
+// Example:
+//
+//	handler := middleware.Metrics(
+//		middleware.CorrelationID(middleware.RoutePattern(mux), correlationIDFactory),
+//		middleware.MetricsConfig{},
+//	)
+func RoutePattern(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if holder, found := r.Context().Value(routePatternHolderCtxKey{}).(*atomic.Pointer[string]); found {
+				pattern := r.Pattern
+				holder.Store(&pattern)
+			}
+		}()
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// routePattern returns the pattern matched by the [http.ServeMux] for given request,
+// or the one passed back through [RoutePattern]. Empty string is returned if no pattern was matched.
+func routePattern(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	if holder, found := r.Context().Value(routePatternHolderCtxKey{}).(*atomic.Pointer[string]); found {
+		if pattern := holder.Load(); pattern != nil {
+			return *pattern
+		}
+	}
+
+	return ""
+}
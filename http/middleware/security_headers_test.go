@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	t.Parallel()
+
+	t.Run("default headers are set", testSecurityHeadersDefaults)
+	t.Run("handler set headers are not overwritten", testSecurityHeadersNotOverwritten)
+	t.Run("headers are set if handler writes nothing", testSecurityHeadersNoWrite)
+	t.Run("CSP nonce is generated per request", testSecurityHeadersCSPNonce)
+}
+
+func testSecurityHeadersDefaults(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(t.Name()))
+		})
+		config = middleware.DefaultSecurityHeadersConfig()
+		req    = httptest.NewRequest(http.MethodGet, "https://example.com/foo", nil)
+		w      = httptest.NewRecorder()
+	)
+	config.HSTSPreload = true
+	config.PermissionsPolicy = ""
+
+	// act
+	middleware.SecurityHeaders(nextHandler, config).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, t.Name(), w.Body.String())
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", w.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, 0, len(w.Header().Values("Permissions-Policy")))
+}
+
+func testSecurityHeadersNotOverwritten(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Add("X-Frame-Options", "SAMEORIGIN")
+			w.Header().Add("Content-Security-Policy", "default-src 'self'")
+			w.WriteHeader(http.StatusCreated)
+		})
+		req = httptest.NewRequest(http.MethodGet, "https://example.com/foo", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	middleware.SecurityHeaders(nextHandler, middleware.DefaultSecurityHeadersConfig()).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []string{"SAMEORIGIN"}, w.Header().Values("X-Frame-Options"))
+	assert.Equal(t, []string{"default-src 'self'"}, w.Header().Values("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+}
+
+func testSecurityHeadersNoWrite(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})
+		req         = httptest.NewRequest(http.MethodGet, "https://example.com/foo", nil)
+		w           = httptest.NewRecorder()
+	)
+
+	// act
+	middleware.SecurityHeaders(nextHandler, middleware.DefaultSecurityHeadersConfig()).ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+}
+
+func testSecurityHeadersCSPNonce(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nonces      []string
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := middleware.CSPNonceFromContext(r.Context())
+			nonces = append(nonces, nonce)
+			w.Write([]byte(`<script nonce="` + nonce + `"></script>`))
+		})
+		config = middleware.SecurityHeadersConfig{
+			ContentSecurityPolicy: "script-src 'self' 'nonce-" + middleware.CSPNoncePlaceholder + "'",
+		}
+		subject = middleware.SecurityHeaders(nextHandler, config)
+	)
+
+	for i := range 2 {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/page", nil)
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		// assert
+		if assert.Equal(t, i+1, len(nonces)) {
+			assert.True(t, len(nonces[i]) > 0)
+			assert.Equal(t, "script-src 'self' 'nonce-"+nonces[i]+"'", w.Header().Get("Content-Security-Policy"))
+			assert.True(t, strings.Contains(w.Body.String(), nonces[i]))
+		}
+		assert.Equal(t, "", w.Header().Get("Strict-Transport-Security"))
+	}
+	assert.True(t, nonces[0] != nonces[1])
+	assert.Equal(t, "", middleware.CSPNonceFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
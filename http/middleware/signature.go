@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/actforgood/xconf"
+	"github.com/actforgood/xerr"
+
+	httpTransport "github.com/actforgood/xtransport/http"
+)
+
+// ErrInvalidSignature is returned by a [SignatureScheme] if the request
+// signature is missing, malformed, or does not match any of the secrets.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureSecretProvider is the contract for providing the secrets requests are signed with.
+// Multiple secrets can be returned, allowing a secret to be rotated without downtime:
+// a signature is accepted if it matches any of the secrets.
+type SignatureSecretProvider interface {
+	// Secrets returns the secrets given request may be signed with.
+	Secrets(r *http.Request) ([][]byte, error)
+}
+
+// SignatureSecrets is a [SignatureSecretProvider] holding a set of secrets
+// which can be replaced at runtime, see [SignatureSecrets.Set].
+// It is concurrent safe to use.
+type SignatureSecrets struct {
+	secrets atomic.Pointer[[][]byte]
+}
+
+// NewSignatureSecrets instantiates a new signature secrets holder.
+func NewSignatureSecrets(secrets ...string) *SignatureSecrets {
+	holder := new(SignatureSecrets)
+	holder.Set(secrets...)
+
+	return holder
+}
+
+// Set replaces the secrets.
+// To rotate a secret, set both the new and the old one, and remove the old one
+// once all partners have switched to the new one.
+func (holder *SignatureSecrets) Set(secrets ...string) {
+	byteSecrets := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret != "" {
+			byteSecrets = append(byteSecrets, []byte(secret))
+		}
+	}
+	holder.secrets.Store(&byteSecrets)
+}
+
+// Secrets ...see [SignatureSecretProvider.Secrets].
+func (holder *SignatureSecrets) Secrets(*http.Request) ([][]byte, error) {
+	return *holder.secrets.Load(), nil
+}
+
+// NewXConfSignatureSecrets instantiates a new signature secrets holder, configured from given xconf.Config key.
+// The key's value can be a string (one secret) or a list of strings (multiple secrets, for rotation).
+// If the config is a [xconf.DefaultConfig], secrets are reloaded when the key's value changes;
+// an invalid new value is disregarded, previous secrets being kept.
+func NewXConfSignatureSecrets(config xconf.Config, secretsKey string) (*SignatureSecrets, error) {
+	secrets, err := signatureSecretsFromConfig(config.Get(secretsKey))
+	if err != nil {
+		return nil, err
+	}
+	holder := NewSignatureSecrets(secrets...)
+
+	if defConfig, ok := config.(*xconf.DefaultConfig); ok {
+		defConfig.RegisterObserver(func(cfg xconf.Config, changedKeys ...string) {
+			if !slices.Contains(changedKeys, secretsKey) {
+				return
+			}
+			if secrets, err := signatureSecretsFromConfig(cfg.Get(secretsKey)); err == nil {
+				holder.Set(secrets...)
+			}
+		})
+	}
+
+	return holder, nil
+}
+
+func signatureSecretsFromConfig(value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return []string{v}, nil
+		}
+	case []string:
+		if len(v) > 0 {
+			return v, nil
+		}
+	case []any:
+		secrets := make([]string, 0, len(v))
+		for _, item := range v {
+			secret, ok := item.(string)
+			if !ok {
+				return nil, xerr.New("signature secrets should be strings")
+			}
+			secrets = append(secrets, secret)
+		}
+		if len(secrets) > 0 {
+			return secrets, nil
+		}
+	}
+
+	return nil, xerr.New("signature secrets config should be a non empty string or list of strings")
+}
+
+// SignatureReplayStore is the contract for a store which remembers already seen signatures,
+// in order to reject replayed requests.
+type SignatureReplayStore interface {
+	// Seen marks the signature as seen for given ttl,
+	// returning whether it was already seen.
+	Seen(ctx context.Context, signature string, ttl time.Duration) (bool, error)
+}
+
+// MemorySignatureReplayStore is an in-memory [SignatureReplayStore].
+// Expired signatures are evicted lazily.
+// It is concurrent safe to use.
+type MemorySignatureReplayStore struct {
+	signatures map[string]time.Time // signature => expiry
+	nextSweep  time.Time
+	mu         sync.Mutex
+	now        func() time.Time
+}
+
+// NewMemorySignatureReplayStore instantiates a new in-memory signature replay store.
+func NewMemorySignatureReplayStore() *MemorySignatureReplayStore {
+	return &MemorySignatureReplayStore{
+		signatures: make(map[string]time.Time),
+		now:        time.Now,
+	}
+}
+
+// Seen ...see [SignatureReplayStore.Seen].
+func (store *MemorySignatureReplayStore) Seen(_ context.Context, signature string, ttl time.Duration) (bool, error) {
+	now := store.now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.After(store.nextSweep) {
+		for sig, expiresAt := range store.signatures {
+			if now.After(expiresAt) {
+				delete(store.signatures, sig)
+			}
+		}
+		store.nextSweep = now.Add(ttl)
+	}
+
+	if expiresAt, found := store.signatures[signature]; found && !now.After(expiresAt) {
+		return true, nil
+	}
+	store.signatures[signature] = now.Add(ttl)
+
+	return false, nil
+}
+
+// SignatureConfig holds the configuration for [VerifySignature] middleware.
+type SignatureConfig struct {
+	// Scheme is the signature scheme, like [NewGitHubSignatureScheme], [NewStripeSignatureScheme],
+	// or a custom [HMACSignatureScheme].
+	Scheme SignatureScheme
+	// Secrets provides the secrets requests are signed with.
+	Secrets SignatureSecretProvider
+	// MaxBodyBytes is the max allowed body size. Defaults to [httpTransport.GetRequestBody] default limit.
+	MaxBodyBytes int64
+	// Tolerance is the accepted difference between signature's timestamp and current time,
+	// for timestamped schemes. Defaults to 5 minutes.
+	Tolerance time.Duration
+	// ReplayStore, if set, is used to reject a signature already seen within the tolerance window.
+	ReplayStore SignatureReplayStore
+}
+
+const defaultSignatureTolerance = 5 * time.Minute
+
+// VerifySignature is a decorator/middleware that verifies the HMAC signature of the request,
+// as webhooks are usually signed.
+// The body is buffered (within the configured size limit) and restored for the next handler.
+// Requests with a missing / invalid / expired / replayed signature get a 401 Unauthorized response,
+// requests with a too large body get a 413 Request Entity Too Large response.
+// Secrets provider / replay store errors are logged and a 500 Internal Server Error response is sent.
+func VerifySignature(next http.Handler, config SignatureConfig, logger *slog.Logger) http.Handler {
+	if config.Tolerance <= 0 {
+		config.Tolerance = defaultSignatureTolerance
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(httpTransport.GetRequestBody(w, r, config.MaxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			}
+
+			return
+		}
+
+		secrets, err := config.Secrets.Secrets(r)
+		if err != nil {
+			logSignatureError(logger, r, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+		info, err := config.Scheme.Verify(r.Header, body, secrets)
+		if err == nil && !info.Timestamp.IsZero() {
+			if age := time.Since(info.Timestamp); age > config.Tolerance || age < -config.Tolerance {
+				err = fmt.Errorf("%w: timestamp outside of tolerance window", ErrInvalidSignature)
+			}
+		}
+		if err == nil && config.ReplayStore != nil {
+			seen, errStore := config.ReplayStore.Seen(r.Context(), info.Signature, 2*config.Tolerance)
+			if errStore != nil {
+				logSignatureError(logger, r, errStore)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+			if seen {
+				err = fmt.Errorf("%w: replayed signature", ErrInvalidSignature)
+			}
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		r.ContentLength = int64(len(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func logSignatureError(logger *slog.Logger, r *http.Request, err error) {
+	logger.Error(
+		"could not verify request signature",
+		"err", err,
+		"path", r.URL.Path,
+		"method", r.Method,
+	)
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureInfo holds information about a verified signature.
+type SignatureInfo struct {
+	// Signature is the matched signature, as sent by the client.
+	Signature string
+	// Timestamp is the signing time, for timestamped schemes, zero value otherwise.
+	Timestamp time.Time
+}
+
+// SignatureScheme is the contract for a request signature scheme.
+type SignatureScheme interface {
+	// Verify verifies the signature found in headers against the body,
+	// accepting it if it matches any of the secrets.
+	// It should return an error wrapping [ErrInvalidSignature] if signature is missing or not valid.
+	Verify(header http.Header, body []byte, secrets [][]byte) (SignatureInfo, error)
+}
+
+// SignatureEncoding is the encoding of the signature.
+type SignatureEncoding int
+
+const (
+	// SignatureEncodingHex represents hex encoding.
+	SignatureEncodingHex SignatureEncoding = iota
+	// SignatureEncodingBase64 represents standard base64 encoding.
+	SignatureEncodingBase64
+)
+
+func (enc SignatureEncoding) decode(signature string) ([]byte, error) {
+	if enc == SignatureEncodingBase64 {
+		return base64.StdEncoding.DecodeString(signature)
+	}
+
+	return hex.DecodeString(signature)
+}
+
+// HMACSignatureScheme is a configurable HMAC [SignatureScheme].
+//
+// Usage example (Slack like scheme):
+//
+//	scheme := middleware.HMACSignatureScheme{
+//	    Header:          "X-Slack-Signature",
+//	    Prefix:          "v0=",
+//	    Hash:            sha256.New,
+//	    TimestampHeader: "X-Slack-Request-Timestamp",
+//	    SignedPayload: func(timestamp string, body []byte) []byte {
+//	        return append([]byte("v0:"+timestamp+":"), body...)
+//	    },
+//	}
+type HMACSignatureScheme struct {
+	// Header is the header carrying the signature.
+	Header string
+	// Prefix is the signature's prefix, like "sha256=".
+	Prefix string
+	// Hash is the hash function, like sha256.New or sha512.New.
+	// Defaults to sha256.New.
+	Hash func() hash.Hash
+	// Encoding is the signature's encoding, hex by default.
+	Encoding SignatureEncoding
+	// TimestampHeader, if set, is the header carrying the signing unix timestamp.
+	TimestampHeader string
+	// SignedPayload builds the payload which is signed.
+	// Defaults to the body if no timestamp header is configured, or to "timestamp.body" otherwise.
+	SignedPayload func(timestamp string, body []byte) []byte
+}
+
+// NewGitHubSignatureScheme returns the GitHub webhooks signature scheme
+// ("X-Hub-Signature-256: sha256=<hex hmac-sha256 of body>").
+func NewGitHubSignatureScheme() HMACSignatureScheme {
+	return HMACSignatureScheme{
+		Header: "X-Hub-Signature-256",
+		Prefix: "sha256=",
+		Hash:   sha256.New,
+	}
+}
+
+// Verify ...see [SignatureScheme.Verify].
+func (scheme HMACSignatureScheme) Verify(header http.Header, body []byte, secrets [][]byte) (SignatureInfo, error) {
+	var info SignatureInfo
+	signature, found := strings.CutPrefix(header.Get(scheme.Header), scheme.Prefix)
+	if !found || signature == "" {
+		return info, fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	decodedSignature, err := scheme.Encoding.decode(signature)
+	if err != nil {
+		return info, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+
+	payload := body
+	var timestamp string
+	if scheme.TimestampHeader != "" {
+		timestamp = header.Get(scheme.TimestampHeader)
+		if info.Timestamp, err = parseUnixTimestamp(timestamp); err != nil {
+			return info, err
+		}
+		payload = append([]byte(timestamp+"."), body...)
+	}
+	if scheme.SignedPayload != nil {
+		payload = scheme.SignedPayload(timestamp, body)
+	}
+
+	hashFunc := scheme.Hash
+	if hashFunc == nil {
+		hashFunc = sha256.New
+	}
+	if !hmacMatchesAny(hashFunc, secrets, payload, decodedSignature) {
+		return info, ErrInvalidSignature
+	}
+	info.Signature = signature
+
+	return info, nil
+}
+
+// stripeSignatureScheme is the Stripe webhooks [SignatureScheme].
+type stripeSignatureScheme struct{}
+
+// NewStripeSignatureScheme returns the Stripe webhooks signature scheme
+// ("Stripe-Signature: t=<unix timestamp>,v1=<hex hmac-sha256 of "timestamp.body">[,v1=...]").
+func NewStripeSignatureScheme() SignatureScheme {
+	return stripeSignatureScheme{}
+}
+
+// Verify ...see [SignatureScheme.Verify].
+func (stripeSignatureScheme) Verify(header http.Header, body []byte, secrets [][]byte) (SignatureInfo, error) {
+	var (
+		info       SignatureInfo
+		timestamp  string
+		signatures []string
+	)
+	for part := range strings.SplitSeq(header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if len(signatures) == 0 {
+		return info, fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	var err error
+	if info.Timestamp, err = parseUnixTimestamp(timestamp); err != nil {
+		return info, err
+	}
+
+	payload := append([]byte(timestamp+"."), body...)
+	for _, signature := range signatures {
+		decodedSignature, err := hex.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+		if hmacMatchesAny(sha256.New, secrets, payload, decodedSignature) {
+			info.Signature = signature
+
+			return info, nil
+		}
+	}
+
+	return info, ErrInvalidSignature
+}
+
+func parseUnixTimestamp(timestamp string) (time.Time, error) {
+	unixTimestamp, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: missing or malformed timestamp", ErrInvalidSignature)
+	}
+
+	return time.Unix(unixTimestamp, 0), nil
+}
+
+// hmacMatchesAny checks, in constant time, if signature matches the payload's HMAC for any of the secrets.
+func hmacMatchesAny(hashFunc func() hash.Hash, secrets [][]byte, payload, signature []byte) bool {
+	var matched bool
+	for _, secret := range secrets {
+		mac := hmac.New(hashFunc, secret)
+		mac.Write(payload)
+		if hmac.Equal(signature, mac.Sum(nil)) {
+			matched = true
+		}
+	}
+
+	return matched
+}
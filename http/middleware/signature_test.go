@@ -0,0 +1,448 @@
+package middleware_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xconf"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	t.Run("GitHub scheme", testVerifySignatureGitHub)
+	t.Run("Stripe scheme", testVerifySignatureStripe)
+	t.Run("custom HMAC-SHA512 base64 timestamped scheme", testVerifySignatureCustomScheme)
+	t.Run("zero value HMAC scheme defaults to HMAC-SHA256", testVerifySignatureZeroValueScheme)
+	t.Run("rotated secrets are accepted", testVerifySignatureRotatedSecrets)
+	t.Run("replayed request is rejected", testVerifySignatureReplay)
+	t.Run("too large body", testVerifySignatureTooLargeBody)
+	t.Run("secrets provider error", testVerifySignatureSecretsError)
+}
+
+const webhookPayload = `{"event":"payment.succeeded","id":"evt_123"}`
+
+func hmacHex(hashFunc func() hash.Hash, secret, payload string) string {
+	mac := hmac.New(hashFunc, []byte(secret))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookHandler returns a handler which records the body it received.
+func webhookHandler(receivedBody *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*receivedBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func newWebhookRequest(body string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/webhooks", strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	return req
+}
+
+func testVerifySignatureGitHub(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name           string
+		signature      string
+		expectedStatus int
+	}{
+		{
+			name:           "valid signature",
+			signature:      "sha256=" + hmacHex(sha256.New, "gh-secret", webhookPayload),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid signature",
+			signature:      "sha256=" + hmacHex(sha256.New, "other-secret", webhookPayload),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "malformed signature",
+			signature:      "sha256=xyz",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing signature",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				receivedBody string
+				subject      = middleware.VerifySignature(
+					webhookHandler(&receivedBody),
+					middleware.SignatureConfig{
+						Scheme:  middleware.NewGitHubSignatureScheme(),
+						Secrets: middleware.NewSignatureSecrets("gh-secret"),
+					},
+					slog.New(mock.NewSlogHandler()),
+				)
+				req = newWebhookRequest(webhookPayload, map[string]string{"X-Hub-Signature-256": test.signature})
+				w   = httptest.NewRecorder()
+			)
+
+			// act
+			subject.ServeHTTP(w, req)
+
+			// assert
+			assert.Equal(t, test.expectedStatus, w.Code)
+			if test.expectedStatus == http.StatusNoContent {
+				assert.Equal(t, webhookPayload, receivedBody)
+			} else {
+				assert.Equal(t, "", receivedBody)
+			}
+		})
+	}
+}
+
+func testVerifySignatureStripe(t *testing.T) {
+	t.Parallel()
+
+	var (
+		now   = time.Now().Unix()
+		stale = now - 600
+	)
+	stripeHeader := func(timestamp int64, secret string) string {
+		ts := strconv.FormatInt(timestamp, 10)
+
+		return "t=" + ts + ",v1=" + hmacHex(sha256.New, secret, ts+"."+webhookPayload) + ",v0=ignored"
+	}
+	tests := [...]struct {
+		name           string
+		signature      string
+		expectedStatus int
+	}{
+		{
+			name:           "valid signature",
+			signature:      stripeHeader(now, "whsec_test"),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "one of multiple signatures is valid",
+			signature: stripeHeader(now, "whsec_old") + ",v1=" +
+				hmacHex(sha256.New, "whsec_test", strconv.FormatInt(now, 10)+"."+webhookPayload),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "timestamp outside tolerance",
+			signature:      stripeHeader(stale, "whsec_test"),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "timestamp tampered",
+			signature: strings.Replace(
+				stripeHeader(now, "whsec_test"), strconv.FormatInt(now, 10), strconv.FormatInt(now-1, 10), 1,
+			),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing timestamp",
+			signature:      "v1=" + hmacHex(sha256.New, "whsec_test", webhookPayload),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			var (
+				receivedBody string
+				subject      = middleware.VerifySignature(
+					webhookHandler(&receivedBody),
+					middleware.SignatureConfig{
+						Scheme:  middleware.NewStripeSignatureScheme(),
+						Secrets: middleware.NewSignatureSecrets("whsec_test"),
+					},
+					slog.New(mock.NewSlogHandler()),
+				)
+				req = newWebhookRequest(webhookPayload, map[string]string{"Stripe-Signature": test.signature})
+				w   = httptest.NewRecorder()
+			)
+
+			// act
+			subject.ServeHTTP(w, req)
+
+			// assert
+			assert.Equal(t, test.expectedStatus, w.Code)
+		})
+	}
+}
+
+func testVerifySignatureCustomScheme(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		receivedBody string
+		timestamp    = strconv.FormatInt(time.Now().Unix(), 10)
+		mac          = hmac.New(sha512.New, []byte("custom-secret"))
+	)
+	mac.Write([]byte("v0:" + timestamp + ":" + webhookPayload))
+	var (
+		subject = middleware.VerifySignature(
+			webhookHandler(&receivedBody),
+			middleware.SignatureConfig{
+				Scheme: middleware.HMACSignatureScheme{
+					Header:          "X-Signature",
+					Prefix:          "v0=",
+					Hash:            sha512.New,
+					Encoding:        middleware.SignatureEncodingBase64,
+					TimestampHeader: "X-Timestamp",
+					SignedPayload: func(timestamp string, body []byte) []byte {
+						return append([]byte("v0:"+timestamp+":"), body...)
+					},
+				},
+				Secrets: middleware.NewSignatureSecrets("custom-secret"),
+			},
+			slog.New(mock.NewSlogHandler()),
+		)
+		req = newWebhookRequest(webhookPayload, map[string]string{
+			"X-Signature": "v0=" + base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+			"X-Timestamp": timestamp,
+		})
+		w = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, webhookPayload, receivedBody)
+}
+
+func testVerifySignatureZeroValueScheme(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		receivedBody string
+		subject      = middleware.VerifySignature(
+			webhookHandler(&receivedBody),
+			middleware.SignatureConfig{
+				Scheme:  middleware.HMACSignatureScheme{Header: "X-Signature"},
+				Secrets: middleware.NewSignatureSecrets("secret"),
+			},
+			slog.New(mock.NewSlogHandler()),
+		)
+		serve = func(signature string) int {
+			w := httptest.NewRecorder()
+			subject.ServeHTTP(w, newWebhookRequest(webhookPayload, map[string]string{"X-Signature": signature}))
+
+			return w.Code
+		}
+	)
+
+	// act & assert
+	assert.Equal(t, http.StatusNoContent, serve(hmacHex(sha256.New, "secret", webhookPayload)))
+	assert.Equal(t, webhookPayload, receivedBody)
+	assert.Equal(t, http.StatusUnauthorized, serve(hmacHex(sha512.New, "secret", webhookPayload)))
+}
+
+func testVerifySignatureRotatedSecrets(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		receivedBody string
+		secrets      = middleware.NewSignatureSecrets("old-secret")
+		subject      = middleware.VerifySignature(
+			webhookHandler(&receivedBody),
+			middleware.SignatureConfig{Scheme: middleware.NewGitHubSignatureScheme(), Secrets: secrets},
+			slog.New(mock.NewSlogHandler()),
+		)
+		oldSignature = "sha256=" + hmacHex(sha256.New, "old-secret", webhookPayload)
+		newSignature = "sha256=" + hmacHex(sha256.New, "new-secret", webhookPayload)
+		serve        = func(signature string) int {
+			w := httptest.NewRecorder()
+			subject.ServeHTTP(w, newWebhookRequest(webhookPayload, map[string]string{"X-Hub-Signature-256": signature}))
+
+			return w.Code
+		}
+	)
+
+	// act & assert
+	assert.Equal(t, http.StatusUnauthorized, serve(newSignature))
+
+	secrets.Set("new-secret", "old-secret")
+	assert.Equal(t, http.StatusNoContent, serve(newSignature))
+	assert.Equal(t, http.StatusNoContent, serve(oldSignature))
+
+	secrets.Set("new-secret")
+	assert.Equal(t, http.StatusUnauthorized, serve(oldSignature))
+}
+
+func testVerifySignatureReplay(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		receivedBody string
+		timestamp    = strconv.FormatInt(time.Now().Unix(), 10)
+		signature    = "t=" + timestamp + ",v1=" + hmacHex(sha256.New, "whsec_test", timestamp+"."+webhookPayload)
+		subject      = middleware.VerifySignature(
+			webhookHandler(&receivedBody),
+			middleware.SignatureConfig{
+				Scheme:      middleware.NewStripeSignatureScheme(),
+				Secrets:     middleware.NewSignatureSecrets("whsec_test"),
+				ReplayStore: middleware.NewMemorySignatureReplayStore(),
+			},
+			slog.New(mock.NewSlogHandler()),
+		)
+		w1 = httptest.NewRecorder()
+		w2 = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w1, newWebhookRequest(webhookPayload, map[string]string{"Stripe-Signature": signature}))
+	subject.ServeHTTP(w2, newWebhookRequest(webhookPayload, map[string]string{"Stripe-Signature": signature}))
+
+	// assert
+	assert.Equal(t, http.StatusNoContent, w1.Code)
+	assert.Equal(t, http.StatusUnauthorized, w2.Code)
+}
+
+func testVerifySignatureTooLargeBody(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		receivedBody string
+		subject      = middleware.VerifySignature(
+			webhookHandler(&receivedBody),
+			middleware.SignatureConfig{
+				Scheme:       middleware.NewGitHubSignatureScheme(),
+				Secrets:      middleware.NewSignatureSecrets("gh-secret"),
+				MaxBodyBytes: 10,
+			},
+			slog.New(mock.NewSlogHandler()),
+		)
+		req = newWebhookRequest(webhookPayload, map[string]string{
+			"X-Hub-Signature-256": "sha256=" + hmacHex(sha256.New, "gh-secret", webhookPayload),
+		})
+		w = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "", receivedBody)
+}
+
+func testVerifySignatureSecretsError(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		receivedBody string
+		loggerMock   = mock.NewSlogHandler()
+		subject      = middleware.VerifySignature(
+			webhookHandler(&receivedBody),
+			middleware.SignatureConfig{
+				Scheme:  middleware.NewGitHubSignatureScheme(),
+				Secrets: errSignatureSecrets{},
+			},
+			slog.New(loggerMock),
+		)
+		req = newWebhookRequest(webhookPayload, nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func TestNewXConfSignatureSecrets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("secrets are reloaded", testNewXConfSignatureSecretsReload)
+	t.Run("invalid config", testNewXConfSignatureSecretsInvalidConfig)
+}
+
+func testNewXConfSignatureSecretsReload(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		configSecrets atomic.Value
+		loader        = xconf.LoaderFunc(func() (map[string]any, error) {
+			return map[string]any{"webhook_secrets": configSecrets.Load()}, nil
+		})
+	)
+	configSecrets.Store([]any{"secret-1"})
+	config, err := xconf.NewDefaultConfig(loader, xconf.DefaultConfigWithReloadInterval(10*time.Millisecond))
+	assert.RequireNil(t, err)
+	defer config.Close()
+	subject, err := middleware.NewXConfSignatureSecrets(config, "webhook_secrets")
+	assert.RequireNil(t, err)
+
+	// act & assert
+	secrets, _ := subject.Secrets(nil)
+	assert.Equal(t, [][]byte{[]byte("secret-1")}, secrets)
+
+	configSecrets.Store([]any{"secret-2", "secret-1"})
+	time.Sleep(50 * time.Millisecond)
+
+	secrets, _ = subject.Secrets(nil)
+	assert.Equal(t, [][]byte{[]byte("secret-2"), []byte("secret-1")}, secrets)
+}
+
+func testNewXConfSignatureSecretsInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	config := xconf.NewMockConfig("webhook_secrets", []any{"secret-1", 123})
+
+	// act
+	subject, err := middleware.NewXConfSignatureSecrets(config, "webhook_secrets")
+
+	// assert
+	assert.NotNil(t, err)
+	assert.Nil(t, subject)
+}
+
+type errSignatureSecrets struct{}
+
+func (errSignatureSecrets) Secrets(*http.Request) ([][]byte, error) {
+	return nil, errors.New("intentionally triggered secrets error")
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig holds the configuration for [Timeout] middleware.
+type TimeoutConfig struct {
+	// Timeout is the max time a request is allowed to be served in.
+	// If not positive, requests have no deadline, unless one is requested through header.
+	Timeout time.Duration
+	// HeaderName is the name of an optional header through which an upstream can request a timeout,
+	// like "X-Request-Timeout". Its value can be a number of seconds ("1.5"), or a duration ("1500ms").
+	// The header is ignored if empty.
+	HeaderName string
+	// MaxTimeout caps the timeout requested through header.
+	// If not set, Timeout is used as cap (case in which, if Timeout is not set either, header is ignored).
+	MaxTimeout time.Duration
+	// StatusCode is the status code sent when the request times out.
+	// Defaults to [http.StatusServiceUnavailable] if not set, you may prefer [http.StatusGatewayTimeout].
+	StatusCode int
+	// Body is the body sent when the request times out.
+	// Defaults to "request timed out" if not set.
+	Body string
+	// ContentType is the Content-Type of the body sent when the request times out.
+	// Defaults to "text/plain; charset=utf-8" if not set.
+	ContentType string
+}
+
+// Timeout is a decorator/middleware that attaches a deadline to the request context
+// and responds with configured status code and body if the handler exceeds it.
+// The handler's writes after timeout fail with [http.ErrHandlerTimeout].
+//
+// Like [http.TimeoutHandler], the handler's response is buffered,
+// so streaming (Flush) and Hijack are not supported behind this middleware.
+// A panic in the handler is propagated to the serving goroutine, so that it can
+// be caught by an outer [Recover] middleware.
+// For per route timeouts, decorate the handlers registered on the [http.ServeMux] with different configs.
+func Timeout(next http.Handler, config TimeoutConfig) http.Handler {
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	if config.Body == "" {
+		config.Body = "request timed out"
+	}
+	if config.ContentType == "" {
+		config.ContentType = "text/plain; charset=utf-8"
+	}
+	if config.MaxTimeout <= 0 {
+		config.MaxTimeout = config.Timeout
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := config.requestTimeout(r)
+		if timeout <= 0 { // no deadline
+			next.ServeHTTP(w, r)
+
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		var (
+			done      = make(chan struct{})
+			panicChan = make(chan any, 1)
+			tw        = &timeoutWriter{h: make(http.Header)}
+		)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			dst := w.Header()
+			for k, vv := range tw.h {
+				dst[k] = vv
+			}
+			if !tw.wroteHeader {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			_, _ = w.Write(tw.buf.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				tw.err = http.ErrHandlerTimeout
+				w.Header().Set("Content-Type", config.ContentType)
+				w.WriteHeader(config.StatusCode)
+				_, _ = w.Write([]byte(config.Body))
+			} else { // client went away
+				tw.err = ctx.Err()
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}
+	})
+}
+
+// requestTimeout returns the timeout to be applied for the request, a not positive one meaning no deadline.
+func (config TimeoutConfig) requestTimeout(r *http.Request) time.Duration {
+	if config.HeaderName == "" {
+		return config.Timeout
+	}
+	headerValue := r.Header.Get(config.HeaderName)
+	if headerValue == "" {
+		return config.Timeout
+	}
+
+	timeout, err := time.ParseDuration(headerValue)
+	if err != nil {
+		seconds, errFloat := strconv.ParseFloat(headerValue, 64)
+		if errFloat != nil {
+			return config.Timeout
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	if timeout <= 0 {
+		return config.Timeout
+	}
+
+	return min(timeout, config.MaxTimeout)
+}
+
+// timeoutWriter is a buffered response writer, which refuses writes after timeout.
+type timeoutWriter struct {
+	h           http.Header
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	err         error
+	mu          sync.Mutex
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.err != nil {
+		return 0, tw.err
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.err != nil || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if code >= 100 && code < 200 {
+		return // informational responses cannot be buffered.
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
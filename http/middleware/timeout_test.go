@@ -0,0 +1,215 @@
+package middleware_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport/http/middleware"
+	"github.com/actforgood/xtransport/testing/assert"
+	"github.com/actforgood/xtransport/testing/mock"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("response is passed through if handler finishes in time", testTimeoutInTime)
+	t.Run("timeout response is sent if handler exceeds deadline", testTimeoutExceeded)
+	t.Run("timeout requested through header is honoured and capped", testTimeoutFromHeader)
+	t.Run("zero timeout means no deadline", testTimeoutZero)
+	t.Run("panic is propagated to recover middleware", testTimeoutPanic)
+	t.Run("composes with access log", testTimeoutWithAccessLog)
+}
+
+func testTimeoutInTime(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hasDeadline := r.Context().Deadline()
+			assert.True(t, hasDeadline)
+			w.Header().Set("X-Foo", "bar")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(t.Name()))
+		})
+		subject = middleware.Timeout(nextHandler, middleware.TimeoutConfig{Timeout: time.Second})
+		req     = httptest.NewRequest(http.MethodPost, "http://example.com/fast", nil)
+		w       = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "bar", w.Header().Get("X-Foo"))
+	assert.Equal(t, t.Name(), w.Body.String())
+}
+
+func testTimeoutExceeded(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		writeErrChan = make(chan error, 1)
+		nextHandler  = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			_, err := w.Write([]byte("too late"))
+			writeErrChan <- err
+		})
+		subject = middleware.Timeout(nextHandler, middleware.TimeoutConfig{
+			Timeout:     10 * time.Millisecond,
+			StatusCode:  http.StatusGatewayTimeout,
+			Body:        `{"error":"timeout"}`,
+			ContentType: "application/json",
+		})
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/slow", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"timeout"}`, w.Body.String())
+	assert.True(t, errors.Is(<-writeErrChan, http.ErrHandlerTimeout))
+}
+
+func testTimeoutFromHeader(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		actualTimeouts = make(chan time.Duration, 3)
+		nextHandler    = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, _ := r.Context().Deadline()
+			actualTimeouts <- time.Until(deadline)
+			w.WriteHeader(http.StatusOK)
+		})
+		subject = middleware.Timeout(nextHandler, middleware.TimeoutConfig{
+			Timeout:    time.Second,
+			HeaderName: "X-Request-Timeout",
+			MaxTimeout: 5 * time.Second,
+		})
+	)
+	tests := [...]struct {
+		headerValue string
+		min, max    time.Duration
+	}{
+		{headerValue: "0.2", min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{headerValue: "60s", min: 4 * time.Second, max: 5 * time.Second},
+		{headerValue: "invalid", min: 900 * time.Millisecond, max: time.Second},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/header", nil)
+		req.Header.Set("X-Request-Timeout", test.headerValue)
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		// assert
+		actualTimeout := <-actualTimeouts
+		assert.True(t, actualTimeout > test.min && actualTimeout <= test.max)
+	}
+}
+
+func testTimeoutZero(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		hasDeadlines = make(chan bool, 2)
+		nextHandler  = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hasDeadline := r.Context().Deadline()
+			hasDeadlines <- hasDeadline
+			w.WriteHeader(http.StatusNoContent)
+		})
+		zeroValueSubject  = middleware.Timeout(nextHandler, middleware.TimeoutConfig{})
+		headerOnlySubject = middleware.Timeout(nextHandler, middleware.TimeoutConfig{
+			HeaderName: "X-Request-Timeout",
+			MaxTimeout: time.Second,
+		})
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/no-deadline", nil)
+	)
+	req.Header.Set("X-Request-Timeout", "0.5")
+
+	for _, subject := range [...]http.Handler{zeroValueSubject, headerOnlySubject} {
+		w := httptest.NewRecorder()
+
+		// act
+		subject.ServeHTTP(w, req)
+
+		// assert
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+	assert.Equal(t, false, <-hasDeadlines)
+	assert.Equal(t, true, <-hasDeadlines)
+}
+
+func testTimeoutPanic(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			panic(errors.New("intentionally triggered panic"))
+		})
+		loggerMock = mock.NewSlogHandler()
+		logger     = slog.New(loggerMock)
+		subject    = middleware.Recover(
+			middleware.Timeout(nextHandler, middleware.TimeoutConfig{Timeout: time.Second}),
+			logger,
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/panic", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, loggerMock.LogCallsCount(slog.LevelError))
+}
+
+func testTimeoutWithAccessLog(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			w.WriteHeader(http.StatusOK)
+		})
+		loggerMock = mock.NewSlogHandler()
+		logger     = slog.New(loggerMock)
+		subject    = middleware.AccessLog(
+			middleware.Timeout(nextHandler, middleware.TimeoutConfig{Timeout: 5 * time.Millisecond}),
+			logger,
+			nil,
+		)
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/slow", nil)
+		w   = httptest.NewRecorder()
+	)
+
+	// act
+	subject.ServeHTTP(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	respBody, _ := io.ReadAll(w.Result().Body)
+	assert.Equal(t, "request timed out", string(respBody))
+	if assert.Equal(t, 1, loggerMock.LogCallsCount(middleware.AccessLevel)) {
+		assert.Equal(t, int64(http.StatusServiceUnavailable), loggerMock.ValueAt(1, "statusCode"))
+	}
+}
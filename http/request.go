@@ -4,7 +4,6 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"strings"
 )
 
 const defaultMaxBodyBytes int64 = 524288 // 0.5 Mb
@@ -22,25 +21,15 @@ func GetRequestBody(w http.ResponseWriter, r *http.Request, maxBodyBytes ...int6
 	return http.MaxBytesReader(w, r.Body, maxBody)
 }
 
-// GetClientIP returns client ip from following sources, in this order:
-// X-Real-Ip, X-Forwarded-For (first ip in an eventual list), r.RemoteAddr.
-// Note: we assume these headers come from a trusted proxy,
-// as client can also set them to any arbitrary value which may lead to ip spoofing.
+// GetClientIP returns client ip, as resolved by [DefaultClientIPResolver],
+// which trusts no proxy, and thus returns the request's remote address.
+// Note: forwarding headers are ignored, as client can set them to any arbitrary value
+// which may lead to ip spoofing. Use a [ClientIPResolver] configured with your trusted proxies
+// to honour them.
 func GetClientIP(r *http.Request) net.IP {
-	if r.Header.Get("X-Real-Ip") != "" {
-		if ip := net.ParseIP(r.Header.Get("X-Real-Ip")); ip != nil {
-			return ip
-		}
+	if addr := DefaultClientIPResolver.ClientIP(r); addr.IsValid() {
+		return net.IP(addr.AsSlice())
 	}
-	if r.Header.Get("X-Forwarded-For") != "" {
-		ips := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
-		for _, ip := range ips {
-			if ip := net.ParseIP(strings.TrimSpace(ip)); ip != nil {
-				return ip
-			}
-		}
-	}
-	addr, _, _ := net.SplitHostPort(r.RemoteAddr)
 
-	return net.ParseIP(addr)
+	return nil
 }
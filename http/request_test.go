@@ -61,34 +61,19 @@ func testGetRequestBodyLimitNotReached(t *testing.T) {
 func TestGetClientIP(t *testing.T) {
 	t.Parallel()
 
-	t.Run("returns real ip", testGetClientIPWithRealIP)
-	t.Run("returns first ip from XFF", testGetClientIPWithXFF)
+	t.Run("ignores forwarding headers", testGetClientIPIgnoresForwardingHeaders)
 	t.Run("returns remote address", testGetClientIPWithRemoteAddr)
 }
 
-func testGetClientIPWithRealIP(t *testing.T) {
-	t.Parallel()
-
-	// arrange
-	req := httptest.NewRequest(http.MethodGet, "http://example.com/realip", nil)
-	expectedIP := "1.2.3.4"
-	req.Header.Set("X-Real-Ip", expectedIP)
-	req.Header.Set("X-Forwarded-For", "8.8.8.8, 9.9.9.9")
-
-	// act
-	actualIP := httpTransport.GetClientIP(req)
-
-	// assert
-	assert.Equal(t, expectedIP, actualIP.String())
-}
-
-func testGetClientIPWithXFF(t *testing.T) {
+func testGetClientIPIgnoresForwardingHeaders(t *testing.T) {
 	t.Parallel()
 
 	// arrange
 	req := httptest.NewRequest(http.MethodGet, "http://example.com/xff", nil)
-	expectedIP := "8.8.8.8"
-	req.Header.Set("X-Forwarded-For", expectedIP+", 9.9.9.9")
+	req.Header.Set("X-Real-Ip", "1.2.3.4")
+	req.Header.Set("X-Forwarded-For", "8.8.8.8, 9.9.9.9")
+	req.Header.Set("Forwarded", "for=8.8.8.8")
+	expectedIP := "192.0.2.1"
 
 	// act
 	actualIP := httpTransport.GetClientIP(req)
@@ -11,6 +11,7 @@ import (
 
 	"github.com/actforgood/xtransport"
 	httpTransport "github.com/actforgood/xtransport/http"
+	"github.com/actforgood/xtransport/metrics"
 	"github.com/actforgood/xtransport/testing/assert"
 	"github.com/actforgood/xtransport/testing/mock"
 )
@@ -121,3 +122,23 @@ func TestHTTPTransport(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, false, probe.IsReady())
 }
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		req      = httptest.NewRequest(http.MethodGet, "http://example.com/metrics", nil)
+		w        = httptest.NewRecorder()
+		registry = metrics.NewRegistry()
+	)
+	registry.NewCounterVec("jobs_total", "Total jobs.").Inc()
+
+	// act
+	httpTransport.Metrics(registry)(w, req)
+
+	// assert
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Result().Header.Get("Content-Type"))
+	assert.Equal(t, "# HELP jobs_total Total jobs.\n# TYPE jobs_total counter\njobs_total 1\n", w.Body.String())
+}
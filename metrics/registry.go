@@ -0,0 +1,294 @@
+// Package metrics provides a minimal, dependency free, metrics registry
+// (counters, gauges, histograms with labels), able to expose its metrics
+// in Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets are the default histogram buckets for durations measured in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the default histogram buckets for sizes measured in bytes.
+var DefaultSizeBuckets = []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// DefaultRegistry is the registry used by default by the metrics recorders of this module.
+var DefaultRegistry = NewRegistry()
+
+// metricType is the type of a metric, as exposed in "# TYPE" line.
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// Registry holds metric families and writes them in Prometheus text exposition format.
+// It is concurrent safe to use.
+type Registry struct {
+	families map[string]*family
+	mu       sync.RWMutex
+}
+
+// NewRegistry instantiates a new, empty, [Registry].
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family holds all the series of a metric.
+type family struct {
+	name       string
+	help       string
+	typ        metricType
+	labelNames []string
+	buckets    []float64 // histograms only
+	series     map[string]*series
+	mu         sync.Mutex
+}
+
+// series holds the values of a metric for a set of label values.
+type series struct {
+	labelValues  []string
+	value        float64  // counter / gauge value
+	bucketCounts []uint64 // histograms only, non cumulative
+	count        uint64   // histograms only
+	sum          float64  // histograms only
+}
+
+// register returns the family with given name, creating it if it does not exist.
+// It panics if a family with same name, but a different type / labels was already registered,
+// as it is a programming error.
+func (reg *Registry) register(name, help string, typ metricType, buckets []float64, labelNames []string) *family {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if f, found := reg.families[name]; found {
+		if f.typ != typ || !slices.Equal(f.labelNames, labelNames) {
+			panic("metrics: metric " + name + " was already registered with a different type or labels")
+		}
+
+		return f
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: slices.Clone(labelNames),
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	reg.families[name] = f
+
+	return f
+}
+
+// with executes fn upon the series with given label values, under lock.
+func (f *family) with(labelValues []string, fn func(*series)) {
+	if len(labelValues) != len(f.labelNames) {
+		panic("metrics: metric " + f.name + " expects " + strconv.Itoa(len(f.labelNames)) + " label values")
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, found := f.series[key]
+	if !found {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		if f.typ == typeHistogram {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// CounterVec is a counter partitioned by labels. Its value can only increase.
+type CounterVec struct {
+	f *family
+}
+
+// NewCounterVec registers (or returns the already registered) counter with given name, help and label names.
+func (reg *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{f: reg.register(name, help, typeCounter, nil, labelNames)}
+}
+
+// Inc increments by 1 the counter with given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds given value to the counter with given label values. Negative values are ignored.
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.f.with(labelValues, func(s *series) {
+		s.value += value
+	})
+}
+
+// GaugeVec is a gauge partitioned by labels. Its value can go up and down.
+type GaugeVec struct {
+	f *family
+}
+
+// NewGaugeVec registers (or returns the already registered) gauge with given name, help and label names.
+func (reg *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{f: reg.register(name, help, typeGauge, nil, labelNames)}
+}
+
+// Add adds given (possibly negative) value to the gauge with given label values.
+func (g *GaugeVec) Add(value float64, labelValues ...string) {
+	g.f.with(labelValues, func(s *series) {
+		s.value += value
+	})
+}
+
+// Set sets the value of the gauge with given label values.
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.f.with(labelValues, func(s *series) {
+		s.value = value
+	})
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	f *family
+}
+
+// NewHistogramVec registers (or returns the already registered) histogram with given name, help,
+// buckets' upper bounds and label names. Buckets are sorted; "+Inf" bucket is implicit.
+func (reg *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	buckets = slices.DeleteFunc(buckets, func(bucket float64) bool { return math.IsInf(bucket, 1) })
+
+	return &HistogramVec{f: reg.register(name, help, typeHistogram, buckets, labelNames)}
+}
+
+// Observe records given value into the histogram with given label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.f.with(labelValues, func(s *series) {
+		if idx, _ := slices.BinarySearch(h.f.buckets, value); idx < len(s.bucketCounts) {
+			s.bucketCounts[idx]++
+		}
+		s.count++
+		s.sum += value
+	})
+}
+
+// WriteText writes all the metrics in Prometheus text exposition format (version 0.0.4).
+// Families and series are sorted, for a deterministic output.
+func (reg *Registry) WriteText(w io.Writer) error {
+	reg.mu.RLock()
+	families := make([]*family, 0, len(reg.families))
+	for _, f := range reg.families {
+		families = append(families, f)
+	}
+	reg.mu.RUnlock()
+	slices.SortFunc(families, func(a, b *family) int { return strings.Compare(a.name, b.name) })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.writeText(bw)
+	}
+
+	return bw.Flush()
+}
+
+func (f *family) writeText(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.help != "" {
+		_, _ = w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	}
+	_, _ = w.WriteString("# TYPE " + f.name + " " + string(f.typ) + "\n")
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.typ != typeHistogram {
+			writeSample(w, f.name, f.labelNames, s.labelValues, "", "", s.value)
+
+			continue
+		}
+		var cumulative uint64
+		for idx, bucket := range f.buckets {
+			cumulative += s.bucketCounts[idx]
+			writeSample(w, f.name+"_bucket", f.labelNames, s.labelValues, "le", formatFloat(bucket), float64(cumulative))
+		}
+		writeSample(w, f.name+"_bucket", f.labelNames, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, f.name+"_sum", f.labelNames, s.labelValues, "", "", s.sum)
+		writeSample(w, f.name+"_count", f.labelNames, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// writeSample writes a sample line, like `name{label="value",extraLabel="extraValue"} 1`.
+func writeSample(
+	w *bufio.Writer,
+	name string,
+	labelNames, labelValues []string,
+	extraLabelName, extraLabelValue string,
+	value float64,
+) {
+	_, _ = w.WriteString(name)
+	if len(labelNames) > 0 || extraLabelName != "" {
+		_ = w.WriteByte('{')
+		for idx, labelName := range labelNames {
+			if idx > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(labelName + `="` + escapeLabelValue(labelValues[idx]) + `"`)
+		}
+		if extraLabelName != "" {
+			if len(labelNames) > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(extraLabelName + `="` + extraLabelValue + `"`)
+		}
+		_ = w.WriteByte('}')
+	}
+	_ = w.WriteByte(' ')
+	_, _ = w.WriteString(formatFloat(value))
+	_ = w.WriteByte('\n')
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
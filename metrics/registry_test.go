@@ -0,0 +1,120 @@
+package metrics_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/actforgood/xtransport/metrics"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("metrics are written in text exposition format", testRegistryWriteText)
+	t.Run("already registered metric is returned", testRegistryAlreadyRegistered)
+	t.Run("registering a metric with different labels panics", testRegistryConflict)
+}
+
+func testRegistryWriteText(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject   = metrics.NewRegistry()
+		counter   = subject.NewCounterVec("jobs_total", "Total jobs.\nDone or not.", "queue", "status")
+		gauge     = subject.NewGaugeVec("workers", "")
+		histogram = subject.NewHistogramVec("job_duration_seconds", "Job duration.", []float64{1, 0.5}, "queue")
+		output    strings.Builder
+	)
+	counter.Inc("emails", "ok")
+	counter.Add(2, "emails", "ok")
+	counter.Add(-1, "emails", "ok") // ignored
+	counter.Inc(`sms "eu"`, "failed")
+	gauge.Set(5)
+	gauge.Add(-2)
+	histogram.Observe(0.2, "emails")
+	histogram.Observe(0.5, "emails")
+	histogram.Observe(3, "emails")
+	expected := `# HELP job_duration_seconds Job duration.
+# TYPE job_duration_seconds histogram
+job_duration_seconds_bucket{queue="emails",le="0.5"} 2
+job_duration_seconds_bucket{queue="emails",le="1"} 2
+job_duration_seconds_bucket{queue="emails",le="+Inf"} 3
+job_duration_seconds_sum{queue="emails"} 3.7
+job_duration_seconds_count{queue="emails"} 3
+# HELP jobs_total Total jobs.\nDone or not.
+# TYPE jobs_total counter
+jobs_total{queue="emails",status="ok"} 3
+jobs_total{queue="sms \"eu\"",status="failed"} 1
+# TYPE workers gauge
+workers 3
+`
+
+	// act
+	err := subject.WriteText(&output)
+
+	// assert
+	assert.Nil(t, err)
+	assert.Equal(t, expected, output.String())
+}
+
+func testRegistryAlreadyRegistered(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject = metrics.NewRegistry()
+		output  strings.Builder
+	)
+	subject.NewCounterVec("jobs_total", "Total jobs.", "queue").Inc("emails")
+
+	// act
+	subject.NewCounterVec("jobs_total", "Total jobs.", "queue").Inc("emails")
+
+	// assert
+	_ = subject.WriteText(&output)
+	assert.True(t, strings.Contains(output.String(), `jobs_total{queue="emails"} 2`))
+}
+
+func testRegistryConflict(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	subject := metrics.NewRegistry()
+	subject.NewCounterVec("jobs_total", "Total jobs.", "queue")
+	defer func() {
+		// assert
+		assert.NotNil(t, recover())
+	}()
+
+	// act
+	subject.NewGaugeVec("jobs_total", "Total jobs.", "queue")
+}
+
+// TestRegistry_concurrency records metrics in a concurrent environment.
+// This test does not assert something in particular, it is aimed to be run with '--race'
+// flag and see that no data race occurs.
+func TestRegistry_concurrency(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject   = metrics.NewRegistry()
+		histogram = subject.NewHistogramVec("duration_seconds", "", metrics.DefaultDurationBuckets, "op")
+		wg        sync.WaitGroup
+	)
+
+	// act
+	for range 10 {
+		wg.Go(func() {
+			for range 100 {
+				histogram.Observe(0.1, "read")
+				subject.NewCounterVec("ops_total", "", "op").Inc("read")
+				_ = subject.WriteText(&strings.Builder{})
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,185 @@
+package xtransport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PanicReport holds information about a recovered panic.
+type PanicReport struct {
+	// Value is the recovered value.
+	Value any
+	// Stack is the (trimmed) stack trace of the panicking goroutine.
+	Stack string
+	// Suppressed is the no. of identical panics which were not reported,
+	// since the previous report, due to de-duplication.
+	Suppressed int
+}
+
+// PanicReporter reports recovered panics, for example to an error tracker.
+type PanicReporter interface {
+	// ReportPanic reports given panic.
+	// It is called synchronously, implementations should not block for long.
+	ReportPanic(ctx context.Context, report PanicReport)
+}
+
+// PanicReporterFunc is an adapter to allow the use of an ordinary function as a [PanicReporter].
+type PanicReporterFunc func(ctx context.Context, report PanicReport)
+
+// ReportPanic calls f(ctx, report).
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, report PanicReport) {
+	f(ctx, report)
+}
+
+// TrimPanicStack trims a stack trace produced by [runtime/debug.Stack] inside a deferred recover
+// function, removing the frames up to and including the panic call (recover internals),
+// and keeping at most maxFrames frames. A maxFrames <= 0 keeps all the frames.
+func TrimPanicStack(stack []byte, maxFrames int) string {
+	lines := strings.Split(strings.TrimRight(string(stack), "\n"), "\n")
+	if len(lines) < 2 {
+		return string(stack)
+	}
+	header, frames := lines[0], lines[1:]         // header is like "goroutine 1 [running]:".
+	for idx := 0; idx+1 < len(frames); idx += 2 { // each frame is made of 2 lines, function and file.
+		if strings.HasPrefix(frames[idx], "panic(") {
+			frames = frames[idx+2:]
+
+			break
+		}
+	}
+	var truncated bool
+	if maxFrames > 0 && len(frames) > 2*maxFrames {
+		frames = frames[:2*maxFrames]
+		truncated = true
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header)
+	sb.WriteByte('\n')
+	for _, frame := range frames {
+		sb.WriteString(frame)
+		sb.WriteByte('\n')
+	}
+	if truncated {
+		sb.WriteString("...additional frames elided...\n")
+	}
+
+	return sb.String()
+}
+
+// panicKey returns the key identifying a panic, made of the recovered value and the panic location.
+func panicKey(value any, trimmedStack string) string {
+	key := fmt.Sprint(value)
+	lines := strings.SplitN(trimmedStack, "\n", 4)
+	if len(lines) >= 3 {
+		function := lines[1]
+		if idx := strings.LastIndexByte(function, '('); idx > 0 {
+			function = function[:idx] // strip arguments, which vary between calls.
+		}
+		key += "\n" + function + "\n" + lines[2]
+	}
+
+	return key
+}
+
+// PanicDeduplicator suppresses identical panics (same value and location)
+// occurred within a time window after one was reported.
+// It is concurrent safe to use.
+type PanicDeduplicator struct {
+	window    time.Duration
+	entries   map[string]*panicDedupEntry
+	lastSweep time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+type panicDedupEntry struct {
+	reportedAt time.Time
+	suppressed int
+}
+
+// NewPanicDeduplicator instantiates a new [PanicDeduplicator] with given window.
+func NewPanicDeduplicator(window time.Duration) *PanicDeduplicator {
+	return &PanicDeduplicator{
+		window:  window,
+		entries: make(map[string]*panicDedupEntry),
+		now:     time.Now,
+	}
+}
+
+// Allow returns whether given panic should be reported.
+// If so, it also returns the no. of identical panics suppressed since its previous report.
+func (d *PanicDeduplicator) Allow(value any, trimmedStack string) (bool, int) {
+	key := panicKey(value, trimmedStack)
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(now)
+	entry, found := d.entries[key]
+	if found && now.Sub(entry.reportedAt) < d.window {
+		entry.suppressed++
+
+		return false, 0
+	}
+	var suppressed int
+	if found {
+		suppressed = entry.suppressed
+	}
+	d.entries[key] = &panicDedupEntry{reportedAt: now}
+
+	return true, suppressed
+}
+
+// Len returns the no. of tracked panics.
+func (d *PanicDeduplicator) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.entries)
+}
+
+// sweep lazily removes the entries older than 2 windows. Lock must be held.
+func (d *PanicDeduplicator) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+	for key, entry := range d.entries {
+		if now.Sub(entry.reportedAt) >= 2*d.window {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// HandlePanic applies the de-duplication (if dedup is not nil) to a recovered panic and,
+// if it should be reported, calls the reporters.
+// It returns the report and whether it should be reported (logged) further.
+func HandlePanic(
+	ctx context.Context,
+	value any,
+	stack []byte,
+	maxFrames int,
+	dedup *PanicDeduplicator,
+	reporters ...PanicReporter,
+) (PanicReport, bool) {
+	report := PanicReport{
+		Value: value,
+		Stack: TrimPanicStack(stack, maxFrames),
+	}
+	if dedup != nil {
+		var allowed bool
+		if allowed, report.Suppressed = dedup.Allow(value, report.Stack); !allowed {
+			return report, false
+		}
+	}
+	for _, reporter := range reporters {
+		reporter.ReportPanic(ctx, report)
+	}
+
+	return report, true
+}
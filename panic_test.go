@@ -0,0 +1,131 @@
+package xtransport_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/actforgood/xtransport"
+	"github.com/actforgood/xtransport/testing/assert"
+)
+
+const panicStackSample = `goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:26 +0x5e
+main.handle.func1()
+	/app/main.go:12 +0x45
+panic({0x4a2f20?, 0xc000014070?})
+	/usr/local/go/src/runtime/panic.go:785 +0x132
+main.doWork(0xc00001c030)
+	/app/main.go:20 +0x25
+main.handle()
+	/app/main.go:15 +0x3c
+created by main.main in goroutine 1
+	/app/main.go:30 +0x1a
+`
+
+func TestTrimPanicStack(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name      string
+		maxFrames int
+		expected  string
+	}{
+		{
+			name:      "all frames after panic are kept",
+			maxFrames: 0,
+			expected: `goroutine 7 [running]:
+main.doWork(0xc00001c030)
+	/app/main.go:20 +0x25
+main.handle()
+	/app/main.go:15 +0x3c
+created by main.main in goroutine 1
+	/app/main.go:30 +0x1a
+`,
+		},
+		{
+			name:      "max frames are kept",
+			maxFrames: 1,
+			expected: `goroutine 7 [running]:
+main.doWork(0xc00001c030)
+	/app/main.go:20 +0x25
+...additional frames elided...
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			result := xtransport.TrimPanicStack([]byte(panicStackSample), test.maxFrames)
+
+			// assert
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestPanicDeduplicator(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		subject    = xtransport.NewPanicDeduplicator(50 * time.Millisecond)
+		stack      = xtransport.TrimPanicStack([]byte(panicStackSample), 0)
+		otherStack = "goroutine 8 [running]:\nmain.other(0x1)\n\t/app/main.go:40 +0x25\n"
+	)
+
+	// act & assert
+	allowed, suppressed := subject.Allow("boom", stack)
+	assert.True(t, allowed)
+	assert.Equal(t, 0, suppressed)
+	allowed, _ = subject.Allow("boom", stack)
+	assert.Equal(t, false, allowed)
+	allowed, _ = subject.Allow("boom", "goroutine 9 [running]:\nmain.doWork(0xc00009c000)\n\t/app/main.go:20 +0x25\n")
+	assert.Equal(t, false, allowed) // same location, different arguments.
+	allowed, _ = subject.Allow("boom", otherStack)
+	assert.True(t, allowed) // different location.
+	allowed, _ = subject.Allow("bang", stack)
+	assert.True(t, allowed) // different value.
+	assert.Equal(t, 3, subject.Len())
+
+	time.Sleep(60 * time.Millisecond)
+	allowed, suppressed = subject.Allow("boom", stack)
+	assert.True(t, allowed)
+	assert.Equal(t, 2, suppressed)
+}
+
+func TestHandlePanic(t *testing.T) {
+	t.Parallel()
+
+	// arrange
+	var (
+		panicErr = errors.New("boom")
+		reports  []xtransport.PanicReport
+		reporter = xtransport.PanicReporterFunc(func(_ context.Context, report xtransport.PanicReport) {
+			reports = append(reports, report)
+		})
+		dedup = xtransport.NewPanicDeduplicator(time.Minute)
+	)
+
+	// act
+	report1, shouldLog1 := xtransport.HandlePanic(
+		context.Background(), panicErr, []byte(panicStackSample), 0, dedup, reporter,
+	)
+	_, shouldLog2 := xtransport.HandlePanic(
+		context.Background(), panicErr, []byte(panicStackSample), 0, dedup, reporter,
+	)
+
+	// assert
+	assert.True(t, shouldLog1)
+	assert.Equal(t, false, shouldLog2)
+	assert.Equal(t, panicErr, report1.Value)
+	assert.Equal(t, xtransport.TrimPanicStack([]byte(panicStackSample), 0), report1.Stack)
+	if assert.Equal(t, 1, len(reports)) {
+		assert.Equal(t, report1, reports[0])
+	}
+}